@@ -176,94 +176,48 @@ func (sf *ASDU) SendReplyMirror(c Connect, cause Cause) error {
 	return c.Send(r)
 }
 
-//// String returns a full description.
-//func (u *ASDU) String() string {
-//	dataSize, err := GetInfoObjSize(u.Type)
-//	if err != nil {
-//		if !u.InfoSeq {
-//			return fmt.Sprintf("%s: %#x", u.Identifier, u.infoObj)
-//		}
-//		return fmt.Sprintf("%s seq: %#x", u.Identifier, u.infoObj)
-//	}
-//
-//	end := len(u.infoObj)
-//	addrSize := u.InfoObjAddrSize
-//	if end < addrSize {
-//		if !u.InfoSeq {
-//			return fmt.Sprintf("%s: %#x <EOF>", u.Identifier, u.infoObj)
-//		}
-//		return fmt.Sprintf("%s seq: %#x <EOF>", u.Identifier, u.infoObj)
-//	}
-//	addr := u.ParseInfoObjAddr(u.infoObj)
-//
-//	buf := bytes.NewBufferString(u.Identifier.String())
-//
-//	for i := addrSize; ; {
-//		start := i
-//		i += dataSize
-//		if i > end {
-//			fmt.Fprintf(buf, " %d:%#x <EOF>", addr, u.infoObj[start:])
-//			break
-//		}
-//		fmt.Fprintf(buf, " %d:%#x", addr, u.infoObj[start:i])
-//		if i == end {
-//			break
-//		}
-//
-//		if u.InfoSeq {
-//			addr++
-//		} else {
-//			start = i
-//			i += addrSize
-//			if i > end {
-//				fmt.Fprintf(buf, " %#x <EOF>", u.infoObj[start:i])
-//				break
-//			}
-//			addr = u.ParseInfoObjAddr(u.infoObj[start:])
-//		}
-//	}
-//
-//	return buf.String()
-//}
-
 // MarshalBinary honors the encoding.BinaryMarshaler interface.
+// The returned data shares the internal buffer of the asdu, it is only valid until the asdu is modified.
 func (sf *ASDU) MarshalBinary() (data []byte, err error) {
+	if data, err = sf.AppendBinary(sf.bootstrap[:0]); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// AppendBinary appends the encoded asdu to dst and returns the extended buffer,
+// unlike MarshalBinary the result does not reference the asdu.
+func (sf *ASDU) AppendBinary(dst []byte) ([]byte, error) {
 	switch {
 	case sf.Coa.Cause == Unused:
-		return nil, ErrCauseZero
+		return dst, ErrCauseZero
 	case !(sf.CauseSize == 1 || sf.CauseSize == 2):
-		return nil, ErrParam
+		return dst, ErrParam
 	case sf.CauseSize == 1 && sf.OrigAddr != 0:
-		return nil, ErrOriginAddrFit
+		return dst, ErrOriginAddrFit
 	case sf.CommonAddr == InvalidCommonAddr:
-		return nil, ErrCommonAddrZero
+		return dst, ErrCommonAddrZero
 	case !(sf.CommonAddrSize == 1 || sf.CommonAddrSize == 2):
-		return nil, ErrParam
+		return dst, ErrParam
 	case sf.CommonAddrSize == 1 && sf.CommonAddr != GlobalCommonAddr && sf.CommonAddr >= 255:
-		return nil, ErrParam
+		return dst, ErrParam
 	}
 
-	raw := sf.bootstrap[:(sf.IdentifierSize() + len(sf.infoObj))]
-	raw[0] = byte(sf.Type)
-	raw[1] = sf.Variable.Value()
-	raw[2] = sf.Coa.Value()
-	offset := 3
+	dst = append(dst, byte(sf.Type), sf.Variable.Value(), sf.Coa.Value())
 	if sf.CauseSize == 2 {
-		raw[offset] = byte(sf.OrigAddr)
-		offset++
+		dst = append(dst, byte(sf.OrigAddr))
 	}
 	if sf.CommonAddrSize == 1 {
 		if sf.CommonAddr == GlobalCommonAddr {
-			raw[offset] = 255
+			dst = append(dst, 255)
 		} else {
-			raw[offset] = byte(sf.CommonAddr)
+			dst = append(dst, byte(sf.CommonAddr))
 		}
 	} else { // 2
-		raw[offset] = byte(sf.CommonAddr)
-		offset++
-		raw[offset] = byte(sf.CommonAddr >> 8)
+		dst = append(dst, byte(sf.CommonAddr), byte(sf.CommonAddr>>8))
 	}
-	return raw, nil
+	// 信息体在bootstrap中时与MarshalBinary的结果重叠, append按memmove复制
+	return append(dst, sf.infoObj...), nil
 }
 
 // UnmarshalBinary honors the encoding.BinaryUnmarshaler interface.
@@ -279,6 +233,9 @@ func (sf *ASDU) UnmarshalBinary(rawAsdu []byte) error {
 	if lenDUI > len(rawAsdu) {
 		return io.EOF
 	}
+	if len(rawAsdu) > ASDUSizeMax {
+		return ErrLengthOutOfRange
+	}
 
 	// parse rawAsdu unit identifier
 	sf.Type = TypeID(rawAsdu[0])
@@ -319,7 +276,7 @@ func (sf *ASDU) fixInfoObjSize() error {
 	}
 
 	switch {
-	case size == 0:
+	case size == 0, sf.Variable.Number == 0:
 		return ErrInfoObjIndexFit
 	case size > len(sf.infoObj):
 		return io.EOF
@@ -321,6 +321,20 @@ func TestASDU_UnmarshalBinary(t *testing.T) {
 			[]byte{},
 			true,
 		},
+		{
+			"sequence without information element",
+			ParamsWide,
+			args{[]byte{0x30, 0x80, 0x06, 0x00, 0x80, 0x60, 0x00, 0x01, 0x02}},
+			[]byte{0x00, 0x01, 0x02},
+			true,
+		},
+		{
+			"large than max size",
+			ParamsWide,
+			args{make([]byte, ASDUSizeMax+1)},
+			[]byte{},
+			true,
+		},
 
 		{
 			"ParamsNarrow global address",
@@ -363,3 +377,74 @@ func TestASDU_UnmarshalBinary(t *testing.T) {
 		})
 	}
 }
+
+func TestASDU_AppendBinary(t *testing.T) {
+	sf := &ASDU{
+		Params:     ParamsWide,
+		Identifier: Identifier{Type: M_SP_NA_1, Variable: VariableStruct{Number: 1}, Coa: CauseOfTransmission{Cause: Spontaneous}, CommonAddr: 0x1234},
+		infoObj:    []byte{0x01, 0x00, 0x00, 0x01},
+	}
+	tests := []struct {
+		name string
+		dst  []byte
+		want []byte
+	}{
+		{"nil", nil, []byte{0x01, 0x01, 0x03, 0x00, 0x34, 0x12, 0x01, 0x00, 0x00, 0x01}},
+		{"prefix", []byte{0x68, 0x0e}, []byte{0x68, 0x0e, 0x01, 0x01, 0x03, 0x00, 0x34, 0x12, 0x01, 0x00, 0x00, 0x01}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sf.AppendBinary(tt.dst)
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ASDU.AppendBinary() = % x, %v, want % x", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// benchASDU M_ME_NC_1 with 30 sequence objects
+func benchASDU() []byte {
+	raw := []byte{byte(M_ME_NC_1), 0x80 | 30, 0x14, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00}
+	for i := 0; i < 30; i++ {
+		raw = append(raw, 0x00, 0x00, 0x80, 0x3f, 0x00)
+	}
+	return raw
+}
+
+func BenchmarkASDU_UnmarshalBinary(b *testing.B) {
+	raw := benchASDU()
+	a := NewEmptyASDU(ParamsWide)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := a.UnmarshalBinary(raw); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkASDU_MarshalBinary(b *testing.B) {
+	a := NewEmptyASDU(ParamsWide)
+	if err := a.UnmarshalBinary(benchASDU()); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := a.MarshalBinary(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkASDU_AppendBinary(b *testing.B) {
+	a := NewEmptyASDU(ParamsWide)
+	if err := a.UnmarshalBinary(benchASDU()); err != nil {
+		b.Fatal(err)
+	}
+	buf := make([]byte, 0, ASDUSizeMax)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := a.AppendBinary(buf[:0]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -10,6 +10,11 @@ import (
 	"time"
 )
 
+// view 返回共享信息体的asdu, 在其上解码不改变sf的信息体
+func (sf *ASDU) view() *ASDU {
+	return &ASDU{Params: sf.Params, Identifier: sf.Identifier, infoObj: sf.infoObj}
+}
+
 // AppendBytes append some bytes to info object
 func (sf *ASDU) AppendBytes(b ...byte) *ASDU {
 	sf.infoObj = append(sf.infoObj, b...)
@@ -185,35 +185,39 @@ func ParameterActivation(c Connect, coa CauseOfTransmission, ca CommonAddr, p Pa
 
 // GetParameterNormal [P_ME_NA_1]，获取 测量值参数,标度化值 信息体
 func (sf *ASDU) GetParameterNormal() ParameterNormalInfo {
+	a := sf.view()
 	return ParameterNormalInfo{
-		sf.DecodeInfoObjAddr(),
-		sf.DecodeNormalize(),
-		ParseQualifierOfParamMV(sf.infoObj[0]),
+		a.DecodeInfoObjAddr(),
+		a.DecodeNormalize(),
+		ParseQualifierOfParamMV(a.infoObj[0]),
 	}
 }
 
 // GetParameterScaled [P_ME_NB_1]，获取 测量值参数,归一化值 信息体
 func (sf *ASDU) GetParameterScaled() ParameterScaledInfo {
+	a := sf.view()
 	return ParameterScaledInfo{
-		sf.DecodeInfoObjAddr(),
-		sf.DecodeScaled(),
-		ParseQualifierOfParamMV(sf.infoObj[0]),
+		a.DecodeInfoObjAddr(),
+		a.DecodeScaled(),
+		ParseQualifierOfParamMV(a.infoObj[0]),
 	}
 }
 
 // GetParameterFloat [P_ME_NC_1]，获取 测量值参数,短浮点数 信息体
 func (sf *ASDU) GetParameterFloat() ParameterFloatInfo {
+	a := sf.view()
 	return ParameterFloatInfo{
-		sf.DecodeInfoObjAddr(),
-		sf.DecodeFloat32(),
-		ParseQualifierOfParamMV(sf.infoObj[0]),
+		a.DecodeInfoObjAddr(),
+		a.DecodeFloat32(),
+		ParseQualifierOfParamMV(a.infoObj[0]),
 	}
 }
 
 // GetParameterActivation [P_AC_NA_1]，获取 参数激活 信息体
 func (sf *ASDU) GetParameterActivation() ParameterActivationInfo {
+	a := sf.view()
 	return ParameterActivationInfo{
-		sf.DecodeInfoObjAddr(),
-		QualifierOfParameterAct(sf.infoObj[0]),
+		a.DecodeInfoObjAddr(),
+		QualifierOfParameterAct(a.infoObj[0]),
 	}
 }
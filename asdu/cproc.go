@@ -388,17 +388,18 @@ func BitsString32Cmd(c Connect, typeID TypeID, coa CauseOfTransmission, commonAd
 
 // GetSingleCmd [C_SC_NA_1] or [C_SC_TA_1] 获取单命令信息体
 func (sf *ASDU) GetSingleCmd() SingleCommandInfo {
+	a := sf.view()
 	var s SingleCommandInfo
 
-	s.Ioa = sf.DecodeInfoObjAddr()
-	value := sf.DecodeByte()
+	s.Ioa = a.DecodeInfoObjAddr()
+	value := a.DecodeByte()
 	s.Value = value&0x01 == 0x01
 	s.Qoc = ParseQualifierOfCommand(value & 0xfe)
 
-	switch sf.Type {
+	switch a.Type {
 	case C_SC_NA_1:
 	case C_SC_TA_1:
-		s.Time = sf.DecodeCP56Time2a()
+		s.Time = a.DecodeCP56Time2a()
 	default:
 		panic(ErrTypeIDNotMatch)
 	}
@@ -408,17 +409,18 @@ func (sf *ASDU) GetSingleCmd() SingleCommandInfo {
 
 // GetDoubleCmd [C_DC_NA_1] or [C_DC_TA_1] 获取双命令信息体
 func (sf *ASDU) GetDoubleCmd() DoubleCommandInfo {
+	a := sf.view()
 	var cmd DoubleCommandInfo
 
-	cmd.Ioa = sf.DecodeInfoObjAddr()
-	value := sf.DecodeByte()
+	cmd.Ioa = a.DecodeInfoObjAddr()
+	value := a.DecodeByte()
 	cmd.Value = DoubleCommand(value & 0x03)
 	cmd.Qoc = ParseQualifierOfCommand(value & 0xfc)
 
-	switch sf.Type {
+	switch a.Type {
 	case C_DC_NA_1:
 	case C_DC_TA_1:
-		cmd.Time = sf.DecodeCP56Time2a()
+		cmd.Time = a.DecodeCP56Time2a()
 	default:
 		panic(ErrTypeIDNotMatch)
 	}
@@ -428,17 +430,18 @@ func (sf *ASDU) GetDoubleCmd() DoubleCommandInfo {
 
 // GetStepCmd [C_RC_NA_1] or [C_RC_TA_1] 获取步调节命令信息体
 func (sf *ASDU) GetStepCmd() StepCommandInfo {
+	a := sf.view()
 	var cmd StepCommandInfo
 
-	cmd.Ioa = sf.DecodeInfoObjAddr()
-	value := sf.DecodeByte()
+	cmd.Ioa = a.DecodeInfoObjAddr()
+	value := a.DecodeByte()
 	cmd.Value = StepCommand(value & 0x03)
 	cmd.Qoc = ParseQualifierOfCommand(value & 0xfc)
 
-	switch sf.Type {
+	switch a.Type {
 	case C_RC_NA_1:
 	case C_RC_TA_1:
-		cmd.Time = sf.DecodeCP56Time2a()
+		cmd.Time = a.DecodeCP56Time2a()
 	default:
 		panic(ErrTypeIDNotMatch)
 	}
@@ -448,16 +451,17 @@ func (sf *ASDU) GetStepCmd() StepCommandInfo {
 
 // GetSetpointNormalCmd [C_SE_NA_1] or [C_SE_TA_1] 获取设定命令,规一化值信息体
 func (sf *ASDU) GetSetpointNormalCmd() SetpointCommandNormalInfo {
+	a := sf.view()
 	var cmd SetpointCommandNormalInfo
 
-	cmd.Ioa = sf.DecodeInfoObjAddr()
-	cmd.Value = sf.DecodeNormalize()
-	cmd.Qos = ParseQualifierOfSetpointCmd(sf.DecodeByte())
+	cmd.Ioa = a.DecodeInfoObjAddr()
+	cmd.Value = a.DecodeNormalize()
+	cmd.Qos = ParseQualifierOfSetpointCmd(a.DecodeByte())
 
-	switch sf.Type {
+	switch a.Type {
 	case C_SE_NA_1:
 	case C_SE_TA_1:
-		cmd.Time = sf.DecodeCP56Time2a()
+		cmd.Time = a.DecodeCP56Time2a()
 	default:
 		panic(ErrTypeIDNotMatch)
 	}
@@ -467,16 +471,17 @@ func (sf *ASDU) GetSetpointNormalCmd() SetpointCommandNormalInfo {
 
 // GetSetpointCmdScaled [C_SE_NB_1] or [C_SE_TB_1] 获取设定命令,标度化值信息体
 func (sf *ASDU) GetSetpointCmdScaled() SetpointCommandScaledInfo {
+	a := sf.view()
 	var cmd SetpointCommandScaledInfo
 
-	cmd.Ioa = sf.DecodeInfoObjAddr()
-	cmd.Value = sf.DecodeScaled()
-	cmd.Qos = ParseQualifierOfSetpointCmd(sf.DecodeByte())
+	cmd.Ioa = a.DecodeInfoObjAddr()
+	cmd.Value = a.DecodeScaled()
+	cmd.Qos = ParseQualifierOfSetpointCmd(a.DecodeByte())
 
-	switch sf.Type {
+	switch a.Type {
 	case C_SE_NB_1:
 	case C_SE_TB_1:
-		cmd.Time = sf.DecodeCP56Time2a()
+		cmd.Time = a.DecodeCP56Time2a()
 	default:
 		panic(ErrTypeIDNotMatch)
 	}
@@ -486,16 +491,17 @@ func (sf *ASDU) GetSetpointCmdScaled() SetpointCommandScaledInfo {
 
 // GetSetpointFloatCmd [C_SE_NC_1] or [C_SE_TC_1] 获取设定命令，短浮点数信息体
 func (sf *ASDU) GetSetpointFloatCmd() SetpointCommandFloatInfo {
+	a := sf.view()
 	var cmd SetpointCommandFloatInfo
 
-	cmd.Ioa = sf.DecodeInfoObjAddr()
-	cmd.Value = sf.DecodeFloat32()
-	cmd.Qos = ParseQualifierOfSetpointCmd(sf.DecodeByte())
+	cmd.Ioa = a.DecodeInfoObjAddr()
+	cmd.Value = a.DecodeFloat32()
+	cmd.Qos = ParseQualifierOfSetpointCmd(a.DecodeByte())
 
-	switch sf.Type {
+	switch a.Type {
 	case C_SE_NC_1:
 	case C_SE_TC_1:
-		cmd.Time = sf.DecodeCP56Time2a()
+		cmd.Time = a.DecodeCP56Time2a()
 	default:
 		panic(ErrTypeIDNotMatch)
 	}
@@ -505,14 +511,15 @@ func (sf *ASDU) GetSetpointFloatCmd() SetpointCommandFloatInfo {
 
 // GetBitsString32Cmd [C_BO_NA_1] or [C_BO_TA_1] 获取比特串命令信息体
 func (sf *ASDU) GetBitsString32Cmd() BitsString32CommandInfo {
+	a := sf.view()
 	var cmd BitsString32CommandInfo
 
-	cmd.Ioa = sf.DecodeInfoObjAddr()
-	cmd.Value = sf.DecodeBitsString32()
-	switch sf.Type {
+	cmd.Ioa = a.DecodeInfoObjAddr()
+	cmd.Value = a.DecodeBitsString32()
+	switch a.Type {
 	case C_BO_NA_1:
 	case C_BO_TA_1:
-		cmd.Time = sf.DecodeCP56Time2a()
+		cmd.Time = a.DecodeCP56Time2a()
 	default:
 		panic(ErrTypeIDNotMatch)
 	}
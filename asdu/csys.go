@@ -261,41 +261,48 @@ func TestCommandCP56Time2a(c Connect, coa CauseOfTransmission, ca CommonAddr, t
 
 // GetInterrogationCmd [C_IC_NA_1] 获取总召唤信息体(信息对象地址，召唤限定词)
 func (sf *ASDU) GetInterrogationCmd() (InfoObjAddr, QualifierOfInterrogation) {
-	return sf.DecodeInfoObjAddr(), QualifierOfInterrogation(sf.infoObj[0])
+	a := sf.view()
+	return a.DecodeInfoObjAddr(), QualifierOfInterrogation(a.infoObj[0])
 }
 
 // GetCounterInterrogationCmd [C_CI_NA_1] 获得计量召唤信息体(信息对象地址，计量召唤限定词)
 func (sf *ASDU) GetCounterInterrogationCmd() (InfoObjAddr, QualifierCountCall) {
-	return sf.DecodeInfoObjAddr(), ParseQualifierCountCall(sf.infoObj[0])
+	a := sf.view()
+	return a.DecodeInfoObjAddr(), ParseQualifierCountCall(a.infoObj[0])
 }
 
 // GetReadCmd [C_RD_NA_1] 获得读命令信息地址
 func (sf *ASDU) GetReadCmd() InfoObjAddr {
-	return sf.DecodeInfoObjAddr()
+	a := sf.view()
+	return a.DecodeInfoObjAddr()
 }
 
 // GetClockSynchronizationCmd [C_CS_NA_1] 获得时钟同步命令信息体(信息对象地址,时间)
 func (sf *ASDU) GetClockSynchronizationCmd() (InfoObjAddr, time.Time) {
-
-	return sf.DecodeInfoObjAddr(), sf.DecodeCP56Time2a()
+	a := sf.view()
+	return a.DecodeInfoObjAddr(), a.DecodeCP56Time2a()
 }
 
 // GetTestCommand [C_TS_NA_1]，获得测试命令信息体(信息对象地址,是否是测试字)
 func (sf *ASDU) GetTestCommand() (InfoObjAddr, bool) {
-	return sf.DecodeInfoObjAddr(), sf.DecodeUint16() == FBPTestWord
+	a := sf.view()
+	return a.DecodeInfoObjAddr(), a.DecodeUint16() == FBPTestWord
 }
 
 // GetResetProcessCmd [C_RP_NA_1] 获得复位进程命令信息体(信息对象地址,复位进程命令限定词)
 func (sf *ASDU) GetResetProcessCmd() (InfoObjAddr, QualifierOfResetProcessCmd) {
-	return sf.DecodeInfoObjAddr(), QualifierOfResetProcessCmd(sf.infoObj[0])
+	a := sf.view()
+	return a.DecodeInfoObjAddr(), QualifierOfResetProcessCmd(a.infoObj[0])
 }
 
 // GetDelayAcquireCommand [C_CD_NA_1] 获取延时获取命令信息体(信息对象地址,延时毫秒数)
 func (sf *ASDU) GetDelayAcquireCommand() (InfoObjAddr, uint16) {
-	return sf.DecodeInfoObjAddr(), sf.DecodeUint16()
+	a := sf.view()
+	return a.DecodeInfoObjAddr(), a.DecodeUint16()
 }
 
 // GetTestCommandCP56Time2a [C_TS_TA_1]，获得测试命令信息体(信息对象地址,是否是测试字)
 func (sf *ASDU) GetTestCommandCP56Time2a() (InfoObjAddr, bool, time.Time) {
-	return sf.DecodeInfoObjAddr(), sf.DecodeUint16() == FBPTestWord, sf.DecodeCP56Time2a()
+	a := sf.view()
+	return a.DecodeInfoObjAddr(), a.DecodeUint16() == FBPTestWord, a.DecodeCP56Time2a()
 }
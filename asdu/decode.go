@@ -0,0 +1,478 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package asdu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Parse* 与对应的 Get* 解码相同的信息体, 但先按类型标识的信息元素长度, 可变结构限定词的
+// 数目和SQ, 信息对象地址长度检查信息体, 信息体不完整, 数目不符或时标无效时返回 *DecodeError,
+// 不会panic. 时标的IV位置位时不检查时标, 与 Get* 一样返回零值时间.
+// Get* 与 Parse* 均不改变asdu的信息体, 同一个asdu可以多次解码.
+
+// DecodeError 信息体解码错误, Err 为 ErrTypeIDNotMatch, ErrTruncatedObject,
+// ErrCountMismatch 或 ErrInvalidTimeTag 等
+type DecodeError struct {
+	Type  TypeID
+	Index int // 出错的信息对象序号, -1 表示整个信息体
+	Err   error
+}
+
+func (sf *DecodeError) Error() string {
+	if sf.Index < 0 {
+		return fmt.Sprintf("%v: %v", sf.Type, sf.Err)
+	}
+	return fmt.Sprintf("%v object %d: %v", sf.Type, sf.Index, sf.Err)
+}
+
+// Unwrap 返回原始错误
+func (sf *DecodeError) Unwrap() error {
+	return sf.Err
+}
+
+// timeTagSize 类型标识的信息元素末尾的时标长度
+func timeTagSize(id TypeID) int {
+	switch id {
+	case M_SP_TA_1, M_DP_TA_1, M_ST_TA_1, M_BO_TA_1, M_ME_TA_1, M_ME_TB_1, M_ME_TC_1,
+		M_IT_TA_1, M_EP_TA_1, M_EP_TB_1, M_EP_TC_1:
+		return 3
+	case M_SP_TB_1, M_DP_TB_1, M_ST_TB_1, M_BO_TB_1, M_ME_TD_1, M_ME_TE_1, M_ME_TF_1,
+		M_IT_TB_1, M_EP_TD_1, M_EP_TE_1, M_EP_TF_1,
+		C_SC_TA_1, C_DC_TA_1, C_RC_TA_1, C_SE_TA_1, C_SE_TB_1, C_SE_TC_1, C_BO_TA_1,
+		C_CS_NA_1, C_TS_TA_1:
+		return 7
+	}
+	return 0
+}
+
+// validCP24Time2a 检查CP24Time2a的毫秒和分钟
+func validCP24Time2a(b []byte) bool {
+	if b[2]&0x80 == 0x80 { // IV
+		return true
+	}
+	return binary.LittleEndian.Uint16(b) < 60000 && b[2]&0x3f < 60
+}
+
+// validCP56Time2a 检查CP56Time2a的毫秒, 分钟, 小时, 日和月
+func validCP56Time2a(b []byte) bool {
+	if b[2]&0x80 == 0x80 { // IV
+		return true
+	}
+	day, month := b[4]&0x1f, b[5]&0x0f
+	return validCP24Time2a(b) && b[3]&0x1f < 24 && day >= 1 && month >= 1 && month <= 12
+}
+
+// checkInfoObj 检查信息体, single 为true时只允许一个信息对象
+func (sf *ASDU) checkInfoObj(single bool, ids ...TypeID) error {
+	match := false
+	for _, id := range ids {
+		match = match || sf.Type == id
+	}
+	if !match {
+		return &DecodeError{sf.Type, -1, ErrTypeIDNotMatch}
+	}
+	objSize, err := GetInfoObjSize(sf.Type)
+	if err != nil {
+		return &DecodeError{sf.Type, -1, err}
+	}
+	if sf.InfoObjAddrSize < 1 || sf.InfoObjAddrSize > 3 {
+		return &DecodeError{sf.Type, -1, ErrParam}
+	}
+
+	n := int(sf.Variable.Number)
+	if n == 0 || (single && n != 1) {
+		return &DecodeError{sf.Type, -1, ErrCountMismatch}
+	}
+	// end 第i个信息元素的结束位置
+	end := func(i int) int {
+		if sf.Variable.IsSequence {
+			return sf.InfoObjAddrSize + (i+1)*objSize
+		}
+		return (i + 1) * (sf.InfoObjAddrSize + objSize)
+	}
+	for i := 0; i < n; i++ {
+		if end(i) > len(sf.infoObj) {
+			return &DecodeError{sf.Type, i, ErrTruncatedObject}
+		}
+	}
+	if end(n-1) < len(sf.infoObj) {
+		return &DecodeError{sf.Type, -1, ErrCountMismatch}
+	}
+
+	tagSize := timeTagSize(sf.Type)
+	if tagSize == 0 {
+		return nil
+	}
+	valid := validCP56Time2a
+	if tagSize == 3 {
+		valid = validCP24Time2a
+	}
+	for i := 0; i < n; i++ {
+		if !valid(sf.infoObj[end(i)-tagSize : end(i)]) {
+			return &DecodeError{sf.Type, i, ErrInvalidTimeTag}
+		}
+	}
+	return nil
+}
+
+// ParseSinglePoint [M_SP_NA_1], [M_SP_TA_1] or [M_SP_TB_1] 检查并获取单点信息信息体集合
+func (sf *ASDU) ParseSinglePoint() ([]SinglePointInfo, error) {
+	if err := sf.checkInfoObj(false, M_SP_NA_1, M_SP_TA_1, M_SP_TB_1); err != nil {
+		return nil, err
+	}
+	return sf.GetSinglePoint(), nil
+}
+
+// ParseDoublePoint [M_DP_NA_1], [M_DP_TA_1] or [M_DP_TB_1] 检查并获取双点信息信息体集合
+func (sf *ASDU) ParseDoublePoint() ([]DoublePointInfo, error) {
+	if err := sf.checkInfoObj(false, M_DP_NA_1, M_DP_TA_1, M_DP_TB_1); err != nil {
+		return nil, err
+	}
+	return sf.GetDoublePoint(), nil
+}
+
+// ParseStepPosition [M_ST_NA_1], [M_ST_TA_1] or [M_ST_TB_1] 检查并获取步位置信息体集合
+func (sf *ASDU) ParseStepPosition() ([]StepPositionInfo, error) {
+	if err := sf.checkInfoObj(false, M_ST_NA_1, M_ST_TA_1, M_ST_TB_1); err != nil {
+		return nil, err
+	}
+	return sf.GetStepPosition(), nil
+}
+
+// ParseBitString32 [M_BO_NA_1], [M_BO_TA_1] or [M_BO_TB_1] 检查并获取比特位串信息体集合
+func (sf *ASDU) ParseBitString32() ([]BitString32Info, error) {
+	if err := sf.checkInfoObj(false, M_BO_NA_1, M_BO_TA_1, M_BO_TB_1); err != nil {
+		return nil, err
+	}
+	return sf.GetBitString32(), nil
+}
+
+// ParseMeasuredValueNormal [M_ME_NA_1], [M_ME_TA_1], [M_ME_TD_1] or [M_ME_ND_1] 检查并获取测量值,规一化值信息体集合
+func (sf *ASDU) ParseMeasuredValueNormal() ([]MeasuredValueNormalInfo, error) {
+	if err := sf.checkInfoObj(false, M_ME_NA_1, M_ME_TA_1, M_ME_TD_1, M_ME_ND_1); err != nil {
+		return nil, err
+	}
+	return sf.GetMeasuredValueNormal(), nil
+}
+
+// ParseMeasuredValueScaled [M_ME_NB_1], [M_ME_TB_1] or [M_ME_TE_1] 检查并获取测量值,标度化值信息体集合
+func (sf *ASDU) ParseMeasuredValueScaled() ([]MeasuredValueScaledInfo, error) {
+	if err := sf.checkInfoObj(false, M_ME_NB_1, M_ME_TB_1, M_ME_TE_1); err != nil {
+		return nil, err
+	}
+	return sf.GetMeasuredValueScaled(), nil
+}
+
+// ParseMeasuredValueFloat [M_ME_NC_1], [M_ME_TC_1] or [M_ME_TF_1] 检查并获取测量值,短浮点数信息体集合
+func (sf *ASDU) ParseMeasuredValueFloat() ([]MeasuredValueFloatInfo, error) {
+	if err := sf.checkInfoObj(false, M_ME_NC_1, M_ME_TC_1, M_ME_TF_1); err != nil {
+		return nil, err
+	}
+	return sf.GetMeasuredValueFloat(), nil
+}
+
+// ParseIntegratedTotals [M_IT_NA_1], [M_IT_TA_1] or [M_IT_TB_1] 检查并获取累计量信息体集合
+func (sf *ASDU) ParseIntegratedTotals() ([]BinaryCounterReadingInfo, error) {
+	if err := sf.checkInfoObj(false, M_IT_NA_1, M_IT_TA_1, M_IT_TB_1); err != nil {
+		return nil, err
+	}
+	return sf.GetIntegratedTotals(), nil
+}
+
+// ParseEventOfProtectionEquipment [M_EP_TA_1] or [M_EP_TD_1] 检查并获取继电器保护设备事件信息体集合
+func (sf *ASDU) ParseEventOfProtectionEquipment() ([]EventOfProtectionEquipmentInfo, error) {
+	if err := sf.checkInfoObj(false, M_EP_TA_1, M_EP_TD_1); err != nil {
+		return nil, err
+	}
+	return sf.GetEventOfProtectionEquipment(), nil
+}
+
+// ParsePackedStartEventsOfProtectionEquipment [M_EP_TB_1] or [M_EP_TE_1] 检查并获取继电器保护设备成组启动事件信息体
+func (sf *ASDU) ParsePackedStartEventsOfProtectionEquipment() (PackedStartEventsOfProtectionEquipmentInfo, error) {
+	if err := sf.checkInfoObj(true, M_EP_TB_1, M_EP_TE_1); err != nil {
+		return PackedStartEventsOfProtectionEquipmentInfo{}, err
+	}
+	return sf.GetPackedStartEventsOfProtectionEquipment(), nil
+}
+
+// ParsePackedOutputCircuitInfo [M_EP_TC_1] or [M_EP_TF_1] 检查并获取继电器保护设备成组输出电路信息信息体
+func (sf *ASDU) ParsePackedOutputCircuitInfo() (PackedOutputCircuitInfoInfo, error) {
+	if err := sf.checkInfoObj(true, M_EP_TC_1, M_EP_TF_1); err != nil {
+		return PackedOutputCircuitInfoInfo{}, err
+	}
+	return sf.GetPackedOutputCircuitInfo(), nil
+}
+
+// ParsePackedSinglePointWithSCD [M_PS_NA_1] 检查并获取带变位检出的成组单点信息信息体集合
+func (sf *ASDU) ParsePackedSinglePointWithSCD() ([]PackedSinglePointWithSCDInfo, error) {
+	if err := sf.checkInfoObj(false, M_PS_NA_1); err != nil {
+		return nil, err
+	}
+	return sf.GetPackedSinglePointWithSCD(), nil
+}
+
+// ParseEndOfInitialization [M_EI_NA_1] 检查并获取初始化结束信息体(信息对象地址,初始化原因)
+func (sf *ASDU) ParseEndOfInitialization() (InfoObjAddr, CauseOfInitial, error) {
+	if err := sf.checkInfoObj(true, M_EI_NA_1); err != nil {
+		return 0, CauseOfInitial{}, err
+	}
+	ioa, coi := sf.GetEndOfInitialization()
+	return ioa, coi, nil
+}
+
+// Range* 与 Parse* 相同地检查信息体, 然后依次解码每个信息对象交给f, f 返回false时停止.
+// 不构造切片也不改变信息体, 无需Clone, 适合高频的遥测数据.
+
+// RangeSinglePoint [M_SP_NA_1], [M_SP_TA_1], [M_SP_TB_1] 检查并依次解码单点信息
+func (sf *ASDU) RangeSinglePoint(f func(SinglePointInfo) bool) error {
+	if err := sf.checkInfoObj(false, M_SP_NA_1, M_SP_TA_1, M_SP_TB_1); err != nil {
+		return err
+	}
+	sf.rangeSinglePoint(f)
+	return nil
+}
+
+// RangeDoublePoint [M_DP_NA_1], [M_DP_TA_1], [M_DP_TB_1] 检查并依次解码双点信息
+func (sf *ASDU) RangeDoublePoint(f func(DoublePointInfo) bool) error {
+	if err := sf.checkInfoObj(false, M_DP_NA_1, M_DP_TA_1, M_DP_TB_1); err != nil {
+		return err
+	}
+	sf.rangeDoublePoint(f)
+	return nil
+}
+
+// RangeStepPosition [M_ST_NA_1], [M_ST_TA_1], [M_ST_TB_1] 检查并依次解码步位置信息
+func (sf *ASDU) RangeStepPosition(f func(StepPositionInfo) bool) error {
+	if err := sf.checkInfoObj(false, M_ST_NA_1, M_ST_TA_1, M_ST_TB_1); err != nil {
+		return err
+	}
+	sf.rangeStepPosition(f)
+	return nil
+}
+
+// RangeBitString32 [M_BO_NA_1], [M_BO_TA_1], [M_BO_TB_1] 检查并依次解码比特位串信息
+func (sf *ASDU) RangeBitString32(f func(BitString32Info) bool) error {
+	if err := sf.checkInfoObj(false, M_BO_NA_1, M_BO_TA_1, M_BO_TB_1); err != nil {
+		return err
+	}
+	sf.rangeBitString32(f)
+	return nil
+}
+
+// RangeMeasuredValueNormal [M_ME_NA_1], [M_ME_TA_1], [M_ME_TD_1], [M_ME_ND_1] 检查并依次解码测量值,规一化值信息
+func (sf *ASDU) RangeMeasuredValueNormal(f func(MeasuredValueNormalInfo) bool) error {
+	if err := sf.checkInfoObj(false, M_ME_NA_1, M_ME_TA_1, M_ME_TD_1, M_ME_ND_1); err != nil {
+		return err
+	}
+	sf.rangeMeasuredValueNormal(f)
+	return nil
+}
+
+// RangeMeasuredValueScaled [M_ME_NB_1], [M_ME_TB_1], [M_ME_TE_1] 检查并依次解码测量值,标度化值信息
+func (sf *ASDU) RangeMeasuredValueScaled(f func(MeasuredValueScaledInfo) bool) error {
+	if err := sf.checkInfoObj(false, M_ME_NB_1, M_ME_TB_1, M_ME_TE_1); err != nil {
+		return err
+	}
+	sf.rangeMeasuredValueScaled(f)
+	return nil
+}
+
+// RangeMeasuredValueFloat [M_ME_NC_1], [M_ME_TC_1], [M_ME_TF_1] 检查并依次解码测量值,短浮点数信息
+func (sf *ASDU) RangeMeasuredValueFloat(f func(MeasuredValueFloatInfo) bool) error {
+	if err := sf.checkInfoObj(false, M_ME_NC_1, M_ME_TC_1, M_ME_TF_1); err != nil {
+		return err
+	}
+	sf.rangeMeasuredValueFloat(f)
+	return nil
+}
+
+// RangeIntegratedTotals [M_IT_NA_1], [M_IT_TA_1], [M_IT_TB_1] 检查并依次解码累计量信息
+func (sf *ASDU) RangeIntegratedTotals(f func(BinaryCounterReadingInfo) bool) error {
+	if err := sf.checkInfoObj(false, M_IT_NA_1, M_IT_TA_1, M_IT_TB_1); err != nil {
+		return err
+	}
+	sf.rangeIntegratedTotals(f)
+	return nil
+}
+
+// RangeEventOfProtectionEquipment [M_EP_TA_1], [M_EP_TD_1] 检查并依次解码继电器保护设备事件信息
+func (sf *ASDU) RangeEventOfProtectionEquipment(f func(EventOfProtectionEquipmentInfo) bool) error {
+	if err := sf.checkInfoObj(false, M_EP_TA_1, M_EP_TD_1); err != nil {
+		return err
+	}
+	sf.rangeEventOfProtectionEquipment(f)
+	return nil
+}
+
+// RangePackedSinglePointWithSCD [M_PS_NA_1] 检查并依次解码带变位检出的成组单点信息
+func (sf *ASDU) RangePackedSinglePointWithSCD(f func(PackedSinglePointWithSCDInfo) bool) error {
+	if err := sf.checkInfoObj(false, M_PS_NA_1); err != nil {
+		return err
+	}
+	sf.rangePackedSinglePointWithSCD(f)
+	return nil
+}
+
+// ParseSingleCmd [C_SC_NA_1] or [C_SC_TA_1] 检查并获取单命令信息体
+func (sf *ASDU) ParseSingleCmd() (SingleCommandInfo, error) {
+	if err := sf.checkInfoObj(true, C_SC_NA_1, C_SC_TA_1); err != nil {
+		return SingleCommandInfo{}, err
+	}
+	return sf.GetSingleCmd(), nil
+}
+
+// ParseDoubleCmd [C_DC_NA_1] or [C_DC_TA_1] 检查并获取双命令信息体
+func (sf *ASDU) ParseDoubleCmd() (DoubleCommandInfo, error) {
+	if err := sf.checkInfoObj(true, C_DC_NA_1, C_DC_TA_1); err != nil {
+		return DoubleCommandInfo{}, err
+	}
+	return sf.GetDoubleCmd(), nil
+}
+
+// ParseStepCmd [C_RC_NA_1] or [C_RC_TA_1] 检查并获取步调节命令信息体
+func (sf *ASDU) ParseStepCmd() (StepCommandInfo, error) {
+	if err := sf.checkInfoObj(true, C_RC_NA_1, C_RC_TA_1); err != nil {
+		return StepCommandInfo{}, err
+	}
+	return sf.GetStepCmd(), nil
+}
+
+// ParseSetpointNormalCmd [C_SE_NA_1] or [C_SE_TA_1] 检查并获取设定命令,规一化值信息体
+func (sf *ASDU) ParseSetpointNormalCmd() (SetpointCommandNormalInfo, error) {
+	if err := sf.checkInfoObj(true, C_SE_NA_1, C_SE_TA_1); err != nil {
+		return SetpointCommandNormalInfo{}, err
+	}
+	return sf.GetSetpointNormalCmd(), nil
+}
+
+// ParseSetpointCmdScaled [C_SE_NB_1] or [C_SE_TB_1] 检查并获取设定命令,标度化值信息体
+func (sf *ASDU) ParseSetpointCmdScaled() (SetpointCommandScaledInfo, error) {
+	if err := sf.checkInfoObj(true, C_SE_NB_1, C_SE_TB_1); err != nil {
+		return SetpointCommandScaledInfo{}, err
+	}
+	return sf.GetSetpointCmdScaled(), nil
+}
+
+// ParseSetpointFloatCmd [C_SE_NC_1] or [C_SE_TC_1] 检查并获取设定命令,短浮点数信息体
+func (sf *ASDU) ParseSetpointFloatCmd() (SetpointCommandFloatInfo, error) {
+	if err := sf.checkInfoObj(true, C_SE_NC_1, C_SE_TC_1); err != nil {
+		return SetpointCommandFloatInfo{}, err
+	}
+	return sf.GetSetpointFloatCmd(), nil
+}
+
+// ParseBitsString32Cmd [C_BO_NA_1] or [C_BO_TA_1] 检查并获取比特串命令信息体
+func (sf *ASDU) ParseBitsString32Cmd() (BitsString32CommandInfo, error) {
+	if err := sf.checkInfoObj(true, C_BO_NA_1, C_BO_TA_1); err != nil {
+		return BitsString32CommandInfo{}, err
+	}
+	return sf.GetBitsString32Cmd(), nil
+}
+
+// ParseInterrogationCmd [C_IC_NA_1] 检查并获取总召唤信息体(信息对象地址，召唤限定词)
+func (sf *ASDU) ParseInterrogationCmd() (InfoObjAddr, QualifierOfInterrogation, error) {
+	if err := sf.checkInfoObj(true, C_IC_NA_1); err != nil {
+		return 0, 0, err
+	}
+	ioa, qoi := sf.GetInterrogationCmd()
+	return ioa, qoi, nil
+}
+
+// ParseCounterInterrogationCmd [C_CI_NA_1] 检查并获取计量召唤信息体(信息对象地址，计量召唤限定词)
+func (sf *ASDU) ParseCounterInterrogationCmd() (InfoObjAddr, QualifierCountCall, error) {
+	if err := sf.checkInfoObj(true, C_CI_NA_1); err != nil {
+		return 0, QualifierCountCall{}, err
+	}
+	ioa, qcc := sf.GetCounterInterrogationCmd()
+	return ioa, qcc, nil
+}
+
+// ParseReadCmd [C_RD_NA_1] 检查并获取读命令信息地址
+func (sf *ASDU) ParseReadCmd() (InfoObjAddr, error) {
+	if err := sf.checkInfoObj(true, C_RD_NA_1); err != nil {
+		return 0, err
+	}
+	return sf.GetReadCmd(), nil
+}
+
+// ParseClockSynchronizationCmd [C_CS_NA_1] 检查并获取时钟同步命令信息体(信息对象地址,时间)
+func (sf *ASDU) ParseClockSynchronizationCmd() (InfoObjAddr, time.Time, error) {
+	if err := sf.checkInfoObj(true, C_CS_NA_1); err != nil {
+		return 0, time.Time{}, err
+	}
+	ioa, t := sf.GetClockSynchronizationCmd()
+	return ioa, t, nil
+}
+
+// ParseTestCommand [C_TS_NA_1] 检查并获取测试命令信息体(信息对象地址,是否是测试字)
+func (sf *ASDU) ParseTestCommand() (InfoObjAddr, bool, error) {
+	if err := sf.checkInfoObj(true, C_TS_NA_1); err != nil {
+		return 0, false, err
+	}
+	ioa, ok := sf.GetTestCommand()
+	return ioa, ok, nil
+}
+
+// ParseResetProcessCmd [C_RP_NA_1] 检查并获取复位进程命令信息体(信息对象地址,复位进程命令限定词)
+func (sf *ASDU) ParseResetProcessCmd() (InfoObjAddr, QualifierOfResetProcessCmd, error) {
+	if err := sf.checkInfoObj(true, C_RP_NA_1); err != nil {
+		return 0, 0, err
+	}
+	ioa, qrp := sf.GetResetProcessCmd()
+	return ioa, qrp, nil
+}
+
+// ParseDelayAcquireCommand [C_CD_NA_1] 检查并获取延时获取命令信息体(信息对象地址,延时毫秒数)
+func (sf *ASDU) ParseDelayAcquireCommand() (InfoObjAddr, uint16, error) {
+	if err := sf.checkInfoObj(true, C_CD_NA_1); err != nil {
+		return 0, 0, err
+	}
+	ioa, msec := sf.GetDelayAcquireCommand()
+	return ioa, msec, nil
+}
+
+// ParseTestCommandCP56Time2a [C_TS_TA_1] 检查并获取测试命令信息体(信息对象地址,是否是测试字,时间)
+func (sf *ASDU) ParseTestCommandCP56Time2a() (InfoObjAddr, bool, time.Time, error) {
+	if err := sf.checkInfoObj(true, C_TS_TA_1); err != nil {
+		return 0, false, time.Time{}, err
+	}
+	ioa, ok, t := sf.GetTestCommandCP56Time2a()
+	return ioa, ok, t, nil
+}
+
+// ParseParameterNormal [P_ME_NA_1] 检查并获取测量值参数,规一化值信息体
+func (sf *ASDU) ParseParameterNormal() (ParameterNormalInfo, error) {
+	if err := sf.checkInfoObj(true, P_ME_NA_1); err != nil {
+		return ParameterNormalInfo{}, err
+	}
+	return sf.GetParameterNormal(), nil
+}
+
+// ParseParameterScaled [P_ME_NB_1] 检查并获取测量值参数,标度化值信息体
+func (sf *ASDU) ParseParameterScaled() (ParameterScaledInfo, error) {
+	if err := sf.checkInfoObj(true, P_ME_NB_1); err != nil {
+		return ParameterScaledInfo{}, err
+	}
+	return sf.GetParameterScaled(), nil
+}
+
+// ParseParameterFloat [P_ME_NC_1] 检查并获取测量值参数,短浮点数信息体
+func (sf *ASDU) ParseParameterFloat() (ParameterFloatInfo, error) {
+	if err := sf.checkInfoObj(true, P_ME_NC_1); err != nil {
+		return ParameterFloatInfo{}, err
+	}
+	return sf.GetParameterFloat(), nil
+}
+
+// ParseParameterActivation [P_AC_NA_1] 检查并获取参数激活信息体
+func (sf *ASDU) ParseParameterActivation() (ParameterActivationInfo, error) {
+	if err := sf.checkInfoObj(true, P_AC_NA_1); err != nil {
+		return ParameterActivationInfo{}, err
+	}
+	return sf.GetParameterActivation(), nil
+}
@@ -0,0 +1,186 @@
+package asdu
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestASDU_checkInfoObj(t *testing.T) {
+	tests := []struct {
+		name       string
+		identifier Identifier
+		infoObj    []byte
+		single     bool
+		wantIndex  int
+		wantErr    error
+	}{
+		{"M_SP_NA_1", Identifier{Type: M_SP_NA_1, Variable: VariableStruct{Number: 2}},
+			[]byte{0x01, 0x00, 0x00, 0x01, 0x02, 0x00, 0x00, 0x00}, false, 0, nil},
+		{"M_SP_NA_1 sequence", Identifier{Type: M_SP_NA_1, Variable: VariableStruct{IsSequence: true, Number: 3}},
+			[]byte{0x01, 0x00, 0x00, 0x01, 0x00, 0x01}, false, 0, nil},
+		{"type not match", Identifier{Type: M_DP_NA_1, Variable: VariableStruct{Number: 1}},
+			[]byte{0x01, 0x00, 0x00, 0x01}, false, -1, ErrTypeIDNotMatch},
+		{"zero number", Identifier{Type: M_SP_NA_1},
+			[]byte{0x01, 0x00, 0x00, 0x01}, false, -1, ErrCountMismatch},
+		{"truncated second object", Identifier{Type: M_SP_NA_1, Variable: VariableStruct{Number: 2}},
+			[]byte{0x01, 0x00, 0x00, 0x01, 0x02, 0x00}, false, 1, ErrTruncatedObject},
+		{"truncated address", Identifier{Type: M_SP_NA_1, Variable: VariableStruct{IsSequence: true, Number: 1}},
+			[]byte{0x01, 0x00}, false, 0, ErrTruncatedObject},
+		{"extra bytes", Identifier{Type: M_SP_NA_1, Variable: VariableStruct{Number: 1}},
+			[]byte{0x01, 0x00, 0x00, 0x01, 0x02}, false, -1, ErrCountMismatch},
+		{"M_SP_TA_1 invalid CP24Time2a", Identifier{Type: M_SP_TA_1, Variable: VariableStruct{Number: 1}},
+			[]byte{0x01, 0x00, 0x00, 0x01, 0x60, 0xea, 0x00}, false, 0, ErrInvalidTimeTag},
+		{"M_SP_TB_1 invalid flag", Identifier{Type: M_SP_TB_1, Variable: VariableStruct{Number: 1}},
+			append([]byte{0x01, 0x00, 0x00, 0x01}, 0x00, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00), false, 0, nil},
+		{"M_SP_TB_1 invalid CP56Time2a", Identifier{Type: M_SP_TB_1, Variable: VariableStruct{Number: 2}},
+			append(append([]byte{0x01, 0x00, 0x00, 0x01}, tm0CP56Time2aBytes...),
+				0x02, 0x00, 0x00, 0x01, 0x01, 0x02, 0x03, 0x04, 0x05, 0x0d, 0x13), false, 1, ErrInvalidTimeTag},
+		{"C_SC_NA_1 more than one", Identifier{Type: C_SC_NA_1, Variable: VariableStruct{Number: 2}},
+			[]byte{0x01, 0x00, 0x00, 0x01, 0x02, 0x00, 0x00, 0x00}, true, -1, ErrCountMismatch},
+		{"C_CS_NA_1", Identifier{Type: C_CS_NA_1, Variable: VariableStruct{Number: 1}},
+			append([]byte{0x00, 0x00, 0x00}, tm0CP56Time2aBytes...), true, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := &ASDU{Params: ParamsWide, Identifier: tt.identifier, infoObj: tt.infoObj}
+			err := sf.checkInfoObj(tt.single, M_SP_NA_1, M_SP_TA_1, M_SP_TB_1, C_SC_NA_1, C_CS_NA_1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ASDU.checkInfoObj() error = %v, want %v", err, tt.wantErr)
+			}
+			var e *DecodeError
+			if errors.As(err, &e) && (e.Type != tt.identifier.Type || e.Index != tt.wantIndex) {
+				t.Errorf("ASDU.checkInfoObj() error = %+v, want index %d", e, tt.wantIndex)
+			}
+		})
+	}
+}
+
+func TestASDU_ParseSinglePoint(t *testing.T) {
+	sf := &ASDU{
+		Params:     ParamsWide,
+		Identifier: Identifier{Type: M_SP_NA_1, Variable: VariableStruct{Number: 1}},
+		infoObj:    []byte{0x01, 0x00, 0x00, 0x01},
+	}
+	got, err := sf.Clone().ParseSinglePoint()
+	if err != nil || !reflect.DeepEqual(got, sf.Clone().GetSinglePoint()) {
+		t.Errorf("ASDU.ParseSinglePoint() = %v, %v", got, err)
+	}
+
+	sf.infoObj = sf.infoObj[:3]
+	if _, err = sf.ParseSinglePoint(); err == nil || err.Error() != "TID<M_SP_NA_1> object 0: asdu: information object truncated" {
+		t.Errorf("ASDU.ParseSinglePoint() error = %v", err)
+	}
+}
+
+func TestASDU_ParseClockSynchronizationCmd(t *testing.T) {
+	tests := []struct {
+		name    string
+		infoObj []byte
+		want    time.Time
+		wantErr bool
+	}{
+		{"valid", append([]byte{0x00, 0x00, 0x00}, tm0CP56Time2aBytes...), tm0, false},
+		{"truncated", append([]byte{0x00, 0x00, 0x00}, tm0CP56Time2aBytes[:6]...), time.Time{}, true},
+		{"zero day", []byte{0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04, 0x00, 0x06, 0x13}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := &ASDU{
+				Params:     ParamsWide,
+				Identifier: Identifier{Type: C_CS_NA_1, Variable: VariableStruct{Number: 1}},
+				infoObj:    tt.infoObj,
+			}
+			_, got, err := sf.ParseClockSynchronizationCmd()
+			if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+				t.Errorf("ASDU.ParseClockSynchronizationCmd() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestASDU_RangeSinglePoint(t *testing.T) {
+	sf := &ASDU{
+		Params:     ParamsWide,
+		Identifier: Identifier{Type: M_SP_NA_1, Variable: VariableStruct{IsSequence: true, Number: 3}},
+		infoObj:    []byte{0x01, 0x00, 0x00, 0x01, 0x00, 0x01},
+	}
+	var got []SinglePointInfo
+	err := sf.RangeSinglePoint(func(v SinglePointInfo) bool {
+		got = append(got, v)
+		return len(got) < 2
+	})
+	want := []SinglePointInfo{{Ioa: 1, Value: true}, {Ioa: 2}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ASDU.RangeSinglePoint() = %v, %v, want %v", got, err, want)
+	}
+	// 遍历不消耗信息对象
+	if all := sf.GetSinglePoint(); len(all) != 3 {
+		t.Errorf("ASDU.GetSinglePoint() after range = %v", all)
+	}
+}
+
+func TestASDU_GetTwice(t *testing.T) {
+	tests := []struct {
+		name    string
+		typeID  TypeID
+		infoObj []byte
+		get     func(a *ASDU) interface{}
+	}{
+		{"single command", C_SC_NA_1, []byte{0x01, 0x00, 0x00, 0x81},
+			func(a *ASDU) interface{} { return a.GetSingleCmd() }},
+		{"interrogation", C_IC_NA_1, []byte{0x00, 0x00, 0x00, 0x14},
+			func(a *ASDU) interface{} { ioa, qoi := a.GetInterrogationCmd(); return []interface{}{ioa, qoi} }},
+		{"clock sync", C_CS_NA_1, append([]byte{0x00, 0x00, 0x00}, tm0CP56Time2aBytes...),
+			func(a *ASDU) interface{} { ioa, t := a.GetClockSynchronizationCmd(); return []interface{}{ioa, t} }},
+		{"parameter float", P_ME_NC_1, []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x80, 0x3f, 0x01},
+			func(a *ASDU) interface{} { return a.GetParameterFloat() }},
+		{"end of initialization", M_EI_NA_1, []byte{0x00, 0x00, 0x00, 0x02},
+			func(a *ASDU) interface{} { ioa, coi := a.GetEndOfInitialization(); return []interface{}{ioa, coi} }},
+		{"packed output circuit", M_EP_TF_1, append([]byte{0x01, 0x00, 0x00, 0x01, 0x00, 0x10, 0x00}, tm0CP56Time2aBytes...),
+			func(a *ASDU) interface{} { return a.GetPackedOutputCircuitInfo() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := &ASDU{
+				Params:     ParamsWide,
+				Identifier: Identifier{Type: tt.typeID, Variable: VariableStruct{Number: 1}},
+				infoObj:    tt.infoObj,
+			}
+			first := tt.get(sf)
+			if got := tt.get(sf); !reflect.DeepEqual(got, first) {
+				t.Errorf("second decode = %v, want %v", got, first)
+			}
+			if len(sf.infoObj) != len(tt.infoObj) {
+				t.Errorf("information object consumed, %d bytes left", len(sf.infoObj))
+			}
+		})
+	}
+}
+
+func BenchmarkASDU_GetMeasuredValueFloat(b *testing.B) {
+	a := NewEmptyASDU(ParamsWide)
+	if err := a.UnmarshalBinary(benchASDU()); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = a.GetMeasuredValueFloat()
+	}
+}
+
+func BenchmarkASDU_RangeMeasuredValueFloat(b *testing.B) {
+	a := NewEmptyASDU(ParamsWide)
+	if err := a.UnmarshalBinary(benchASDU()); err != nil {
+		b.Fatal(err)
+	}
+	var sum float32
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = a.RangeMeasuredValueFloat(func(v MeasuredValueFloatInfo) bool {
+			sum += v.Value
+			return true
+		})
+	}
+}
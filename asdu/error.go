@@ -26,6 +26,8 @@ var (
 	ErrLengthOutOfRange = fmt.Errorf("asdu: asdu filed length large than max %d", ASDUSizeMax)
 	ErrNotAnyObjInfo    = errors.New("asdu: not any object information")
 	ErrTypeIDNotMatch   = errors.New("asdu: type identifier doesn't match call or time tag")
+	ErrTruncatedObject  = errors.New("asdu: information object truncated")
+	ErrCountMismatch    = errors.New("asdu: information object count mismatch")
 
 	ErrCmdCause = errors.New("asdu: cause of transmission for command not standard requirement")
 )
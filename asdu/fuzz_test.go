@@ -0,0 +1,151 @@
+//go:build go1.18
+// +build go1.18
+
+package asdu
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// decoders the Parse* decoder of each type identification, which decode with the Get* decoder
+var decoders = map[TypeID]func(a *ASDU) error{
+	M_SP_NA_1: func(a *ASDU) error { _, err := a.ParseSinglePoint(); return err },
+	M_SP_TA_1: func(a *ASDU) error { _, err := a.ParseSinglePoint(); return err },
+	M_SP_TB_1: func(a *ASDU) error { _, err := a.ParseSinglePoint(); return err },
+	M_DP_NA_1: func(a *ASDU) error { _, err := a.ParseDoublePoint(); return err },
+	M_DP_TA_1: func(a *ASDU) error { _, err := a.ParseDoublePoint(); return err },
+	M_DP_TB_1: func(a *ASDU) error { _, err := a.ParseDoublePoint(); return err },
+	M_ST_NA_1: func(a *ASDU) error { _, err := a.ParseStepPosition(); return err },
+	M_ST_TA_1: func(a *ASDU) error { _, err := a.ParseStepPosition(); return err },
+	M_ST_TB_1: func(a *ASDU) error { _, err := a.ParseStepPosition(); return err },
+	M_BO_NA_1: func(a *ASDU) error { _, err := a.ParseBitString32(); return err },
+	M_BO_TA_1: func(a *ASDU) error { _, err := a.ParseBitString32(); return err },
+	M_BO_TB_1: func(a *ASDU) error { _, err := a.ParseBitString32(); return err },
+	M_ME_NA_1: func(a *ASDU) error { _, err := a.ParseMeasuredValueNormal(); return err },
+	M_ME_TA_1: func(a *ASDU) error { _, err := a.ParseMeasuredValueNormal(); return err },
+	M_ME_TD_1: func(a *ASDU) error { _, err := a.ParseMeasuredValueNormal(); return err },
+	M_ME_ND_1: func(a *ASDU) error { _, err := a.ParseMeasuredValueNormal(); return err },
+	M_ME_NB_1: func(a *ASDU) error { _, err := a.ParseMeasuredValueScaled(); return err },
+	M_ME_TB_1: func(a *ASDU) error { _, err := a.ParseMeasuredValueScaled(); return err },
+	M_ME_TE_1: func(a *ASDU) error { _, err := a.ParseMeasuredValueScaled(); return err },
+	M_ME_NC_1: func(a *ASDU) error { _, err := a.ParseMeasuredValueFloat(); return err },
+	M_ME_TC_1: func(a *ASDU) error { _, err := a.ParseMeasuredValueFloat(); return err },
+	M_ME_TF_1: func(a *ASDU) error { _, err := a.ParseMeasuredValueFloat(); return err },
+	M_IT_NA_1: func(a *ASDU) error { _, err := a.ParseIntegratedTotals(); return err },
+	M_IT_TA_1: func(a *ASDU) error { _, err := a.ParseIntegratedTotals(); return err },
+	M_IT_TB_1: func(a *ASDU) error { _, err := a.ParseIntegratedTotals(); return err },
+	M_EP_TA_1: func(a *ASDU) error { _, err := a.ParseEventOfProtectionEquipment(); return err },
+	M_EP_TD_1: func(a *ASDU) error { _, err := a.ParseEventOfProtectionEquipment(); return err },
+	M_EP_TB_1: func(a *ASDU) error { _, err := a.ParsePackedStartEventsOfProtectionEquipment(); return err },
+	M_EP_TE_1: func(a *ASDU) error { _, err := a.ParsePackedStartEventsOfProtectionEquipment(); return err },
+	M_EP_TC_1: func(a *ASDU) error { _, err := a.ParsePackedOutputCircuitInfo(); return err },
+	M_EP_TF_1: func(a *ASDU) error { _, err := a.ParsePackedOutputCircuitInfo(); return err },
+	M_PS_NA_1: func(a *ASDU) error { _, err := a.ParsePackedSinglePointWithSCD(); return err },
+	M_EI_NA_1: func(a *ASDU) error { _, _, err := a.ParseEndOfInitialization(); return err },
+
+	C_SC_NA_1: func(a *ASDU) error { _, err := a.ParseSingleCmd(); return err },
+	C_SC_TA_1: func(a *ASDU) error { _, err := a.ParseSingleCmd(); return err },
+	C_DC_NA_1: func(a *ASDU) error { _, err := a.ParseDoubleCmd(); return err },
+	C_DC_TA_1: func(a *ASDU) error { _, err := a.ParseDoubleCmd(); return err },
+	C_RC_NA_1: func(a *ASDU) error { _, err := a.ParseStepCmd(); return err },
+	C_RC_TA_1: func(a *ASDU) error { _, err := a.ParseStepCmd(); return err },
+	C_SE_NA_1: func(a *ASDU) error { _, err := a.ParseSetpointNormalCmd(); return err },
+	C_SE_TA_1: func(a *ASDU) error { _, err := a.ParseSetpointNormalCmd(); return err },
+	C_SE_NB_1: func(a *ASDU) error { _, err := a.ParseSetpointCmdScaled(); return err },
+	C_SE_TB_1: func(a *ASDU) error { _, err := a.ParseSetpointCmdScaled(); return err },
+	C_SE_NC_1: func(a *ASDU) error { _, err := a.ParseSetpointFloatCmd(); return err },
+	C_SE_TC_1: func(a *ASDU) error { _, err := a.ParseSetpointFloatCmd(); return err },
+	C_BO_NA_1: func(a *ASDU) error { _, err := a.ParseBitsString32Cmd(); return err },
+	C_BO_TA_1: func(a *ASDU) error { _, err := a.ParseBitsString32Cmd(); return err },
+
+	C_IC_NA_1: func(a *ASDU) error { _, _, err := a.ParseInterrogationCmd(); return err },
+	C_CI_NA_1: func(a *ASDU) error { _, _, err := a.ParseCounterInterrogationCmd(); return err },
+	C_RD_NA_1: func(a *ASDU) error { _, err := a.ParseReadCmd(); return err },
+	C_CS_NA_1: func(a *ASDU) error { _, _, err := a.ParseClockSynchronizationCmd(); return err },
+	C_TS_NA_1: func(a *ASDU) error { _, _, err := a.ParseTestCommand(); return err },
+	C_RP_NA_1: func(a *ASDU) error { _, _, err := a.ParseResetProcessCmd(); return err },
+	C_CD_NA_1: func(a *ASDU) error { _, _, err := a.ParseDelayAcquireCommand(); return err },
+	C_TS_TA_1: func(a *ASDU) error { _, _, _, err := a.ParseTestCommandCP56Time2a(); return err },
+
+	P_ME_NA_1: func(a *ASDU) error { _, err := a.ParseParameterNormal(); return err },
+	P_ME_NB_1: func(a *ASDU) error { _, err := a.ParseParameterScaled(); return err },
+	P_ME_NC_1: func(a *ASDU) error { _, err := a.ParseParameterFloat(); return err },
+	P_AC_NA_1: func(a *ASDU) error { _, err := a.ParseParameterActivation(); return err },
+}
+
+// fuzzSeeds the raw asdu of the UnmarshalBinary table test,
+// and one or two information objects of every decoder type
+func fuzzSeeds() [][]byte {
+	seeds := [][]byte{
+		{0x0b, 0x01, 0x06, 0x80},
+		{0x7d, 0x01, 0x06, 0x00, 0x80, 0x60},
+		{0x0b, 0x01, 0x06, 0x80, 0x00, 0x01, 0x02, 0x03},
+		{0x0b, 0x01, 0x06, 0x00, 0x34, 0x12, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05},
+		{0x01, 0x82, 0x14, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01, 0x00},
+	}
+	for typeID := range decoders {
+		size, _ := GetInfoObjSize(typeID)
+		seeds = append(seeds,
+			append([]byte{byte(typeID), 0x01, 0x06, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00}, make([]byte, size)...),
+			append([]byte{byte(typeID), 0x82, 0x14, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00}, make([]byte, 2*size)...))
+	}
+	return seeds
+}
+
+func TestDecoders(t *testing.T) {
+	for typeID := range decoders {
+		if _, err := GetInfoObjSize(typeID); err != nil {
+			t.Errorf("GetInfoObjSize(%v) error = %v", typeID, err)
+		}
+	}
+}
+
+func FuzzASDU_UnmarshalBinary(f *testing.F) {
+	for _, seed := range fuzzSeeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, params := range []*Params{ParamsNarrow, ParamsWide} {
+			a := NewEmptyASDU(params)
+			if err := a.UnmarshalBinary(data); err != nil {
+				continue
+			}
+			_ = a.String()
+			if decode, ok := decoders[a.Type]; ok {
+				if err := decode(a.Clone()); err != nil &&
+					!errors.Is(err, ErrInvalidTimeTag) && !errors.Is(err, ErrCountMismatch) {
+					t.Errorf("decode % x error = %v", data, err)
+				}
+			}
+			raw, err := a.MarshalBinary()
+			if err != nil {
+				continue
+			}
+			if !bytes.HasPrefix(data, raw) {
+				t.Errorf("MarshalBinary() = % x, want prefix of % x", raw, data)
+			}
+		}
+	})
+}
+
+func FuzzASDU_Parse(f *testing.F) {
+	for _, seed := range fuzzSeeds() {
+		if len(seed) > 6 {
+			f.Add(seed[0], seed[1], seed[6:])
+		}
+	}
+	f.Fuzz(func(t *testing.T, typeID byte, variable byte, infoObj []byte) {
+		decode, ok := decoders[TypeID(typeID)]
+		if !ok {
+			return
+		}
+		a := &ASDU{
+			Params:     ParamsWide,
+			Identifier: Identifier{Type: TypeID(typeID), Variable: ParseVariableStruct(variable)},
+			infoObj:    infoObj,
+		}
+		_ = decode(a)
+	})
+}
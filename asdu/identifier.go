@@ -207,6 +207,14 @@ var infoObjSize = map[TypeID]int{
 	C_SE_NC_1: 5,
 	C_BO_NA_1: 4,
 
+	C_SC_TA_1: 8,
+	C_DC_TA_1: 8,
+	C_RC_TA_1: 8,
+	C_SE_TA_1: 10,
+	C_SE_TB_1: 10,
+	C_SE_TC_1: 12,
+	C_BO_TA_1: 11,
+
 	M_EI_NA_1: 1,
 
 	C_IC_NA_1: 1,
@@ -216,6 +224,7 @@ var infoObjSize = map[TypeID]int{
 	C_TS_NA_1: 2,
 	C_RP_NA_1: 1,
 	C_CD_NA_1: 2,
+	C_TS_TA_1: 9,
 
 	P_ME_NA_1: 3,
 	P_ME_NB_1: 3,
@@ -494,6 +503,11 @@ func (sf CauseOfTransmission) Value() byte {
 	return byte(v)
 }
 
+// String 返回传送原因的语义
+func (sf Cause) String() string {
+	return causeSemantics[sf&0x3f]
+}
+
 // String 返回Cause的字符串,包含相应应用的",neg" and ",test"
 func (sf CauseOfTransmission) String() string {
 	s := "COT<" + causeSemantics[sf.Cause]
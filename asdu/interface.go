@@ -5,6 +5,7 @@
 package asdu
 
 import (
+	"context"
 	"net"
 )
 
@@ -14,3 +15,31 @@ type Connect interface {
 	Send(a *ASDU) error
 	UnderlyingConn() net.Conn
 }
+
+// ContextConnect 可选的Connect扩展, SendContext在发送缓冲满时阻塞等待, 直到有空间,连接断开或ctx结束
+type ContextConnect interface {
+	Connect
+	SendContext(ctx context.Context, a *ASDU) error
+}
+
+// WithContext 返回以ctx发送的Connect, 用于Single, MeasuredValueFloat等发送函数.
+// c实现了ContextConnect时Send调用SendContext, 否则在ctx未结束时调用c.Send.
+func WithContext(ctx context.Context, c Connect) Connect {
+	return &ctxConnect{c, ctx}
+}
+
+type ctxConnect struct {
+	Connect
+	ctx context.Context
+}
+
+// Send imp interface Connect
+func (sf *ctxConnect) Send(a *ASDU) error {
+	if c, ok := sf.Connect.(ContextConnect); ok {
+		return c.SendContext(sf.ctx, a)
+	}
+	if err := sf.ctx.Err(); err != nil {
+		return err
+	}
+	return sf.Connect.Send(a)
+}
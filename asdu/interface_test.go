@@ -0,0 +1,54 @@
+package asdu
+
+import (
+	"context"
+	"testing"
+)
+
+// ctxConn records the context of SendContext
+type ctxConn struct {
+	recorder
+	ctx context.Context
+}
+
+func (sf *ctxConn) SendContext(ctx context.Context, a *ASDU) error {
+	sf.ctx = ctx
+	return sf.Send(a)
+}
+
+func TestWithContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name     string
+		c        Connect
+		ctx      context.Context
+		wantErr  error
+		wantSent int
+	}{
+		{"connect", &recorder{}, context.Background(), nil, 1},
+		{"connect canceled", &recorder{}, canceled, context.Canceled, 0},
+		{"context connect", &ctxConn{}, canceled, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Single(WithContext(tt.ctx, tt.c), false, CauseOfTransmission{Cause: Spontaneous}, 1, SinglePointInfo{Ioa: 1})
+			if err != tt.wantErr {
+				t.Fatalf("Single() error = %v, want %v", err, tt.wantErr)
+			}
+			var sent int
+			switch c := tt.c.(type) {
+			case *recorder:
+				sent = len(c.get())
+			case *ctxConn:
+				sent = len(c.get())
+				if c.ctx != tt.ctx {
+					t.Errorf("SendContext() ctx = %v, want %v", c.ctx, tt.ctx)
+				}
+			}
+			if sent != tt.wantSent {
+				t.Errorf("sent %d asdu, want %d", sent, tt.wantSent)
+			}
+		})
+	}
+}
@@ -1130,328 +1130,428 @@ func PackedSinglePointWithSCD(c Connect, isSequence bool, coa CauseOfTransmissio
 // GetSinglePoint [M_SP_NA_1], [M_SP_TA_1] or [M_SP_TB_1] 获取单点信息信息体集合
 func (sf *ASDU) GetSinglePoint() []SinglePointInfo {
 	info := make([]SinglePointInfo, 0, sf.Variable.Number)
+	sf.rangeSinglePoint(func(v SinglePointInfo) bool {
+		info = append(info, v)
+		return true
+	})
+	return info
+}
+
+// rangeSinglePoint 依次解码信息体, f 返回false时停止, 解码不改变sf的信息体
+func (sf *ASDU) rangeSinglePoint(f func(SinglePointInfo) bool) {
+	a := sf.view()
 	infoObjAddr := InfoObjAddr(0)
-	for i, once := 0, false; i < int(sf.Variable.Number); i++ {
-		if !sf.Variable.IsSequence || !once {
+	for i, once := 0, false; i < int(a.Variable.Number); i++ {
+		if !a.Variable.IsSequence || !once {
 			once = true
-			infoObjAddr = sf.DecodeInfoObjAddr()
+			infoObjAddr = a.DecodeInfoObjAddr()
 		} else {
 			infoObjAddr++
 		}
-		value := sf.DecodeByte()
+		value := a.DecodeByte()
 
 		var t time.Time
-		switch sf.Type {
+		switch a.Type {
 		case M_SP_NA_1:
 		case M_SP_TA_1:
-			t = sf.DecodeCP24Time2a()
+			t = a.DecodeCP24Time2a()
 		case M_SP_TB_1:
-			t = sf.DecodeCP56Time2a()
+			t = a.DecodeCP56Time2a()
 		default:
 			panic(ErrTypeIDNotMatch)
 		}
 
-		info = append(info, SinglePointInfo{
+		if !f(SinglePointInfo{
 			Ioa:   infoObjAddr,
 			Value: value&0x01 == 0x01,
 			Qds:   QualityDescriptor(value & 0xf0),
-			Time:  t})
+			Time:  t}) {
+			return
+		}
 	}
-	return info
 }
 
 // GetDoublePoint [M_DP_NA_1], [M_DP_TA_1] or [M_DP_TB_1] 获得双点信息体集合
 func (sf *ASDU) GetDoublePoint() []DoublePointInfo {
 	info := make([]DoublePointInfo, 0, sf.Variable.Number)
+	sf.rangeDoublePoint(func(v DoublePointInfo) bool {
+		info = append(info, v)
+		return true
+	})
+	return info
+}
+
+// rangeDoublePoint 依次解码信息体, f 返回false时停止, 解码不改变sf的信息体
+func (sf *ASDU) rangeDoublePoint(f func(DoublePointInfo) bool) {
+	a := sf.view()
 	infoObjAddr := InfoObjAddr(0)
-	for i, once := 0, false; i < int(sf.Variable.Number); i++ {
-		if !sf.Variable.IsSequence || !once {
+	for i, once := 0, false; i < int(a.Variable.Number); i++ {
+		if !a.Variable.IsSequence || !once {
 			once = true
-			infoObjAddr = sf.DecodeInfoObjAddr()
+			infoObjAddr = a.DecodeInfoObjAddr()
 		} else {
 			infoObjAddr++
 		}
-		value := sf.DecodeByte()
+		value := a.DecodeByte()
 
 		var t time.Time
-		switch sf.Type {
+		switch a.Type {
 		case M_DP_NA_1:
 		case M_DP_TA_1:
-			t = sf.DecodeCP24Time2a()
+			t = a.DecodeCP24Time2a()
 		case M_DP_TB_1:
-			t = sf.DecodeCP56Time2a()
+			t = a.DecodeCP56Time2a()
 		default:
 			panic(ErrTypeIDNotMatch)
 		}
 
-		info = append(info, DoublePointInfo{
+		if !f(DoublePointInfo{
 			Ioa:   infoObjAddr,
 			Value: DoublePoint(value & 0x03),
 			Qds:   QualityDescriptor(value & 0xf0),
-			Time:  t})
+			Time:  t}) {
+			return
+		}
 	}
-	return info
 }
 
 // GetStepPosition [M_ST_NA_1], [M_ST_TA_1] or [M_ST_TB_1] 获得步位置信息体集合
 func (sf *ASDU) GetStepPosition() []StepPositionInfo {
 	info := make([]StepPositionInfo, 0, sf.Variable.Number)
+	sf.rangeStepPosition(func(v StepPositionInfo) bool {
+		info = append(info, v)
+		return true
+	})
+	return info
+}
+
+// rangeStepPosition 依次解码信息体, f 返回false时停止, 解码不改变sf的信息体
+func (sf *ASDU) rangeStepPosition(f func(StepPositionInfo) bool) {
+	a := sf.view()
 	infoObjAddr := InfoObjAddr(0)
-	for i, once := 0, false; i < int(sf.Variable.Number); i++ {
-		if !sf.Variable.IsSequence || !once {
+	for i, once := 0, false; i < int(a.Variable.Number); i++ {
+		if !a.Variable.IsSequence || !once {
 			once = true
-			infoObjAddr = sf.DecodeInfoObjAddr()
+			infoObjAddr = a.DecodeInfoObjAddr()
 		} else {
 			infoObjAddr++
 		}
-		value := ParseStepPosition(sf.DecodeByte())
-		qds := QualityDescriptor(sf.DecodeByte())
+		value := ParseStepPosition(a.DecodeByte())
+		qds := QualityDescriptor(a.DecodeByte())
 
 		var t time.Time
-		switch sf.Type {
+		switch a.Type {
 		case M_ST_NA_1:
 		case M_ST_TA_1:
-			t = sf.DecodeCP24Time2a()
+			t = a.DecodeCP24Time2a()
 		case M_ST_TB_1:
-			t = sf.DecodeCP56Time2a()
+			t = a.DecodeCP56Time2a()
 		default:
 			panic(ErrTypeIDNotMatch)
 		}
 
-		info = append(info, StepPositionInfo{
+		if !f(StepPositionInfo{
 			Ioa:   infoObjAddr,
 			Value: value,
 			Qds:   qds,
-			Time:  t})
+			Time:  t}) {
+			return
+		}
 	}
-	return info
 }
 
 // GetBitString32 [M_BO_NA_1], [M_BO_TA_1] or [M_BO_TB_1] 获得比特位串信息体集合
 func (sf *ASDU) GetBitString32() []BitString32Info {
 	info := make([]BitString32Info, 0, sf.Variable.Number)
+	sf.rangeBitString32(func(v BitString32Info) bool {
+		info = append(info, v)
+		return true
+	})
+	return info
+}
+
+// rangeBitString32 依次解码信息体, f 返回false时停止, 解码不改变sf的信息体
+func (sf *ASDU) rangeBitString32(f func(BitString32Info) bool) {
+	a := sf.view()
 	infoObjAddr := InfoObjAddr(0)
-	for i, once := 0, false; i < int(sf.Variable.Number); i++ {
-		if !sf.Variable.IsSequence || !once {
+	for i, once := 0, false; i < int(a.Variable.Number); i++ {
+		if !a.Variable.IsSequence || !once {
 			once = true
-			infoObjAddr = sf.DecodeInfoObjAddr()
+			infoObjAddr = a.DecodeInfoObjAddr()
 		} else {
 			infoObjAddr++
 		}
 
-		value := sf.DecodeBitsString32()
-		qds := QualityDescriptor(sf.DecodeByte())
+		value := a.DecodeBitsString32()
+		qds := QualityDescriptor(a.DecodeByte())
 
 		var t time.Time
-		switch sf.Type {
+		switch a.Type {
 		case M_BO_NA_1:
 		case M_BO_TA_1:
-			t = sf.DecodeCP24Time2a()
+			t = a.DecodeCP24Time2a()
 		case M_BO_TB_1:
-			t = sf.DecodeCP56Time2a()
+			t = a.DecodeCP56Time2a()
 		default:
 			panic(ErrTypeIDNotMatch)
 		}
 
-		info = append(info, BitString32Info{
+		if !f(BitString32Info{
 			Ioa:   infoObjAddr,
 			Value: value,
 			Qds:   qds,
-			Time:  t})
+			Time:  t}) {
+			return
+		}
 	}
-	return info
 }
 
 // GetMeasuredValueNormal [M_ME_NA_1], [M_ME_TA_1],[ M_ME_TD_1] or [M_ME_ND_1] 获得测量值,规一化值信息体集合
 func (sf *ASDU) GetMeasuredValueNormal() []MeasuredValueNormalInfo {
 	info := make([]MeasuredValueNormalInfo, 0, sf.Variable.Number)
+	sf.rangeMeasuredValueNormal(func(v MeasuredValueNormalInfo) bool {
+		info = append(info, v)
+		return true
+	})
+	return info
+}
+
+// rangeMeasuredValueNormal 依次解码信息体, f 返回false时停止, 解码不改变sf的信息体
+func (sf *ASDU) rangeMeasuredValueNormal(f func(MeasuredValueNormalInfo) bool) {
+	a := sf.view()
 	infoObjAddr := InfoObjAddr(0)
-	for i, once := 0, false; i < int(sf.Variable.Number); i++ {
-		if !sf.Variable.IsSequence || !once {
+	for i, once := 0, false; i < int(a.Variable.Number); i++ {
+		if !a.Variable.IsSequence || !once {
 			once = true
-			infoObjAddr = sf.DecodeInfoObjAddr()
+			infoObjAddr = a.DecodeInfoObjAddr()
 		} else {
 			infoObjAddr++
 		}
 
-		value := sf.DecodeNormalize()
+		value := a.DecodeNormalize()
 
 		var t time.Time
 		var qds QualityDescriptor
-		switch sf.Type {
+		switch a.Type {
 		case M_ME_NA_1:
-			qds = QualityDescriptor(sf.DecodeByte())
+			qds = QualityDescriptor(a.DecodeByte())
 		case M_ME_TA_1:
-			qds = QualityDescriptor(sf.DecodeByte())
-			t = sf.DecodeCP24Time2a()
+			qds = QualityDescriptor(a.DecodeByte())
+			t = a.DecodeCP24Time2a()
 		case M_ME_TD_1:
-			qds = QualityDescriptor(sf.DecodeByte())
-			t = sf.DecodeCP56Time2a()
+			qds = QualityDescriptor(a.DecodeByte())
+			t = a.DecodeCP56Time2a()
 		case M_ME_ND_1: // 不带品质
 		default:
 			panic(ErrTypeIDNotMatch)
 		}
 
-		info = append(info, MeasuredValueNormalInfo{
+		if !f(MeasuredValueNormalInfo{
 			Ioa:   infoObjAddr,
 			Value: value,
 			Qds:   qds,
-			Time:  t})
+			Time:  t}) {
+			return
+		}
 	}
-	return info
 }
 
 // GetMeasuredValueScaled [M_ME_NB_1], [M_ME_TB_1] or [M_ME_TE_1] 获得测量值，标度化值信息体集合
 func (sf *ASDU) GetMeasuredValueScaled() []MeasuredValueScaledInfo {
 	info := make([]MeasuredValueScaledInfo, 0, sf.Variable.Number)
+	sf.rangeMeasuredValueScaled(func(v MeasuredValueScaledInfo) bool {
+		info = append(info, v)
+		return true
+	})
+	return info
+}
+
+// rangeMeasuredValueScaled 依次解码信息体, f 返回false时停止, 解码不改变sf的信息体
+func (sf *ASDU) rangeMeasuredValueScaled(f func(MeasuredValueScaledInfo) bool) {
+	a := sf.view()
 	infoObjAddr := InfoObjAddr(0)
-	for i, once := 0, false; i < int(sf.Variable.Number); i++ {
-		if !sf.Variable.IsSequence || !once {
+	for i, once := 0, false; i < int(a.Variable.Number); i++ {
+		if !a.Variable.IsSequence || !once {
 			once = true
-			infoObjAddr = sf.DecodeInfoObjAddr()
+			infoObjAddr = a.DecodeInfoObjAddr()
 		} else {
 			infoObjAddr++
 		}
 
-		value := sf.DecodeScaled()
-		qds := QualityDescriptor(sf.DecodeByte())
+		value := a.DecodeScaled()
+		qds := QualityDescriptor(a.DecodeByte())
 
 		var t time.Time
-		switch sf.Type {
+		switch a.Type {
 		case M_ME_NB_1:
 		case M_ME_TB_1:
-			t = sf.DecodeCP24Time2a()
+			t = a.DecodeCP24Time2a()
 		case M_ME_TE_1:
-			t = sf.DecodeCP56Time2a()
+			t = a.DecodeCP56Time2a()
 		default:
 			panic(ErrTypeIDNotMatch)
 		}
 
-		info = append(info, MeasuredValueScaledInfo{
+		if !f(MeasuredValueScaledInfo{
 			Ioa:   infoObjAddr,
 			Value: value,
 			Qds:   qds,
-			Time:  t})
+			Time:  t}) {
+			return
+		}
 	}
-	return info
 }
 
 // GetMeasuredValueFloat [M_ME_NC_1], [M_ME_TC_1] or [M_ME_TF_1].获得测量值,短浮点数信息体集合
 func (sf *ASDU) GetMeasuredValueFloat() []MeasuredValueFloatInfo {
 	info := make([]MeasuredValueFloatInfo, 0, sf.Variable.Number)
+	sf.rangeMeasuredValueFloat(func(v MeasuredValueFloatInfo) bool {
+		info = append(info, v)
+		return true
+	})
+	return info
+}
+
+// rangeMeasuredValueFloat 依次解码信息体, f 返回false时停止, 解码不改变sf的信息体
+func (sf *ASDU) rangeMeasuredValueFloat(f func(MeasuredValueFloatInfo) bool) {
+	a := sf.view()
 	infoObjAddr := InfoObjAddr(0)
-	for i, once := 0, false; i < int(sf.Variable.Number); i++ {
-		if !sf.Variable.IsSequence || !once {
+	for i, once := 0, false; i < int(a.Variable.Number); i++ {
+		if !a.Variable.IsSequence || !once {
 			once = true
-			infoObjAddr = sf.DecodeInfoObjAddr()
+			infoObjAddr = a.DecodeInfoObjAddr()
 		} else {
 			infoObjAddr++
 		}
 
-		value := sf.DecodeFloat32()
-		qua := sf.DecodeByte() & 0xf1
+		value := a.DecodeFloat32()
+		qua := a.DecodeByte() & 0xf1
 
 		var t time.Time
-		switch sf.Type {
+		switch a.Type {
 		case M_ME_NC_1:
 		case M_ME_TC_1:
-			t = sf.DecodeCP24Time2a()
+			t = a.DecodeCP24Time2a()
 		case M_ME_TF_1:
-			t = sf.DecodeCP56Time2a()
+			t = a.DecodeCP56Time2a()
 		default:
 			panic(ErrTypeIDNotMatch)
 		}
-		info = append(info, MeasuredValueFloatInfo{
+		if !f(MeasuredValueFloatInfo{
 			Ioa:   infoObjAddr,
 			Value: value,
 			Qds:   QualityDescriptor(qua),
-			Time:  t})
+			Time:  t}) {
+			return
+		}
 	}
-	return info
 }
 
 // GetIntegratedTotals [M_IT_NA_1], [M_IT_TA_1] or [M_IT_TB_1]. 获得累计量信息体集合
 func (sf *ASDU) GetIntegratedTotals() []BinaryCounterReadingInfo {
 	info := make([]BinaryCounterReadingInfo, 0, sf.Variable.Number)
+	sf.rangeIntegratedTotals(func(v BinaryCounterReadingInfo) bool {
+		info = append(info, v)
+		return true
+	})
+	return info
+}
+
+// rangeIntegratedTotals 依次解码信息体, f 返回false时停止, 解码不改变sf的信息体
+func (sf *ASDU) rangeIntegratedTotals(f func(BinaryCounterReadingInfo) bool) {
+	a := sf.view()
 	infoObjAddr := InfoObjAddr(0)
-	for i, once := 0, false; i < int(sf.Variable.Number); i++ {
-		if !sf.Variable.IsSequence || !once {
+	for i, once := 0, false; i < int(a.Variable.Number); i++ {
+		if !a.Variable.IsSequence || !once {
 			once = true
-			infoObjAddr = sf.DecodeInfoObjAddr()
+			infoObjAddr = a.DecodeInfoObjAddr()
 		} else {
 			infoObjAddr++
 		}
 
-		value := sf.DecodeBinaryCounterReading()
+		value := a.DecodeBinaryCounterReading()
 
 		var t time.Time
-		switch sf.Type {
+		switch a.Type {
 		case M_IT_NA_1:
 		case M_IT_TA_1:
-			t = sf.DecodeCP24Time2a()
+			t = a.DecodeCP24Time2a()
 		case M_IT_TB_1:
-			t = sf.DecodeCP56Time2a()
+			t = a.DecodeCP56Time2a()
 		default:
 			panic(ErrTypeIDNotMatch)
 		}
-		info = append(info, BinaryCounterReadingInfo{
+		if !f(BinaryCounterReadingInfo{
 			Ioa:   infoObjAddr,
 			Value: value,
-			Time:  t})
+			Time:  t}) {
+			return
+		}
 	}
-	return info
 }
 
 // GetEventOfProtectionEquipment [M_EP_TA_1] [M_EP_TD_1] 获取继电器保护设备事件信息体
 func (sf *ASDU) GetEventOfProtectionEquipment() []EventOfProtectionEquipmentInfo {
 	info := make([]EventOfProtectionEquipmentInfo, 0, sf.Variable.Number)
+	sf.rangeEventOfProtectionEquipment(func(v EventOfProtectionEquipmentInfo) bool {
+		info = append(info, v)
+		return true
+	})
+	return info
+}
+
+// rangeEventOfProtectionEquipment 依次解码信息体, f 返回false时停止, 解码不改变sf的信息体
+func (sf *ASDU) rangeEventOfProtectionEquipment(f func(EventOfProtectionEquipmentInfo) bool) {
+	a := sf.view()
 	infoObjAddr := InfoObjAddr(0)
-	for i, once := 0, false; i < int(sf.Variable.Number); i++ {
-		if !sf.Variable.IsSequence || !once {
+	for i, once := 0, false; i < int(a.Variable.Number); i++ {
+		if !a.Variable.IsSequence || !once {
 			once = true
-			infoObjAddr = sf.DecodeInfoObjAddr()
+			infoObjAddr = a.DecodeInfoObjAddr()
 		} else {
 			infoObjAddr++
 		}
 
-		value := sf.DecodeByte()
-		msec := sf.DecodeCP16Time2a()
+		value := a.DecodeByte()
+		msec := a.DecodeCP16Time2a()
 		var t time.Time
-		switch sf.Type {
+		switch a.Type {
 		case M_EP_TA_1:
-			t = sf.DecodeCP24Time2a()
+			t = a.DecodeCP24Time2a()
 		case M_EP_TD_1:
-			t = sf.DecodeCP56Time2a()
+			t = a.DecodeCP56Time2a()
 		default:
 			panic(ErrTypeIDNotMatch)
 		}
-		info = append(info, EventOfProtectionEquipmentInfo{
+		if !f(EventOfProtectionEquipmentInfo{
 			Ioa:   infoObjAddr,
 			Event: SingleEvent(value & 0x03),
 			Qdp:   QualityDescriptorProtection(value & 0xf1),
 			Msec:  msec,
-			Time:  t})
+			Time:  t}) {
+			return
+		}
 	}
-	return info
 }
 
 // GetPackedStartEventsOfProtectionEquipment [M_EP_TB_1] [M_EP_TE_1] 获取继电器保护设备事件信息体
 func (sf *ASDU) GetPackedStartEventsOfProtectionEquipment() PackedStartEventsOfProtectionEquipmentInfo {
+	a := sf.view()
 	info := PackedStartEventsOfProtectionEquipmentInfo{}
 
-	if sf.Variable.IsSequence || sf.Variable.Number != 1 {
+	if a.Variable.IsSequence || a.Variable.Number != 1 {
 		return info
 	}
 
-	info.Ioa = sf.DecodeInfoObjAddr()
-	info.Event = StartEvent(sf.DecodeByte())
-	info.Qdp = QualityDescriptorProtection(sf.DecodeByte() & 0xf1)
-	info.Msec = sf.DecodeCP16Time2a()
-	switch sf.Type {
+	info.Ioa = a.DecodeInfoObjAddr()
+	info.Event = StartEvent(a.DecodeByte())
+	info.Qdp = QualityDescriptorProtection(a.DecodeByte() & 0xf1)
+	info.Msec = a.DecodeCP16Time2a()
+	switch a.Type {
 	case M_EP_TB_1:
-		info.Time = sf.DecodeCP24Time2a()
+		info.Time = a.DecodeCP24Time2a()
 	case M_EP_TE_1:
-		info.Time = sf.DecodeCP56Time2a()
+		info.Time = a.DecodeCP56Time2a()
 	default:
 		panic(ErrTypeIDNotMatch)
 	}
@@ -1460,21 +1560,22 @@ func (sf *ASDU) GetPackedStartEventsOfProtectionEquipment() PackedStartEventsOfP
 
 // GetPackedOutputCircuitInfo [M_EP_TC_1] [M_EP_TF_1] 获取继电器保护设备成组输出电路信息信息体
 func (sf *ASDU) GetPackedOutputCircuitInfo() PackedOutputCircuitInfoInfo {
+	a := sf.view()
 	info := PackedOutputCircuitInfoInfo{}
 
-	if sf.Variable.IsSequence || sf.Variable.Number != 1 {
+	if a.Variable.IsSequence || a.Variable.Number != 1 {
 		return info
 	}
 
-	info.Ioa = sf.DecodeInfoObjAddr()
-	info.Oci = OutputCircuitInfo(sf.DecodeByte())
-	info.Qdp = QualityDescriptorProtection(sf.DecodeByte() & 0xf1)
-	info.Msec = sf.DecodeCP16Time2a()
-	switch sf.Type {
+	info.Ioa = a.DecodeInfoObjAddr()
+	info.Oci = OutputCircuitInfo(a.DecodeByte())
+	info.Qdp = QualityDescriptorProtection(a.DecodeByte() & 0xf1)
+	info.Msec = a.DecodeCP16Time2a()
+	switch a.Type {
 	case M_EP_TC_1:
-		info.Time = sf.DecodeCP24Time2a()
+		info.Time = a.DecodeCP24Time2a()
 	case M_EP_TF_1:
-		info.Time = sf.DecodeCP56Time2a()
+		info.Time = a.DecodeCP56Time2a()
 	default:
 		panic(ErrTypeIDNotMatch)
 	}
@@ -1484,20 +1585,31 @@ func (sf *ASDU) GetPackedOutputCircuitInfo() PackedOutputCircuitInfoInfo {
 // GetPackedSinglePointWithSCD [M_PS_NA_1]. 获得带变位检出的成组单点信息
 func (sf *ASDU) GetPackedSinglePointWithSCD() []PackedSinglePointWithSCDInfo {
 	info := make([]PackedSinglePointWithSCDInfo, 0, sf.Variable.Number)
+	sf.rangePackedSinglePointWithSCD(func(v PackedSinglePointWithSCDInfo) bool {
+		info = append(info, v)
+		return true
+	})
+	return info
+}
+
+// rangePackedSinglePointWithSCD 依次解码信息体, f 返回false时停止, 解码不改变sf的信息体
+func (sf *ASDU) rangePackedSinglePointWithSCD(f func(PackedSinglePointWithSCDInfo) bool) {
+	a := sf.view()
 	infoObjAddr := InfoObjAddr(0)
-	for i, once := 0, false; i < int(sf.Variable.Number); i++ {
-		if !sf.Variable.IsSequence || !once {
+	for i, once := 0, false; i < int(a.Variable.Number); i++ {
+		if !a.Variable.IsSequence || !once {
 			once = true
-			infoObjAddr = sf.DecodeInfoObjAddr()
+			infoObjAddr = a.DecodeInfoObjAddr()
 		} else {
 			infoObjAddr++
 		}
-		scd := sf.DecodeStatusAndStatusChangeDetection()
-		qds := QualityDescriptor(sf.DecodeByte())
-		info = append(info, PackedSinglePointWithSCDInfo{
+		scd := a.DecodeStatusAndStatusChangeDetection()
+		qds := QualityDescriptor(a.DecodeByte())
+		if !f(PackedSinglePointWithSCDInfo{
 			Ioa: infoObjAddr,
 			Scd: scd,
-			Qds: qds})
+			Qds: qds}) {
+			return
+		}
 	}
-	return info
 }
@@ -34,5 +34,6 @@ func EndOfInitialization(c Connect, coa CauseOfTransmission, ca CommonAddr, ioa
 
 // GetEndOfInitialization get GetEndOfInitialization for asdu when the identification [M_EI_NA_1]
 func (sf *ASDU) GetEndOfInitialization() (InfoObjAddr, CauseOfInitial) {
-	return sf.DecodeInfoObjAddr(), ParseCauseOfInitial(sf.infoObj[0])
+	a := sf.view()
+	return a.DecodeInfoObjAddr(), ParseCauseOfInitial(a.infoObj[0])
 }
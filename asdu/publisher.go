@@ -0,0 +1,294 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package asdu
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultPublishWindow 默认的发布缓冲窗口
+const DefaultPublishWindow = 100 * time.Millisecond
+
+// Publisher 监视方向信息对象的合并发送器, 实现了Connect.
+// 经Publisher发送的监视方向ASDU(M_SP_NA_1 ~ M_EP_TF_1)按类型标识,传送原因,源发地址和公共地址
+// 拆分为信息对象缓存一个窗口时间, 到期后合并为尽可能少的ASDU发送, 信息对象地址连续时使用SQ=1.
+// 高优先级类型(默认继电保护事件)及其它ASDU先发送已缓存的信息对象再立即发送, 保持发送的先后顺序.
+type Publisher struct {
+	c        Connect
+	window   time.Duration
+	priority map[TypeID]bool
+	onError  func(a *ASDU, err error)
+
+	mu      sync.Mutex
+	batches map[Identifier]*batch
+	order   []Identifier // 缓存的先后顺序, 用于全部发送
+	closed  bool
+}
+
+// batch 同一标识的缓存信息对象
+type batch struct {
+	ioas  []InfoObjAddr
+	elems []byte // 信息元素, 每个objSize字节
+	timer *time.Timer
+}
+
+// NewPublisher 创建发布器, window <= 0 时使用DefaultPublishWindow
+func NewPublisher(c Connect, window time.Duration) *Publisher {
+	if window <= 0 {
+		window = DefaultPublishWindow
+	}
+	return &Publisher{
+		c:      c,
+		window: window,
+		priority: map[TypeID]bool{
+			M_EP_TA_1: true, M_EP_TB_1: true, M_EP_TC_1: true,
+			M_EP_TD_1: true, M_EP_TE_1: true, M_EP_TF_1: true,
+		},
+		onError: func(*ASDU, error) {},
+		batches: make(map[Identifier]*batch),
+	}
+}
+
+// SetPriority 设置立即发送的高优先级类型, 替换默认的继电保护事件
+func (sf *Publisher) SetPriority(ids ...TypeID) *Publisher {
+	sf.mu.Lock()
+	sf.priority = make(map[TypeID]bool, len(ids))
+	for _, id := range ids {
+		sf.priority[id] = true
+	}
+	sf.mu.Unlock()
+	return sf
+}
+
+// SetErrorHandler 设置缓存的ASDU发送失败时的回调, 回调中不可调用Publisher的方法
+func (sf *Publisher) SetErrorHandler(f func(a *ASDU, err error)) *Publisher {
+	if f != nil {
+		sf.mu.Lock()
+		sf.onError = f
+		sf.mu.Unlock()
+	}
+	return sf
+}
+
+// Params imp interface Connect
+func (sf *Publisher) Params() *Params { return sf.c.Params() }
+
+// UnderlyingConn imp interface Connect
+func (sf *Publisher) UnderlyingConn() net.Conn { return sf.c.UnderlyingConn() }
+
+// Send imp interface Connect, 缓存监视方向的信息对象, 其它ASDU立即发送
+func (sf *Publisher) Send(a *ASDU) error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if sf.closed || !batchable(a.Type) || sf.priority[a.Type] {
+		if err := sf.flushAll(); err != nil {
+			return err
+		}
+		return sf.c.Send(a)
+	}
+
+	objSize, _ := GetInfoObjSize(a.Type)
+	key := a.Identifier
+	key.Variable = VariableStruct{}
+	b, ok := sf.batches[key]
+	if !ok {
+		b = &batch{}
+		sf.batches[key] = b
+		sf.order = append(sf.order, key)
+	}
+	if err := b.add(a, objSize); err != nil {
+		if len(b.ioas) == 0 {
+			sf.remove(key)
+		}
+		return err
+	}
+	// 已足够组成一个满的ASDU, 无需再等待
+	if len(b.ioas) >= sf.maxCount(objSize, false) {
+		return sf.flush(key)
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(sf.window, func() {
+			sf.mu.Lock()
+			defer sf.mu.Unlock()
+			if cur, ok := sf.batches[key]; ok && cur == b {
+				_ = sf.flush(key)
+			}
+		})
+	}
+	return nil
+}
+
+// Flush 立即发送全部缓存的信息对象
+func (sf *Publisher) Flush() error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	return sf.flushAll()
+}
+
+// Close 发送全部缓存的信息对象, 之后的ASDU不再缓存
+func (sf *Publisher) Close() error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	sf.closed = true
+	return sf.flushAll()
+}
+
+// batchable 可合并的监视方向类型
+func batchable(id TypeID) bool {
+	if id < M_SP_NA_1 || id > M_EP_TF_1 {
+		return false
+	}
+	_, err := GetInfoObjSize(id)
+	return err == nil
+}
+
+// sequenceable 允许SQ=1的类型, 带时标的类型只有SQ=0
+func sequenceable(id TypeID) bool {
+	switch id {
+	case M_SP_NA_1, M_DP_NA_1, M_ST_NA_1, M_BO_NA_1, M_ME_NA_1, M_ME_NB_1,
+		M_ME_NC_1, M_ME_ND_1, M_IT_NA_1, M_PS_NA_1:
+		return true
+	}
+	return false
+}
+
+// add 将asdu的信息对象加入缓存
+func (sf *batch) add(a *ASDU, objSize int) error {
+	n := int(a.Variable.Number)
+	if n == 0 {
+		return ErrNotAnyObjInfo
+	}
+	size := n * (a.InfoObjAddrSize + objSize)
+	if a.Variable.IsSequence {
+		size = a.InfoObjAddrSize + n*objSize
+	}
+	if size != len(a.infoObj) {
+		return ErrCountMismatch
+	}
+	v := a.view()
+	var ioa InfoObjAddr
+	for i := 0; i < n; i++ {
+		if !a.Variable.IsSequence || i == 0 {
+			ioa = v.DecodeInfoObjAddr()
+		} else {
+			ioa++
+		}
+		sf.ioas = append(sf.ioas, ioa)
+		sf.elems = append(sf.elems, v.infoObj[:objSize]...)
+		v.infoObj = v.infoObj[objSize:]
+	}
+	return nil
+}
+
+// maxCount 一个ASDU可容纳的信息对象个数
+func (sf *Publisher) maxCount(objSize int, isSequence bool) int {
+	p := sf.c.Params()
+	n := (ASDUSizeMax - p.IdentifierSize()) / (p.InfoObjAddrSize + objSize)
+	if isSequence {
+		n = (ASDUSizeMax - p.IdentifierSize() - p.InfoObjAddrSize) / objSize
+	}
+	if n > 127 {
+		n = 127
+	}
+	return n
+}
+
+func (sf *Publisher) remove(key Identifier) {
+	delete(sf.batches, key)
+	for i, k := range sf.order {
+		if k == key {
+			sf.order = append(sf.order[:i], sf.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (sf *Publisher) flushAll() error {
+	var err error
+	for len(sf.order) > 0 {
+		if e := sf.flush(sf.order[0]); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// flush 按到达顺序发送key缓存的信息对象.
+// 地址连续的一段若使用SQ=1比并入SQ=0的ASDU更省字节, 则单独以SQ=1发送.
+func (sf *Publisher) flush(key Identifier) error {
+	b := sf.batches[key]
+	sf.remove(key)
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+
+	p := sf.c.Params()
+	objSize, _ := GetInfoObjSize(key.Type)
+	elem := func(i int) []byte { return b.elems[i*objSize : (i+1)*objSize] }
+
+	var err error
+	var u *ASDU
+	send := func() {
+		if u == nil {
+			return
+		}
+		if e := sf.c.Send(u); e != nil {
+			sf.onError(u, e)
+			if err == nil {
+				err = e
+			}
+		}
+		u = nil
+	}
+	start := func(isSequence bool) {
+		id := key
+		id.Variable.IsSequence = isSequence
+		u = NewASDU(p, id)
+	}
+
+	maxSQ0, maxSQ1 := sf.maxCount(objSize, false), sf.maxCount(objSize, true)
+	for i := 0; i < len(b.ioas); {
+		run := 1
+		for i+run < len(b.ioas) && b.ioas[i+run] == b.ioas[i+run-1]+1 {
+			run++
+		}
+		if sequenceable(key.Type) && (run-1)*p.InfoObjAddrSize > p.IdentifierSize() {
+			send()
+			for ; run > 0; run -= maxSQ1 {
+				n := run
+				if n > maxSQ1 {
+					n = maxSQ1
+				}
+				start(true)
+				if e := u.AppendInfoObjAddr(b.ioas[i]); e != nil {
+					return e
+				}
+				for j := i; j < i+n; j++ {
+					u.AppendBytes(elem(j)...)
+				}
+				u.Variable.Number = byte(n)
+				send()
+				i += n
+			}
+			continue
+		}
+		for end := i + run; i < end; i++ {
+			if u == nil {
+				start(false)
+			}
+			if e := u.AppendInfoObjAddr(b.ioas[i]); e != nil {
+				return e
+			}
+			u.AppendBytes(elem(i)...)
+			if u.Variable.Number++; int(u.Variable.Number) == maxSQ0 {
+				send()
+			}
+		}
+	}
+	send()
+	return err
+}
@@ -0,0 +1,129 @@
+package asdu
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder records the sent asdu as "type SQ=x ioa..."
+type recorder struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (sf *recorder) Params() *Params          { return ParamsWide }
+func (sf *recorder) UnderlyingConn() net.Conn { return nil }
+func (sf *recorder) Send(u *ASDU) error {
+	s := fmt.Sprintf("%v SQ=%v", u.Type, u.Variable.IsSequence)
+	switch u.Type {
+	case M_SP_NA_1, M_SP_TB_1:
+		for _, v := range u.GetSinglePoint() {
+			s += fmt.Sprintf(" %d", v.Ioa)
+		}
+	default:
+		s += fmt.Sprintf(" %d", u.Variable.Number)
+	}
+	sf.mu.Lock()
+	sf.sent = append(sf.sent, s)
+	sf.mu.Unlock()
+	return nil
+}
+
+func (sf *recorder) get() []string {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	return sf.sent
+}
+
+func TestPublisher(t *testing.T) {
+	spont := CauseOfTransmission{Cause: Spontaneous}
+	single := func(ioas ...InfoObjAddr) func(c Connect) error {
+		return func(c Connect) error {
+			for _, ioa := range ioas {
+				if err := Single(c, false, spont, 1, SinglePointInfo{Ioa: ioa}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	tests := []struct {
+		name string
+		send []func(c Connect) error
+		want []string
+	}{
+		{"coalesce", []func(c Connect) error{single(10, 20, 30)},
+			[]string{"TID<M_SP_NA_1> SQ=false 10 20 30"}},
+		{"contiguous", []func(c Connect) error{single(1, 2, 3, 4, 5)},
+			[]string{"TID<M_SP_NA_1> SQ=true 1 2 3 4 5"}},
+		{"short run", []func(c Connect) error{single(1, 2, 3, 9)},
+			[]string{"TID<M_SP_NA_1> SQ=false 1 2 3 9"}},
+		{"mixed", []func(c Connect) error{single(9, 1, 2, 3, 4, 5, 7)},
+			[]string{"TID<M_SP_NA_1> SQ=false 9", "TID<M_SP_NA_1> SQ=true 1 2 3 4 5", "TID<M_SP_NA_1> SQ=false 7"}},
+		{"split by cause", []func(c Connect) error{single(1),
+			func(c Connect) error {
+				return Single(c, false, CauseOfTransmission{Cause: Request}, 1, SinglePointInfo{Ioa: 2})
+			}, single(3)},
+			[]string{"TID<M_SP_NA_1> SQ=false 1 3", "TID<M_SP_NA_1> SQ=false 2"}},
+		{"time tag no sequence", []func(c Connect) error{func(c Connect) error {
+			return SingleCP56Time2a(c, spont, 1, SinglePointInfo{Ioa: 1, Time: tm0},
+				SinglePointInfo{Ioa: 2, Time: tm0}, SinglePointInfo{Ioa: 3, Time: tm0}, SinglePointInfo{Ioa: 4, Time: tm0})
+		}}, []string{"TID<M_SP_TB_1> SQ=false 1 2 3 4"}},
+		{"priority flush", []func(c Connect) error{single(1), func(c Connect) error {
+			return EventOfProtectionEquipmentCP56Time2a(c, spont, 1, EventOfProtectionEquipmentInfo{Ioa: 5, Msec: 1, Time: tm0})
+		}, single(2)},
+			[]string{"TID<M_SP_NA_1> SQ=false 1", "TID<M_EP_TD_1> SQ=false 1", "TID<M_SP_NA_1> SQ=false 2"}},
+		{"command pass through", []func(c Connect) error{single(1), func(c Connect) error {
+			return InterrogationCmd(c, CauseOfTransmission{Cause: Activation}, 1, QOIStation)
+		}},
+			[]string{"TID<M_SP_NA_1> SQ=false 1", "TID<C_IC_NA_1> SQ=false 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+			p := NewPublisher(r, time.Hour)
+			for _, f := range tt.send {
+				if err := f(p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := p.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if got := r.get(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Publisher sent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublisher_window(t *testing.T) {
+	r := &recorder{}
+	p := NewPublisher(r, 20*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		_ = MeasuredValueFloat(p, false, CauseOfTransmission{Cause: Spontaneous}, 1,
+			MeasuredValueFloatInfo{Ioa: InfoObjAddr(100 + 2*i)})
+	}
+	if got := r.get(); len(got) != 0 {
+		t.Fatalf("sent %q before window", got)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if got := r.get(); !reflect.DeepEqual(got, []string{"TID<M_ME_NC_1> SQ=false 3"}) {
+		t.Errorf("sent %q after window", got)
+	}
+
+	// 满一个ASDU立即发送
+	r = &recorder{}
+	p = NewPublisher(r, time.Hour)
+	for i := 0; i < 30; i++ {
+		_ = MeasuredValueFloat(p, false, CauseOfTransmission{Cause: Spontaneous}, 1,
+			MeasuredValueFloatInfo{Ioa: InfoObjAddr(100 + 2*i)})
+	}
+	if got := r.get(); !reflect.DeepEqual(got, []string{"TID<M_ME_NC_1> SQ=false 30"}) {
+		t.Errorf("sent %q for full asdu", got)
+	}
+}
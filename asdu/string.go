@@ -0,0 +1,224 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package asdu
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// String returns a full description, 例: "TID<M_SP_NA_1> COT<Spontaneous> @1: 100: on, 101: off qds=0x80"
+// 信息体无法解码时以十六进制给出.
+func (sf *ASDU) String() string {
+	objs, err := sf.InfoObjStrings()
+	if err != nil {
+		return fmt.Sprintf("%s: [% x] <%v>", sf.Identifier, sf.infoObj, err)
+	}
+	return sf.Identifier.String() + ": " + strings.Join(objs, ", ")
+}
+
+// InfoObjStrings decode and describe each information object with the Parse* method,
+// it returns the *DecodeError if the information object is malformed.
+func (sf *ASDU) InfoObjStrings() (objs []string, err error) {
+	add := func(ioa InfoObjAddr, value interface{}, qual string, t time.Time) {
+		s := fmt.Sprintf("%d: %v", ioa, value)
+		if qual != "" {
+			s += " " + qual
+		}
+		if !t.IsZero() {
+			s += " t=" + t.Format("2006-01-02 15:04:05.000")
+		}
+		objs = append(objs, s)
+	}
+	qds := func(q QualityDescriptor) string {
+		if q == QDSGood {
+			return ""
+		}
+		return fmt.Sprintf("qds=%#02x", byte(q))
+	}
+	qdp := func(q QualityDescriptorProtection, msec uint16) string {
+		if q == 0 {
+			return fmt.Sprintf("elapsed=%dms", msec)
+		}
+		return fmt.Sprintf("qdp=%#02x elapsed=%dms", byte(q), msec)
+	}
+	onOff := func(v bool) string {
+		if v {
+			return "on"
+		}
+		return "off"
+	}
+
+	switch sf.Type {
+	case M_SP_NA_1, M_SP_TA_1, M_SP_TB_1:
+		var info []SinglePointInfo
+		info, err = sf.ParseSinglePoint()
+		for _, v := range info {
+			add(v.Ioa, onOff(v.Value), qds(v.Qds), v.Time)
+		}
+	case M_DP_NA_1, M_DP_TA_1, M_DP_TB_1:
+		var info []DoublePointInfo
+		info, err = sf.ParseDoublePoint()
+		for _, v := range info {
+			add(v.Ioa, v.Value.Value(), qds(v.Qds), v.Time)
+		}
+	case M_ST_NA_1, M_ST_TA_1, M_ST_TB_1:
+		var info []StepPositionInfo
+		info, err = sf.ParseStepPosition()
+		for _, v := range info {
+			add(v.Ioa, fmt.Sprintf("%d transient=%t", v.Value.Val, v.Value.HasTransient), qds(v.Qds), v.Time)
+		}
+	case M_BO_NA_1, M_BO_TA_1, M_BO_TB_1:
+		var info []BitString32Info
+		info, err = sf.ParseBitString32()
+		for _, v := range info {
+			add(v.Ioa, fmt.Sprintf("%#08x", v.Value), qds(v.Qds), v.Time)
+		}
+	case M_ME_NA_1, M_ME_TA_1, M_ME_TD_1, M_ME_ND_1:
+		var info []MeasuredValueNormalInfo
+		info, err = sf.ParseMeasuredValueNormal()
+		for _, v := range info {
+			add(v.Ioa, v.Value.Float64(), qds(v.Qds), v.Time)
+		}
+	case M_ME_NB_1, M_ME_TB_1, M_ME_TE_1:
+		var info []MeasuredValueScaledInfo
+		info, err = sf.ParseMeasuredValueScaled()
+		for _, v := range info {
+			add(v.Ioa, v.Value, qds(v.Qds), v.Time)
+		}
+	case M_ME_NC_1, M_ME_TC_1, M_ME_TF_1:
+		var info []MeasuredValueFloatInfo
+		info, err = sf.ParseMeasuredValueFloat()
+		for _, v := range info {
+			add(v.Ioa, v.Value, qds(v.Qds), v.Time)
+		}
+	case M_IT_NA_1, M_IT_TA_1, M_IT_TB_1:
+		var info []BinaryCounterReadingInfo
+		info, err = sf.ParseIntegratedTotals()
+		for _, v := range info {
+			add(v.Ioa, fmt.Sprintf("%d sq=%d", v.Value.CounterReading, v.Value.SeqNumber),
+				fmt.Sprintf("cy=%t ca=%t iv=%t", v.Value.HasCarry, v.Value.IsAdjusted, v.Value.IsInvalid), v.Time)
+		}
+	case M_EP_TA_1, M_EP_TD_1:
+		var info []EventOfProtectionEquipmentInfo
+		info, err = sf.ParseEventOfProtectionEquipment()
+		for _, v := range info {
+			add(v.Ioa, fmt.Sprintf("%#02x", byte(v.Event)), qdp(v.Qdp, v.Msec), v.Time)
+		}
+	case M_EP_TB_1, M_EP_TE_1:
+		var v PackedStartEventsOfProtectionEquipmentInfo
+		v, err = sf.ParsePackedStartEventsOfProtectionEquipment()
+		add(v.Ioa, fmt.Sprintf("%#02x", byte(v.Event)), qdp(v.Qdp, v.Msec), v.Time)
+	case M_EP_TC_1, M_EP_TF_1:
+		var v PackedOutputCircuitInfoInfo
+		v, err = sf.ParsePackedOutputCircuitInfo()
+		add(v.Ioa, fmt.Sprintf("%#02x", byte(v.Oci)), qdp(v.Qdp, v.Msec), v.Time)
+	case M_PS_NA_1:
+		var info []PackedSinglePointWithSCDInfo
+		info, err = sf.ParsePackedSinglePointWithSCD()
+		for _, v := range info {
+			add(v.Ioa, fmt.Sprintf("%#08x", uint32(v.Scd)), qds(v.Qds), time.Time{})
+		}
+	case M_EI_NA_1:
+		var ioa InfoObjAddr
+		var coi CauseOfInitial
+		ioa, coi, err = sf.ParseEndOfInitialization()
+		add(ioa, fmt.Sprintf("coi=%d", coi.Cause), fmt.Sprintf("localChange=%t", coi.IsLocalChange), time.Time{})
+
+	case C_SC_NA_1, C_SC_TA_1:
+		var v SingleCommandInfo
+		v, err = sf.ParseSingleCmd()
+		add(v.Ioa, onOff(v.Value), fmt.Sprintf("qoc=%#02x", v.Qoc.Value()), v.Time)
+	case C_DC_NA_1, C_DC_TA_1:
+		var v DoubleCommandInfo
+		v, err = sf.ParseDoubleCmd()
+		add(v.Ioa, byte(v.Value), fmt.Sprintf("qoc=%#02x", v.Qoc.Value()), v.Time)
+	case C_RC_NA_1, C_RC_TA_1:
+		var v StepCommandInfo
+		v, err = sf.ParseStepCmd()
+		add(v.Ioa, byte(v.Value), fmt.Sprintf("qoc=%#02x", v.Qoc.Value()), v.Time)
+	case C_SE_NA_1, C_SE_TA_1:
+		var v SetpointCommandNormalInfo
+		v, err = sf.ParseSetpointNormalCmd()
+		add(v.Ioa, v.Value.Float64(), fmt.Sprintf("qos=%#02x", v.Qos.Value()), v.Time)
+	case C_SE_NB_1, C_SE_TB_1:
+		var v SetpointCommandScaledInfo
+		v, err = sf.ParseSetpointCmdScaled()
+		add(v.Ioa, v.Value, fmt.Sprintf("qos=%#02x", v.Qos.Value()), v.Time)
+	case C_SE_NC_1, C_SE_TC_1:
+		var v SetpointCommandFloatInfo
+		v, err = sf.ParseSetpointFloatCmd()
+		add(v.Ioa, v.Value, fmt.Sprintf("qos=%#02x", v.Qos.Value()), v.Time)
+	case C_BO_NA_1, C_BO_TA_1:
+		var v BitsString32CommandInfo
+		v, err = sf.ParseBitsString32Cmd()
+		add(v.Ioa, fmt.Sprintf("%#08x", v.Value), "", v.Time)
+
+	case C_IC_NA_1:
+		var ioa InfoObjAddr
+		var qoi QualifierOfInterrogation
+		ioa, qoi, err = sf.ParseInterrogationCmd()
+		add(ioa, fmt.Sprintf("qoi=%d", qoi), "", time.Time{})
+	case C_CI_NA_1:
+		var ioa InfoObjAddr
+		var qcc QualifierCountCall
+		ioa, qcc, err = sf.ParseCounterInterrogationCmd()
+		add(ioa, fmt.Sprintf("qcc=%#02x", qcc.Value()), "", time.Time{})
+	case C_RD_NA_1:
+		var ioa InfoObjAddr
+		ioa, err = sf.ParseReadCmd()
+		add(ioa, "read", "", time.Time{})
+	case C_CS_NA_1:
+		var ioa InfoObjAddr
+		var t time.Time
+		ioa, t, err = sf.ParseClockSynchronizationCmd()
+		add(ioa, "clock", "", t)
+	case C_TS_NA_1:
+		var ioa InfoObjAddr
+		var ok bool
+		ioa, ok, err = sf.ParseTestCommand()
+		add(ioa, fmt.Sprintf("test=%t", ok), "", time.Time{})
+	case C_RP_NA_1:
+		var ioa InfoObjAddr
+		var qrp QualifierOfResetProcessCmd
+		ioa, qrp, err = sf.ParseResetProcessCmd()
+		add(ioa, fmt.Sprintf("qrp=%d", qrp), "", time.Time{})
+	case C_CD_NA_1:
+		var ioa InfoObjAddr
+		var msec uint16
+		ioa, msec, err = sf.ParseDelayAcquireCommand()
+		add(ioa, fmt.Sprintf("delay=%dms", msec), "", time.Time{})
+	case C_TS_TA_1:
+		var ioa InfoObjAddr
+		var ok bool
+		var t time.Time
+		ioa, ok, t, err = sf.ParseTestCommandCP56Time2a()
+		add(ioa, fmt.Sprintf("test=%t", ok), "", t)
+
+	case P_ME_NA_1:
+		var v ParameterNormalInfo
+		v, err = sf.ParseParameterNormal()
+		add(v.Ioa, v.Value.Float64(), fmt.Sprintf("qpm=%#02x", v.Qpm.Value()), time.Time{})
+	case P_ME_NB_1:
+		var v ParameterScaledInfo
+		v, err = sf.ParseParameterScaled()
+		add(v.Ioa, v.Value, fmt.Sprintf("qpm=%#02x", v.Qpm.Value()), time.Time{})
+	case P_ME_NC_1:
+		var v ParameterFloatInfo
+		v, err = sf.ParseParameterFloat()
+		add(v.Ioa, v.Value, fmt.Sprintf("qpm=%#02x", v.Qpm.Value()), time.Time{})
+	case P_AC_NA_1:
+		var v ParameterActivationInfo
+		v, err = sf.ParseParameterActivation()
+		add(v.Ioa, fmt.Sprintf("qpa=%d", v.Qpa), "", time.Time{})
+	default:
+		return nil, ErrTypeIDNotMatch
+	}
+	if err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
@@ -0,0 +1,58 @@
+package asdu
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestASDU_String(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+		want string
+	}{
+		{
+			"single point",
+			[]byte{0x01, 0x02, 0x03, 0x01, 0x64, 0x01, 0x65, 0x80},
+			"TID<M_SP_NA_1> COT<Spontaneous> @1: 100: on, 101: off qds=0x80",
+		},
+		{
+			"measured value float sequence",
+			[]byte{0x0d, 0x82, 0x14, 0x01, 0x0a, 0x00, 0x00, 0x20, 0x41, 0x00, 0x00, 0x00, 0x80, 0xbf, 0x01},
+			"TID<M_ME_NC_1> COT<InterrogatedByStation> @1: 10: 10, 11: -1 qds=0x01",
+		},
+		{
+			"interrogation",
+			[]byte{0x64, 0x01, 0x06, 0x01, 0x00, 0x14},
+			"TID<C_IC_NA_1> COT<Activation> @1: 0: qoi=20",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewEmptyASDU(ParamsNarrow)
+			if err := a.UnmarshalBinary(tt.raw); err != nil {
+				t.Fatal(err)
+			}
+			if got := a.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			if got := a.String(); got != tt.want {
+				t.Errorf("String() is not repeatable, got %q", got)
+			}
+		})
+	}
+}
+
+func TestASDU_InfoObjStrings_malformed(t *testing.T) {
+	// 第二个单点信息缺少品质描述词
+	a := &ASDU{
+		Params:     ParamsNarrow,
+		Identifier: Identifier{Type: M_SP_NA_1, Variable: VariableStruct{Number: 2}},
+		infoObj:    []byte{0x64, 0x01, 0x65},
+	}
+	objs, err := a.InfoObjStrings()
+	var de *DecodeError
+	if objs != nil || !errors.As(err, &de) || de.Index != 1 || de.Err != ErrTruncatedObject {
+		t.Errorf("InfoObjStrings() = %q, %v", objs, err)
+	}
+}
@@ -0,0 +1,146 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+func TestWriter_Reader(t *testing.T) {
+	server := &net.TCPAddr{IP: net.IPv4(192, 168, 1, 10), Port: cs104.Port}
+	client := &net.TCPAddr{IP: net.IPv4(192, 168, 1, 20), Port: 50000}
+	start := time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+
+	apdus := []struct {
+		dir  cs104.Direction
+		apdu []byte
+	}{
+		{cs104.DirReceived, []byte{0x68, 0x04, 0x07, 0x00, 0x00, 0x00}},
+		{cs104.DirSent, []byte{0x68, 0x04, 0x0b, 0x00, 0x00, 0x00}},
+		{cs104.DirReceived, []byte{0x68, 0x0e, 0x00, 0x00, 0x00, 0x00, 0x64, 0x01, 0x06, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14}},
+		{cs104.DirSent, []byte{0x68, 0x0e, 0x00, 0x00, 0x02, 0x00, 0x64, 0x01, 0x07, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14}},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range apdus {
+		w.Observe(&cs104.Frame{
+			Direction: v.dir,
+			Time:      start.Add(time.Duration(i) * time.Millisecond),
+			Local:     server,
+			Remote:    client,
+			APDU:      v.apdu,
+		})
+	}
+	if err = w.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	ps, err := ReadAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != len(apdus) {
+		t.Fatalf("ReadAll() got %d packets, want %d", len(ps), len(apdus))
+	}
+	for i, p := range ps {
+		if !bytes.Equal(p.APDU, apdus[i].apdu) || !p.Time.Equal(start.Add(time.Duration(i)*time.Millisecond)) {
+			t.Errorf("packet %d = % x %v", i, p.APDU, p.Time)
+		}
+		f := p.Frame(asdu.ParamsWide)
+		if f.Direction != apdus[i].dir || f.Local.String() != server.String() || f.Remote.String() != client.String() {
+			t.Errorf("packet %d frame %v %v %v", i, f.Direction, f.Local, f.Remote)
+		}
+	}
+	f := ps[3].Frame(asdu.ParamsWide)
+	if f.Err != nil || f.Control != (cs104.Control{Type: cs104.FrameI, RecvSN: 1}) ||
+		f.ASDU.Type != asdu.C_IC_NA_1 || f.ASDU.Coa.Cause != asdu.ActivationCon {
+		t.Errorf("frame %v %v %v", f.Control, f.ASDU, f.Err)
+	}
+}
+
+func TestReader_pcap(t *testing.T) {
+	src := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: cs104.Port}
+	dst := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 40000}
+	testfr := []byte{0x68, 0x04, 0x43, 0x00, 0x00, 0x00}
+	stopdt := []byte{0x68, 0x04, 0x13, 0x00, 0x00, 0x00}
+
+	var buf bytes.Buffer
+	hdr := make([]byte, 24)
+	binary.BigEndian.PutUint32(hdr[0:], magicMicroseconds)
+	binary.BigEndian.PutUint16(hdr[4:], 2)
+	binary.BigEndian.PutUint16(hdr[6:], 4)
+	binary.BigEndian.PutUint32(hdr[16:], 65535)
+	binary.BigEndian.PutUint32(hdr[20:], linkTypeEthernet)
+	buf.Write(hdr)
+	record := func(seq uint32, payload []byte) {
+		pkt := append(make([]byte, 14), encodeIPv4(segment{src, dst, seq, 1, payload})...)
+		binary.BigEndian.PutUint16(pkt[12:], 0x0800)
+		h := make([]byte, 16)
+		binary.BigEndian.PutUint32(h[0:], 1577836800)
+		binary.BigEndian.PutUint32(h[4:], 500)
+		binary.BigEndian.PutUint32(h[8:], uint32(len(pkt)))
+		binary.BigEndian.PutUint32(h[12:], uint32(len(pkt)))
+		buf.Write(h)
+		buf.Write(pkt)
+	}
+	record(100, testfr[:3])                           // APDU跨报文段
+	record(103, append(testfr[3:], stopdt[:2]...))    // 下一个APDU的开始
+	record(103, append(testfr[3:], stopdt[:2]...))    // 重传
+	record(108, stopdt[2:])                           // 完成
+	record(200, []byte{0x00, 0x01, 0x02, 0x03})       // 丢失数据后的垃圾
+	record(204, append([]byte{0xff}, testfr...))      // 重新同步
+	record(211, []byte{0x68, 0x04, 0x43, 0x00, 0x00}) // 未完成, 文件结束
+
+	rd, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]byte
+	for {
+		p, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Src.String() != src.String() || p.Time.Unix() != 1577836800 || p.Time.Nanosecond() != 500000 {
+			t.Errorf("packet %v %v", p.Src, p.Time)
+		}
+		got = append(got, p.APDU)
+	}
+	want := [][]byte{testfr, stopdt, testfr}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Next() = % x, want % x", got, want)
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"magic", []byte{0x01, 0x02, 0x03, 0x04}},
+		{"pcap header", []byte{0xd4, 0xc3, 0xb2, 0xa1, 0x02, 0x00}},
+		{"pcapng bom", []byte{0x0a, 0x0d, 0x0d, 0x0a, 0x1c, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewReader(bytes.NewReader(tt.data)); err != ErrFormat {
+				t.Errorf("NewReader() error = %v, want %v", err, ErrFormat)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+const (
+	startFrame   = 0x68     // APDU启动字符
+	maxBlockSize = 16 << 20 // 块或报文的最大长度
+)
+
+// pcap file magic
+const (
+	magicMicroseconds = 0xa1b2c3d4
+	magicNanoseconds  = 0xa1b23c4d
+)
+
+// ErrFormat 不是pcap或pcapng文件, 或文件已损坏
+var ErrFormat = errors.New("capture: invalid pcap or pcapng format")
+
+// Packet 从抓包文件中重组出的一个APDU
+type Packet struct {
+	Time time.Time
+	Src  *net.TCPAddr
+	Dst  *net.TCPAddr
+	APDU []byte
+}
+
+// Frame 解析APDU, 并以参数p解码I帧的ASDU.
+// 以端口为cs104.Port的一端作为本端, 即从服务端发出的为 cs104.DirSent.
+func (sf *Packet) Frame(p *asdu.Params) *cs104.Frame {
+	dir, local, remote := cs104.DirReceived, sf.Dst, sf.Src
+	if sf.Src.Port == cs104.Port && sf.Dst.Port != cs104.Port {
+		dir, local, remote = cs104.DirSent, sf.Src, sf.Dst
+	}
+	f := cs104.NewFrame(p, dir, sf.Time, sf.APDU)
+	f.Local, f.Remote = local, remote
+	return f
+}
+
+// iface pcapng的接口描述
+type iface struct {
+	linkType uint16
+	snapLen  uint32
+	tsPerSec uint64 // 每秒的时间戳单位数
+}
+
+// stream TCP连接一个方向的重组状态
+type stream struct {
+	synced bool
+	next   uint32 // 期望的下一个序号
+	buf    []byte
+}
+
+// Reader 读取pcap或pcapng文件, 按TCP连接重组出APDU.
+// 重传的报文段被忽略, 丢失数据时丢弃未完成的APDU并在下一个启动字符处重新同步.
+type Reader struct {
+	r       io.Reader
+	ng      bool
+	order   binary.ByteOrder
+	ifaces  []iface // pcap文件只有一个
+	streams map[flow]*stream
+	queue   []*Packet
+}
+
+// NewReader new a reader, the format(pcap or pcapng) is detected by the file magic
+func NewReader(r io.Reader) (*Reader, error) {
+	sf := &Reader{r: r, streams: make(map[flow]*stream)}
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, ErrFormat
+	}
+	if binary.LittleEndian.Uint32(magic) == blockSectionHeader {
+		sf.ng = true
+		if err := sf.readSectionHeader(); err != nil {
+			return nil, err
+		}
+		return sf, nil
+	}
+
+	tsPerSec := uint64(time.Second / time.Microsecond)
+	switch {
+	case binary.LittleEndian.Uint32(magic) == magicMicroseconds:
+		sf.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(magic) == magicMicroseconds:
+		sf.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(magic) == magicNanoseconds:
+		sf.order, tsPerSec = binary.LittleEndian, uint64(time.Second)
+	case binary.BigEndian.Uint32(magic) == magicNanoseconds:
+		sf.order, tsPerSec = binary.BigEndian, uint64(time.Second)
+	default:
+		return nil, ErrFormat
+	}
+	hdr := make([]byte, 20)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, ErrFormat
+	}
+	sf.ifaces = []iface{{
+		linkType: uint16(sf.order.Uint32(hdr[16:])),
+		snapLen:  sf.order.Uint32(hdr[12:]),
+		tsPerSec: tsPerSec,
+	}}
+	return sf, nil
+}
+
+// Next returns the next APDU, io.EOF is returned at the end of file
+func (sf *Reader) Next() (*Packet, error) {
+	for len(sf.queue) == 0 {
+		t, linkType, data, err := sf.readPacket()
+		if err != nil {
+			return nil, err
+		}
+		seg, err := decodeLink(linkType, data)
+		if err != nil {
+			continue // 非TCP/IP报文
+		}
+		sf.reassemble(t, seg)
+	}
+	p := sf.queue[0]
+	sf.queue = sf.queue[1:]
+	return p, nil
+}
+
+// ReadAll read all APDU from r
+func ReadAll(r io.Reader) ([]*Packet, error) {
+	rd, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	var ps []*Packet
+	for {
+		p, err := rd.Next()
+		if err == io.EOF {
+			return ps, nil
+		}
+		if err != nil {
+			return ps, err
+		}
+		ps = append(ps, p)
+	}
+}
+
+// readPacket 读取下一个报文
+func (sf *Reader) readPacket() (time.Time, uint16, []byte, error) {
+	if !sf.ng {
+		hdr := make([]byte, 16)
+		if err := sf.readFull(hdr); err != nil {
+			return time.Time{}, 0, nil, err
+		}
+		capLen := sf.order.Uint32(hdr[8:])
+		if capLen > maxBlockSize {
+			return time.Time{}, 0, nil, ErrFormat
+		}
+		data := make([]byte, capLen)
+		if err := sf.readFull(data); err != nil {
+			return time.Time{}, 0, nil, unexpected(err)
+		}
+		ifc := sf.ifaces[0]
+		ts := uint64(sf.order.Uint32(hdr[0:]))*ifc.tsPerSec + uint64(sf.order.Uint32(hdr[4:]))
+		return ifc.time(ts), ifc.linkType, data, nil
+	}
+
+	for {
+		typ, body, err := sf.readBlock()
+		if err != nil {
+			return time.Time{}, 0, nil, err
+		}
+		switch typ {
+		case blockSectionHeader:
+			// readBlock 已处理
+		case blockInterface:
+			if len(body) < 8 {
+				return time.Time{}, 0, nil, ErrFormat
+			}
+			ifc := iface{
+				linkType: sf.order.Uint16(body[0:]),
+				snapLen:  sf.order.Uint32(body[4:]),
+				tsPerSec: uint64(time.Second / time.Microsecond),
+			}
+			sf.readOptions(body[8:], func(code uint16, val []byte) {
+				if code == optionInterfaceTsResl && len(val) >= 1 {
+					ifc.tsPerSec = tsResolution(val[0])
+				}
+			})
+			sf.ifaces = append(sf.ifaces, ifc)
+		case blockEnhancedPacket:
+			if len(body) < 20 {
+				return time.Time{}, 0, nil, ErrFormat
+			}
+			id, capLen := sf.order.Uint32(body[0:]), sf.order.Uint32(body[12:])
+			if int(id) >= len(sf.ifaces) || int(capLen) > len(body)-20 {
+				return time.Time{}, 0, nil, ErrFormat
+			}
+			ifc := sf.ifaces[id]
+			ts := uint64(sf.order.Uint32(body[4:]))<<32 | uint64(sf.order.Uint32(body[8:]))
+			return ifc.time(ts), ifc.linkType, body[20 : 20+capLen], nil
+		case blockSimplePacket:
+			if len(body) < 4 || len(sf.ifaces) == 0 {
+				return time.Time{}, 0, nil, ErrFormat
+			}
+			ifc, data := sf.ifaces[0], body[4:]
+			if n := sf.order.Uint32(body[0:]); int(n) < len(data) {
+				data = data[:n]
+			}
+			if ifc.snapLen > 0 && int(ifc.snapLen) < len(data) {
+				data = data[:ifc.snapLen]
+			}
+			return time.Time{}, ifc.linkType, data, nil
+		}
+	}
+}
+
+// readBlock 读取pcapng的一个块, 返回块类型及块内容(不含头尾的类型和长度)
+func (sf *Reader) readBlock() (uint32, []byte, error) {
+	hdr := make([]byte, 8)
+	if err := sf.readFull(hdr); err != nil {
+		return 0, nil, err
+	}
+	// 节头块的类型是回文, 字节序在块内的BOM中给出
+	if binary.LittleEndian.Uint32(hdr) == blockSectionHeader {
+		if err := sf.readSectionHeaderWith(hdr[4:]); err != nil {
+			return 0, nil, err
+		}
+		return blockSectionHeader, nil, nil
+	}
+	length := sf.order.Uint32(hdr[4:])
+	if length < 12 || length%4 != 0 || length > maxBlockSize {
+		return 0, nil, ErrFormat
+	}
+	body := make([]byte, length-8)
+	if err := sf.readFull(body); err != nil {
+		return 0, nil, unexpected(err)
+	}
+	return sf.order.Uint32(hdr), body[:len(body)-4], nil
+}
+
+// readSectionHeader 读取节头块, 块类型已读取
+func (sf *Reader) readSectionHeader() error {
+	length := make([]byte, 4)
+	if err := sf.readFull(length); err != nil {
+		return ErrFormat
+	}
+	return sf.readSectionHeaderWith(length)
+}
+
+// readSectionHeaderWith 读取节头块, 块类型和长度已读取
+func (sf *Reader) readSectionHeaderWith(length []byte) error {
+	bom := make([]byte, 4)
+	if err := sf.readFull(bom); err != nil {
+		return unexpected(err)
+	}
+	switch uint32(byteOrderMagic) {
+	case binary.LittleEndian.Uint32(bom):
+		sf.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(bom):
+		sf.order = binary.BigEndian
+	default:
+		return ErrFormat
+	}
+	n := sf.order.Uint32(length)
+	if n < 28 || n%4 != 0 || n > maxBlockSize {
+		return ErrFormat
+	}
+	if err := sf.readFull(make([]byte, n-12)); err != nil {
+		return unexpected(err)
+	}
+	// 新的节重新定义接口, 连接状态保留
+	sf.ifaces = sf.ifaces[:0]
+	return nil
+}
+
+// readOptions 遍历pcapng的选项
+func (sf *Reader) readOptions(b []byte, fn func(code uint16, val []byte)) {
+	for len(b) >= 4 {
+		code, n := sf.order.Uint16(b[0:]), int(sf.order.Uint16(b[2:]))
+		if code == optionEndOfOpt || len(b) < 4+n {
+			return
+		}
+		fn(code, b[4:4+n])
+		padded := (n + 3) &^ 3
+		if len(b) < 4+padded {
+			return
+		}
+		b = b[4+padded:]
+	}
+}
+
+func (sf *Reader) readFull(b []byte) error {
+	_, err := io.ReadFull(sf.r, b)
+	return err
+}
+
+// reassemble 重组TCP数据, 将完整的APDU放入队列
+func (sf *Reader) reassemble(t time.Time, seg segment) {
+	if len(seg.payload) == 0 {
+		return
+	}
+	key := flow{seg.src.String(), seg.dst.String()}
+	st, ok := sf.streams[key]
+	if !ok {
+		st = &stream{}
+		sf.streams[key] = st
+	}
+
+	payload, end := seg.payload, seg.seq+uint32(len(seg.payload))
+	if st.synced {
+		switch d := int32(seg.seq - st.next); {
+		case d > 0: // 丢失数据
+			st.buf = st.buf[:0]
+		case d < 0: // 重传
+			if int(-d) >= len(payload) {
+				return
+			}
+			payload = payload[-d:]
+		}
+	}
+	st.synced, st.next = true, end
+	st.buf = append(st.buf, payload...)
+
+	for {
+		i := bytes.IndexByte(st.buf, startFrame)
+		if i < 0 {
+			st.buf = st.buf[:0]
+			return
+		}
+		st.buf = st.buf[i:]
+		if len(st.buf) < 2 {
+			return
+		}
+		if st.buf[1] < 4 {
+			st.buf = st.buf[1:]
+			continue
+		}
+		n := int(st.buf[1]) + 2
+		if len(st.buf) < n {
+			return
+		}
+		sf.queue = append(sf.queue, &Packet{
+			Time: t,
+			Src:  seg.src,
+			Dst:  seg.dst,
+			APDU: append([]byte(nil), st.buf[:n]...),
+		})
+		st.buf = st.buf[n:]
+	}
+}
+
+// time 时间戳转换为时间
+func (sf iface) time(ts uint64) time.Time {
+	sec, frac := ts/sf.tsPerSec, ts%sf.tsPerSec
+	return time.Unix(int64(sec), int64(float64(frac)*float64(time.Second)/float64(sf.tsPerSec)))
+}
+
+// tsResolution if_tsresol转换为每秒的时间戳单位数
+func tsResolution(v byte) uint64 {
+	if v&0x80 != 0 {
+		return 1 << (v & 0x7f & 63)
+	}
+	n := uint64(1)
+	for i := byte(0); i < v && i < 19; i++ {
+		n *= 10
+	}
+	return n
+}
+
+// unexpected 报文中途结束
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package capture
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+// link-layer header type, See http://www.tcpdump.org/linktypes.html
+const (
+	linkTypeNull     = 0   // BSD loopback
+	linkTypeEthernet = 1   // IEEE 802.3 Ethernet
+	linkTypeRaw      = 101 // raw IPv4 or IPv6
+	linkTypeLinuxSLL = 113 // Linux cooked capture
+	linkTypeIPv4     = 228 // raw IPv4
+	linkTypeIPv6     = 229 // raw IPv6
+)
+
+const (
+	ipv4HeaderSize = 20
+	tcpHeaderSize  = 20
+
+	tcpFlagPSH = 0x08
+	tcpFlagACK = 0x10
+)
+
+var errNotTCP = errors.New("capture: not a TCP/IP packet")
+
+// segment 一个TCP报文段
+type segment struct {
+	src, dst *net.TCPAddr
+	seq, ack uint32
+	payload  []byte
+}
+
+// encodeIPv4 生成IPv4 + TCP报文
+func encodeIPv4(s segment) []byte {
+	b := make([]byte, ipv4HeaderSize+tcpHeaderSize+len(s.payload))
+	ip, tcp := b[:ipv4HeaderSize], b[ipv4HeaderSize:]
+
+	ip[0] = 0x45 // version 4, IHL 5
+	binary.BigEndian.PutUint16(ip[2:], uint16(len(b)))
+	ip[6] = 0x40 // don't fragment
+	ip[8] = 64   // TTL
+	ip[9] = 6    // TCP
+	copy(ip[12:16], s.src.IP.To4())
+	copy(ip[16:20], s.dst.IP.To4())
+	binary.BigEndian.PutUint16(ip[10:], checksum(ip, 0))
+
+	binary.BigEndian.PutUint16(tcp[0:], uint16(s.src.Port))
+	binary.BigEndian.PutUint16(tcp[2:], uint16(s.dst.Port))
+	binary.BigEndian.PutUint32(tcp[4:], s.seq)
+	binary.BigEndian.PutUint32(tcp[8:], s.ack)
+	tcp[12] = tcpHeaderSize / 4 << 4
+	tcp[13] = tcpFlagPSH | tcpFlagACK
+	binary.BigEndian.PutUint16(tcp[14:], 65535) // window
+	copy(tcp[tcpHeaderSize:], s.payload)
+
+	// pseudo header
+	var sum uint32
+	sum += uint32(binary.BigEndian.Uint16(ip[12:])) + uint32(binary.BigEndian.Uint16(ip[14:]))
+	sum += uint32(binary.BigEndian.Uint16(ip[16:])) + uint32(binary.BigEndian.Uint16(ip[18:]))
+	sum += 6 + uint32(len(tcp))
+	binary.BigEndian.PutUint16(tcp[16:], checksum(tcp, sum))
+	return b
+}
+
+// checksum internet checksum, See RFC 1071
+func checksum(b []byte, sum uint32) uint16 {
+	for ; len(b) > 1; b = b[2:] {
+		sum += uint32(b[0])<<8 | uint32(b[1])
+	}
+	if len(b) == 1 {
+		sum += uint32(b[0]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// decodeLink 去除链路层头部, 解析IP和TCP头部
+func decodeLink(linkType uint16, b []byte) (segment, error) {
+	switch linkType {
+	case linkTypeNull:
+		if len(b) < 4 {
+			return segment{}, errNotTCP
+		}
+		return decodeIP(b[4:])
+	case linkTypeEthernet:
+		if len(b) < 14 {
+			return segment{}, errNotTCP
+		}
+		etherType, b := binary.BigEndian.Uint16(b[12:]), b[14:]
+		for etherType == 0x8100 && len(b) >= 4 { // 802.1Q VLAN
+			etherType, b = binary.BigEndian.Uint16(b[2:]), b[4:]
+		}
+		if etherType != 0x0800 && etherType != 0x86dd {
+			return segment{}, errNotTCP
+		}
+		return decodeIP(b)
+	case linkTypeLinuxSLL:
+		if len(b) < 16 {
+			return segment{}, errNotTCP
+		}
+		return decodeIP(b[16:])
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+		return decodeIP(b)
+	}
+	return segment{}, errNotTCP
+}
+
+// decodeIP 解析IPv4或IPv6及TCP头部
+func decodeIP(b []byte) (segment, error) {
+	var src, dst net.IP
+	var proto byte
+
+	if len(b) < 1 {
+		return segment{}, errNotTCP
+	}
+	switch b[0] >> 4 {
+	case 4:
+		ihl := int(b[0]&0x0f) * 4
+		if len(b) < ipv4HeaderSize || ihl < ipv4HeaderSize || len(b) < ihl {
+			return segment{}, errNotTCP
+		}
+		if total := int(binary.BigEndian.Uint16(b[2:])); total >= ihl && total < len(b) {
+			b = b[:total] // strip ethernet padding
+		}
+		if binary.BigEndian.Uint16(b[6:])&0x3fff != 0 { // fragment
+			return segment{}, errNotTCP
+		}
+		src, dst, proto = net.IP(b[12:16]), net.IP(b[16:20]), b[9]
+		b = b[ihl:]
+	case 6:
+		if len(b) < 40 {
+			return segment{}, errNotTCP
+		}
+		if payload := int(binary.BigEndian.Uint16(b[4:])); 40+payload < len(b) {
+			b = b[:40+payload]
+		}
+		src, dst, proto = net.IP(b[8:24]), net.IP(b[24:40]), b[6]
+		b = b[40:]
+	default:
+		return segment{}, errNotTCP
+	}
+	if proto != 6 || len(b) < tcpHeaderSize {
+		return segment{}, errNotTCP
+	}
+	offset := int(b[12]>>4) * 4
+	if offset < tcpHeaderSize || len(b) < offset {
+		return segment{}, errNotTCP
+	}
+	return segment{
+		src:     &net.TCPAddr{IP: append(net.IP(nil), src...), Port: int(binary.BigEndian.Uint16(b[0:]))},
+		dst:     &net.TCPAddr{IP: append(net.IP(nil), dst...), Port: int(binary.BigEndian.Uint16(b[2:]))},
+		seq:     binary.BigEndian.Uint32(b[4:]),
+		ack:     binary.BigEndian.Uint32(b[8:]),
+		payload: b[offset:],
+	}, nil
+}
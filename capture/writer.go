@@ -0,0 +1,144 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+// Package capture write cs104 frames to pcapng files and read APDUs from pcap or pcapng files.
+package capture
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+// pcapng block type
+const (
+	blockSectionHeader    = 0x0a0d0d0a
+	blockInterface        = 0x00000001
+	blockSimplePacket     = 0x00000003
+	blockEnhancedPacket   = 0x00000006
+	byteOrderMagic        = 0x1a2b3c4d
+	optionEndOfOpt        = 0
+	optionInterfaceTsResl = 9
+)
+
+// 地址未知或不是IPv4时使用的地址
+var (
+	DefaultLocalAddr  = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: cs104.Port}
+	DefaultRemoteAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 49152}
+)
+
+// flow TCP连接的一个方向
+type flow struct {
+	src, dst string
+}
+
+// Writer 将cs104的帧写入pcapng文件, 每个APDU作为一个IPv4 TCP报文段,
+// 可用Wireshark的iec104解析器打开. 实现了 cs104.Observer, 可直接作为客户端或服务端的观察者.
+type Writer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	seqs map[flow]uint32 // 每个方向下一个TCP序号
+	err  error
+}
+
+var _ cs104.Observer = (*Writer)(nil)
+
+// NewWriter new a pcapng writer and write the section header and interface description
+func NewWriter(w io.Writer) (*Writer, error) {
+	sf := &Writer{w: w, seqs: make(map[flow]uint32)}
+
+	shb := make([]byte, 28)
+	binary.LittleEndian.PutUint32(shb[0:], blockSectionHeader)
+	binary.LittleEndian.PutUint32(shb[4:], uint32(len(shb)))
+	binary.LittleEndian.PutUint32(shb[8:], byteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[12:], 1) // major version
+	binary.LittleEndian.PutUint16(shb[14:], 0) // minor version
+	binary.LittleEndian.PutUint64(shb[16:], 0xffffffffffffffff)
+	binary.LittleEndian.PutUint32(shb[24:], uint32(len(shb)))
+
+	idb := make([]byte, 20)
+	binary.LittleEndian.PutUint32(idb[0:], blockInterface)
+	binary.LittleEndian.PutUint32(idb[4:], uint32(len(idb)))
+	binary.LittleEndian.PutUint16(idb[8:], linkTypeRaw)
+	binary.LittleEndian.PutUint32(idb[12:], 0) // snap length, no limit
+	binary.LittleEndian.PutUint32(idb[16:], uint32(len(idb)))
+
+	if _, err := w.Write(append(shb, idb...)); err != nil {
+		return nil, err
+	}
+	return sf, nil
+}
+
+// WriteFrame write the frame as a TCP segment, the address of the frame which is unknown
+// or not IPv4 will use DefaultLocalAddr and DefaultRemoteAddr.
+func (sf *Writer) WriteFrame(f *cs104.Frame) error {
+	local, remote := tcpAddr(f.Local, DefaultLocalAddr), tcpAddr(f.Remote, DefaultRemoteAddr)
+	if f.Direction == cs104.DirSent {
+		return sf.writeSegment(f.Time, local, remote, f.APDU)
+	}
+	return sf.writeSegment(f.Time, remote, local, f.APDU)
+}
+
+// Observe imp cs104.Observer, the first error is kept and returned by Err
+func (sf *Writer) Observe(f *cs104.Frame) {
+	if err := sf.WriteFrame(f); err != nil {
+		sf.mu.Lock()
+		if sf.err == nil {
+			sf.err = err
+		}
+		sf.mu.Unlock()
+	}
+}
+
+// Err returns the first error occurred in Observe
+func (sf *Writer) Err() error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	return sf.err
+}
+
+// writeSegment 写入一个从src到dst的TCP报文段, 序号按方向累加
+func (sf *Writer) writeSegment(t time.Time, src, dst *net.TCPAddr, payload []byte) error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	fw, bw := flow{src.String(), dst.String()}, flow{dst.String(), src.String()}
+	seq, ok := sf.seqs[fw]
+	if !ok {
+		seq = 1
+	}
+	ack, ok := sf.seqs[bw]
+	if !ok {
+		ack = 1
+	}
+	sf.seqs[fw] = seq + uint32(len(payload))
+	data := encodeIPv4(segment{src, dst, seq, ack, payload})
+
+	padded := (len(data) + 3) &^ 3
+	b := make([]byte, 28+padded+4)
+	ts := uint64(t.UnixNano() / int64(time.Microsecond))
+	binary.LittleEndian.PutUint32(b[0:], blockEnhancedPacket)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)))
+	binary.LittleEndian.PutUint32(b[8:], 0) // interface id
+	binary.LittleEndian.PutUint32(b[12:], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(b[16:], uint32(ts))
+	binary.LittleEndian.PutUint32(b[20:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(b[24:], uint32(len(data)))
+	copy(b[28:], data)
+	binary.LittleEndian.PutUint32(b[len(b)-4:], uint32(len(b)))
+	_, err := sf.w.Write(b)
+	return err
+}
+
+// tcpAddr 转换为IPv4的TCP地址, 无法转换时返回def
+func tcpAddr(addr net.Addr, def *net.TCPAddr) *net.TCPAddr {
+	if a, ok := addr.(*net.TCPAddr); ok && a.IP.To4() != nil {
+		return a
+	}
+	return def
+}
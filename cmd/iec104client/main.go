@@ -0,0 +1,131 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+// iec104client is an interactive IEC 104 master.
+//
+// It connects to an outstation, sends STARTDT on connect, and reads commands from
+// standard input, type help for the list of commands. Every received ASDU is printed
+// with the decoded information objects.
+//
+// Usage:
+//
+//	iec104client [flags] [host:port]
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+func main() {
+	var (
+		ca       = flag.Uint("ca", 1, "common address of the outstation")
+		cause    = flag.Int("cause", 2, "cause of transmission size, 1 or 2")
+		caSize   = flag.Int("casize", 2, "common address size, 1 or 2")
+		ioa      = flag.Int("ioa", 3, "information object address size, 1, 2 or 3")
+		timeout  = flag.Duration("timeout", 10*time.Second, "timeout waiting the select confirm of SBO commands")
+		noStart  = flag.Bool("nostart", false, "do not send STARTDT on connect")
+		debug    = flag.Bool("debug", false, "log every frame")
+		interval = flag.Duration("reconnect", time.Second, "reconnect interval")
+	)
+	flag.Parse()
+
+	server := "127.0.0.1:2404"
+	if flag.NArg() > 0 {
+		server = flag.Arg(0)
+	}
+	params := &asdu.Params{CauseSize: *cause, CommonAddrSize: *caSize, InfoObjAddrSize: *ioa, InfoObjTimeZone: time.Local}
+	if err := params.Valid(); err != nil {
+		fatal(err)
+	}
+	if err := params.ValidCommonAddr(asdu.CommonAddr(*ca)); err != nil && asdu.CommonAddr(*ca) != asdu.GlobalCommonAddr {
+		fatal(err)
+	}
+
+	option := cs104.NewOption().SetParams(params).SetReconnectInterval(*interval)
+	if err := option.AddRemoteServer(server); err != nil {
+		fatal(err)
+	}
+	h := &handler{}
+	client := cs104.NewClient(h, option)
+	client.LogMode(*debug)
+	m := newMaster(client, os.Stdout, asdu.CommonAddr(*ca), *timeout)
+	h.master = m
+
+	client.SetOnConnectHandler(func(c *cs104.Client) {
+		m.printf("connected to %s\n", server)
+		if !*noStart {
+			c.SendStartDt()
+		}
+	})
+	client.SetOnActiveHandler(func(*cs104.Client) { m.printf("data transfer started\n") })
+	client.SetConnectionLostHandler(func(*cs104.Client) { m.printf("connection lost\n") })
+	if err := client.Start(); err != nil {
+		fatal(err)
+	}
+	defer client.Close()
+
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		if m.exec(sc.Text()) {
+			return
+		}
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "iec104client:", err)
+	os.Exit(1)
+}
+
+// handler imp cs104.ClientHandlerInterface, print all asdu
+type handler struct {
+	master *master
+}
+
+func (sf *handler) InterrogationHandler(_ asdu.Connect, a *asdu.ASDU) error {
+	sf.master.handle(a)
+	return nil
+}
+
+func (sf *handler) CounterInterrogationHandler(_ asdu.Connect, a *asdu.ASDU) error {
+	sf.master.handle(a)
+	return nil
+}
+
+func (sf *handler) ReadHandler(_ asdu.Connect, a *asdu.ASDU) error {
+	sf.master.handle(a)
+	return nil
+}
+
+func (sf *handler) TestCommandHandler(_ asdu.Connect, a *asdu.ASDU) error {
+	sf.master.handle(a)
+	return nil
+}
+
+func (sf *handler) ClockSyncHandler(_ asdu.Connect, a *asdu.ASDU) error {
+	sf.master.handle(a)
+	return nil
+}
+
+func (sf *handler) ResetProcessHandler(_ asdu.Connect, a *asdu.ASDU) error {
+	sf.master.handle(a)
+	return nil
+}
+
+func (sf *handler) DelayAcquisitionHandler(_ asdu.Connect, a *asdu.ASDU) error {
+	sf.master.handle(a)
+	return nil
+}
+
+func (sf *handler) ASDUHandler(_ asdu.Connect, a *asdu.ASDU) error {
+	sf.master.handle(a)
+	return nil
+}
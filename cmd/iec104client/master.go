@@ -0,0 +1,514 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// link 主站使用的链路, cs104.Client 实现了它
+type link interface {
+	asdu.Connect
+	SendStartDt()
+	SendStopDt()
+}
+
+// errUsage 参数错误, 打印命令的用法
+var errUsage = errors.New("usage")
+
+// waitKey 等待确认的控制命令
+type waitKey struct {
+	ca     asdu.CommonAddr
+	typeID asdu.TypeID
+	ioa    asdu.InfoObjAddr
+}
+
+// master 解析并执行交互命令, 打印收到的ASDU
+type master struct {
+	conn    link
+	ca      asdu.CommonAddr
+	timeout time.Duration // 选择确认的超时时间
+
+	mu    sync.Mutex
+	out   io.Writer
+	waits map[waitKey]chan *asdu.ASDU
+}
+
+func newMaster(conn link, out io.Writer, ca asdu.CommonAddr, timeout time.Duration) *master {
+	return &master{
+		conn:    conn,
+		ca:      ca,
+		timeout: timeout,
+		out:     out,
+		waits:   make(map[waitKey]chan *asdu.ASDU),
+	}
+}
+
+// command 一个交互命令
+type command struct {
+	usage string
+	help  string
+	run   func(sf *master, args []string) error
+}
+
+var commands = map[string]command{
+	"startdt": {"startdt", "start data transfer", func(sf *master, _ []string) error {
+		sf.conn.SendStartDt()
+		return nil
+	}},
+	"stopdt": {"stopdt", "stop data transfer", func(sf *master, _ []string) error {
+		sf.conn.SendStopDt()
+		return nil
+	}},
+	"ca": {"ca <addr>", "set the common address of the following commands, 65535 is global", (*master).setCommonAddr},
+	"gi": {"gi [group]", "general interrogation, or group interrogation of group 1..16", (*master).interrogation},
+	"ci": {"ci [request] [freeze]", "counter interrogation, request 1..4 group or 5 total(default), " +
+		"freeze read(default), freeze, freeze-reset or reset", (*master).counterInterrogation},
+	"read":  {"read <ioa>", "read command", (*master).read},
+	"clock": {"clock [2006-01-02T15:04:05.000]", "clock synchronization, default now", (*master).clock},
+	"test":  {"test", "test command", (*master).test},
+	"reset": {"reset [qrp]", "reset process, qrp 1 general reset(default), 2 reset pending events", (*master).reset},
+	"sc": {"sc <ioa> <on|off> [sbo] [time] [qu=n]", "single command",
+		(*master).singleCmd},
+	"dc": {"dc <ioa> <on|off> [sbo] [time] [qu=n]", "double command",
+		(*master).doubleCmd},
+	"rc": {"rc <ioa> <up|down> [sbo] [time] [qu=n]", "regulating step command",
+		(*master).stepCmd},
+	"sen": {"sen <ioa> <value> [sbo] [time] [qu=n]", "set-point command, normalized value in [-1, 1)",
+		(*master).setpointNormal},
+	"ses": {"ses <ioa> <value> [sbo] [time] [qu=n]", "set-point command, scaled value",
+		(*master).setpointScaled},
+	"sef": {"sef <ioa> <value> [sbo] [time] [qu=n]", "set-point command, short floating point value",
+		(*master).setpointFloat},
+	"bo": {"bo <ioa> <value> [time]", "bitstring of 32 bit command, value like 0x0000ffff",
+		(*master).bitsString32},
+}
+
+// exec 执行一行命令, 返回是否退出
+func (sf *master) exec(line string) (quit bool) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return false
+	}
+	name := strings.ToLower(args[0])
+	switch name {
+	case "quit", "exit":
+		return true
+	case "help", "?":
+		sf.help()
+		return false
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		sf.printf("unknown command %q, type help for the list of commands\n", name)
+		return false
+	}
+	if err := cmd.run(sf, args[1:]); err != nil {
+		if err == errUsage {
+			sf.printf("usage: %s\n", cmd.usage)
+		} else {
+			sf.printf("%s: %v\n", name, err)
+		}
+	}
+	return false
+}
+
+func (sf *master) help() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-44s %s\n", commands[name].usage, commands[name].help)
+	}
+	fmt.Fprintf(&b, "  %-44s %s\n", "quit", "exit")
+	fmt.Fprintf(&b, "control commands: sbo select before operate, time use the type with CP56Time2a\n")
+	sf.printf("%s", b.String())
+}
+
+func (sf *master) printf(format string, v ...interface{}) {
+	sf.mu.Lock()
+	fmt.Fprintf(sf.out, format, v...)
+	sf.mu.Unlock()
+}
+
+// handle 打印收到的ASDU, 并通知等待确认的控制命令
+func (sf *master) handle(a *asdu.ASDU) {
+	sf.printf("%s %s\n", time.Now().Format("15:04:05.000"), a)
+
+	if a.Coa.Cause != asdu.ActivationCon && a.Coa.Cause != asdu.DeactivationCon {
+		return
+	}
+	c := a.Clone()
+	key := waitKey{a.CommonAddr, a.Type, c.DecodeInfoObjAddr()}
+	sf.mu.Lock()
+	ch, ok := sf.waits[key]
+	sf.mu.Unlock()
+	if ok {
+		select {
+		case ch <- a:
+		default:
+		}
+	}
+}
+
+// control 发送控制命令, 选择执行时先发送选择命令, 收到肯定确认后再发送执行命令
+func (sf *master) control(typeID asdu.TypeID, ioa asdu.InfoObjAddr, sbo bool, send func(inSelect bool) error) error {
+	if sbo {
+		key := waitKey{sf.ca, typeID, ioa}
+		ch := make(chan *asdu.ASDU, 1)
+		sf.mu.Lock()
+		sf.waits[key] = ch
+		sf.mu.Unlock()
+		defer func() {
+			sf.mu.Lock()
+			delete(sf.waits, key)
+			sf.mu.Unlock()
+		}()
+
+		if err := send(true); err != nil {
+			return err
+		}
+		select {
+		case a := <-ch:
+			if a.Coa.IsNegative {
+				return errors.New("select rejected")
+			}
+		case <-time.After(sf.timeout):
+			return errors.New("select confirm timeout")
+		}
+	}
+	return send(false)
+}
+
+func (sf *master) setCommonAddr(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	v, err := strconv.ParseUint(args[0], 0, 16)
+	if err != nil {
+		return errUsage
+	}
+	ca := asdu.CommonAddr(v)
+	if ca != asdu.GlobalCommonAddr {
+		if err = sf.conn.Params().ValidCommonAddr(ca); err != nil {
+			return err
+		}
+	}
+	sf.ca = ca
+	return nil
+}
+
+func (sf *master) interrogation(args []string) error {
+	qoi := asdu.QOIStation
+	switch len(args) {
+	case 0:
+	case 1:
+		g, err := strconv.ParseUint(args[0], 0, 8)
+		if err != nil || g > 16 {
+			return errUsage
+		}
+		if g > 0 {
+			qoi = asdu.QOIGroup1 + asdu.QualifierOfInterrogation(g-1)
+		}
+	default:
+		return errUsage
+	}
+	return asdu.InterrogationCmd(sf.conn, activation(), sf.ca, qoi)
+}
+
+func (sf *master) counterInterrogation(args []string) error {
+	qcc := asdu.QualifierCountCall{Request: asdu.QCCTotal, Freeze: asdu.QCCFrzRead}
+	if len(args) > 2 {
+		return errUsage
+	}
+	if len(args) > 0 {
+		r, err := strconv.ParseUint(args[0], 0, 8)
+		if err != nil || r < 1 || r > 5 {
+			return errUsage
+		}
+		qcc.Request = asdu.QCCRequest(r)
+	}
+	if len(args) > 1 {
+		switch args[1] {
+		case "read":
+			qcc.Freeze = asdu.QCCFrzRead
+		case "freeze":
+			qcc.Freeze = asdu.QCCFrzFreezeNoReset
+		case "freeze-reset":
+			qcc.Freeze = asdu.QCCFrzFreezeReset
+		case "reset":
+			qcc.Freeze = asdu.QCCFrzReset
+		default:
+			return errUsage
+		}
+	}
+	return asdu.CounterInterrogationCmd(sf.conn, activation(), sf.ca, qcc)
+}
+
+func (sf *master) read(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	ioa, err := parseIOA(args[0])
+	if err != nil {
+		return errUsage
+	}
+	return asdu.ReadCmd(sf.conn, asdu.CauseOfTransmission{Cause: asdu.Request}, sf.ca, ioa)
+}
+
+func (sf *master) clock(args []string) error {
+	t := time.Now()
+	switch len(args) {
+	case 0:
+	case 1:
+		var err error
+		if t, err = time.ParseInLocation("2006-01-02T15:04:05.000", args[0], sf.conn.Params().InfoObjTimeZone); err != nil {
+			return errUsage
+		}
+	default:
+		return errUsage
+	}
+	return asdu.ClockSynchronizationCmd(sf.conn, activation(), sf.ca, t)
+}
+
+func (sf *master) test(args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	return asdu.TestCommand(sf.conn, activation(), sf.ca)
+}
+
+func (sf *master) reset(args []string) error {
+	qrp := asdu.QPRGeneralRest
+	switch len(args) {
+	case 0:
+	case 1:
+		v, err := strconv.ParseUint(args[0], 0, 8)
+		if err != nil || v == 0 {
+			return errUsage
+		}
+		qrp = asdu.QualifierOfResetProcessCmd(v)
+	default:
+		return errUsage
+	}
+	return asdu.ResetProcessCmd(sf.conn, activation(), sf.ca, qrp)
+}
+
+// ctlArgs 控制命令的公共参数
+type ctlArgs struct {
+	ioa   asdu.InfoObjAddr
+	value string
+	sbo   bool
+	time  bool
+	qual  uint64
+}
+
+// parseCtlArgs 解析控制命令 "<ioa> <value> [sbo] [time] [qu=n]"
+func parseCtlArgs(args []string) (ctlArgs, error) {
+	var c ctlArgs
+	if len(args) < 2 {
+		return c, errUsage
+	}
+	ioa, err := parseIOA(args[0])
+	if err != nil {
+		return c, errUsage
+	}
+	c.ioa, c.value = ioa, strings.ToLower(args[1])
+	for _, opt := range args[2:] {
+		switch {
+		case opt == "sbo":
+			c.sbo = true
+		case opt == "time":
+			c.time = true
+		case strings.HasPrefix(opt, "qu="):
+			if c.qual, err = strconv.ParseUint(opt[3:], 0, 7); err != nil {
+				return c, errUsage
+			}
+		default:
+			return c, errUsage
+		}
+	}
+	return c, nil
+}
+
+// typeID 按是否带时标选择类型标识
+func (c ctlArgs) typeID(noTime, withTime asdu.TypeID) asdu.TypeID {
+	if c.time {
+		return withTime
+	}
+	return noTime
+}
+
+func (sf *master) singleCmd(args []string) error {
+	c, err := parseCtlArgs(args)
+	if err != nil {
+		return err
+	}
+	var on bool
+	switch c.value {
+	case "on", "1":
+		on = true
+	case "off", "0":
+	default:
+		return errUsage
+	}
+	typeID := c.typeID(asdu.C_SC_NA_1, asdu.C_SC_TA_1)
+	return sf.control(typeID, c.ioa, c.sbo, func(inSelect bool) error {
+		return asdu.SingleCmd(sf.conn, typeID, activation(), sf.ca, asdu.SingleCommandInfo{
+			Ioa:   c.ioa,
+			Value: on,
+			Qoc:   asdu.QualifierOfCommand{Qual: asdu.QOCQual(c.qual), InSelect: inSelect},
+			Time:  time.Now(),
+		})
+	})
+}
+
+func (sf *master) doubleCmd(args []string) error {
+	c, err := parseCtlArgs(args)
+	if err != nil {
+		return err
+	}
+	var value asdu.DoubleCommand
+	switch c.value {
+	case "on":
+		value = asdu.DCOOn
+	case "off":
+		value = asdu.DCOOff
+	default:
+		return errUsage
+	}
+	typeID := c.typeID(asdu.C_DC_NA_1, asdu.C_DC_TA_1)
+	return sf.control(typeID, c.ioa, c.sbo, func(inSelect bool) error {
+		return asdu.DoubleCmd(sf.conn, typeID, activation(), sf.ca, asdu.DoubleCommandInfo{
+			Ioa:   c.ioa,
+			Value: value,
+			Qoc:   asdu.QualifierOfCommand{Qual: asdu.QOCQual(c.qual), InSelect: inSelect},
+			Time:  time.Now(),
+		})
+	})
+}
+
+func (sf *master) stepCmd(args []string) error {
+	c, err := parseCtlArgs(args)
+	if err != nil {
+		return err
+	}
+	var value asdu.StepCommand
+	switch c.value {
+	case "up":
+		value = asdu.SCOStepUP
+	case "down":
+		value = asdu.SCOStepDown
+	default:
+		return errUsage
+	}
+	typeID := c.typeID(asdu.C_RC_NA_1, asdu.C_RC_TA_1)
+	return sf.control(typeID, c.ioa, c.sbo, func(inSelect bool) error {
+		return asdu.StepCmd(sf.conn, typeID, activation(), sf.ca, asdu.StepCommandInfo{
+			Ioa:   c.ioa,
+			Value: value,
+			Qoc:   asdu.QualifierOfCommand{Qual: asdu.QOCQual(c.qual), InSelect: inSelect},
+			Time:  time.Now(),
+		})
+	})
+}
+
+func (sf *master) setpointNormal(args []string) error {
+	c, err := parseCtlArgs(args)
+	if err != nil {
+		return err
+	}
+	v, err := strconv.ParseFloat(c.value, 64)
+	if err != nil || v < -1 || v >= 1 {
+		return errUsage
+	}
+	typeID := c.typeID(asdu.C_SE_NA_1, asdu.C_SE_TA_1)
+	return sf.control(typeID, c.ioa, c.sbo, func(inSelect bool) error {
+		return asdu.SetpointCmdNormal(sf.conn, typeID, activation(), sf.ca, asdu.SetpointCommandNormalInfo{
+			Ioa:   c.ioa,
+			Value: asdu.Normalize(v * 32768),
+			Qos:   asdu.QualifierOfSetpointCmd{Qual: asdu.QOSQual(c.qual), InSelect: inSelect},
+			Time:  time.Now(),
+		})
+	})
+}
+
+func (sf *master) setpointScaled(args []string) error {
+	c, err := parseCtlArgs(args)
+	if err != nil {
+		return err
+	}
+	v, err := strconv.ParseInt(c.value, 0, 16)
+	if err != nil {
+		return errUsage
+	}
+	typeID := c.typeID(asdu.C_SE_NB_1, asdu.C_SE_TB_1)
+	return sf.control(typeID, c.ioa, c.sbo, func(inSelect bool) error {
+		return asdu.SetpointCmdScaled(sf.conn, typeID, activation(), sf.ca, asdu.SetpointCommandScaledInfo{
+			Ioa:   c.ioa,
+			Value: int16(v),
+			Qos:   asdu.QualifierOfSetpointCmd{Qual: asdu.QOSQual(c.qual), InSelect: inSelect},
+			Time:  time.Now(),
+		})
+	})
+}
+
+func (sf *master) setpointFloat(args []string) error {
+	c, err := parseCtlArgs(args)
+	if err != nil {
+		return err
+	}
+	v, err := strconv.ParseFloat(c.value, 32)
+	if err != nil {
+		return errUsage
+	}
+	typeID := c.typeID(asdu.C_SE_NC_1, asdu.C_SE_TC_1)
+	return sf.control(typeID, c.ioa, c.sbo, func(inSelect bool) error {
+		return asdu.SetpointCmdFloat(sf.conn, typeID, activation(), sf.ca, asdu.SetpointCommandFloatInfo{
+			Ioa:   c.ioa,
+			Value: float32(v),
+			Qos:   asdu.QualifierOfSetpointCmd{Qual: asdu.QOSQual(c.qual), InSelect: inSelect},
+			Time:  time.Now(),
+		})
+	})
+}
+
+func (sf *master) bitsString32(args []string) error {
+	c, err := parseCtlArgs(args)
+	if err != nil || c.sbo || c.qual != 0 { // 没有命令限定词
+		return errUsage
+	}
+	v, err := strconv.ParseUint(c.value, 0, 32)
+	if err != nil {
+		return errUsage
+	}
+	typeID := c.typeID(asdu.C_BO_NA_1, asdu.C_BO_TA_1)
+	return asdu.BitsString32Cmd(sf.conn, typeID, activation(), sf.ca, asdu.BitsString32CommandInfo{
+		Ioa:   c.ioa,
+		Value: uint32(v),
+		Time:  time.Now(),
+	})
+}
+
+func activation() asdu.CauseOfTransmission {
+	return asdu.CauseOfTransmission{Cause: asdu.Activation}
+}
+
+func parseIOA(s string) (asdu.InfoObjAddr, error) {
+	v, err := strconv.ParseUint(s, 0, 24)
+	return asdu.InfoObjAddr(v), err
+}
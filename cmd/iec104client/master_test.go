@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// mockLink record the sent asdu, reply the select command with confirm if confirm is set
+type mockLink struct {
+	sent    []*asdu.ASDU
+	confirm func(a *asdu.ASDU)
+}
+
+func (sf *mockLink) Params() *asdu.Params     { return asdu.ParamsWide }
+func (sf *mockLink) UnderlyingConn() net.Conn { return nil }
+func (sf *mockLink) SendStartDt()             {}
+func (sf *mockLink) SendStopDt()              {}
+func (sf *mockLink) Send(a *asdu.ASDU) error {
+	sf.sent = append(sf.sent, a)
+	if sf.confirm != nil {
+		sf.confirm(a)
+	}
+	return nil
+}
+
+func TestMaster_exec(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string // 发送的ASDU
+		out  string   // 输出包含
+	}{
+		{"gi", "gi", []string{"TID<C_IC_NA_1> COT<Activation> @1: 0: qoi=20"}, ""},
+		{"group", "gi 2", []string{"TID<C_IC_NA_1> COT<Activation> @1: 0: qoi=22"}, ""},
+		{"ci", "ci 5 freeze", []string{"TID<C_CI_NA_1> COT<Activation> @1: 0: qcc=0x45"}, ""},
+		{"read", "read 100", []string{"TID<C_RD_NA_1> COT<Request> @1: 100: read"}, ""},
+		{"single", "sc 100 on qu=1", []string{"TID<C_SC_NA_1> COT<Activation> @1: 100: on qoc=0x04"}, ""},
+		{"single sbo", "sc 100 off sbo", []string{
+			"TID<C_SC_NA_1> COT<Activation> @1: 100: off qoc=0x80",
+			"TID<C_SC_NA_1> COT<Activation> @1: 100: off qoc=0x00",
+		}, ""},
+		{"setpoint", "ses 0x10 -5", []string{"TID<C_SE_NB_1> COT<Activation> @1: 16: -5 qos=0x00"}, ""},
+		{"bitstring", "bo 1 0xff", []string{"TID<C_BO_NA_1> COT<Activation> @1: 1: 0x000000ff"}, ""},
+		{"usage", "sc 100", nil, "usage: sc <ioa>"},
+		{"unknown", "foo", nil, "unknown command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			l := &mockLink{}
+			m := newMaster(l, &out, 1, time.Second)
+			l.confirm = func(a *asdu.ASDU) {
+				if a.Type == asdu.C_SC_NA_1 && a.Clone().GetSingleCmd().Qoc.InSelect {
+					r := a.Clone()
+					r.Coa.Cause = asdu.ActivationCon
+					go m.handle(r)
+				}
+			}
+			m.exec(tt.line)
+			var got []string
+			for _, a := range l.sent {
+				got = append(got, a.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("exec() sent %q, want %q", got, tt.want)
+			}
+			if !strings.Contains(out.String(), tt.out) {
+				t.Errorf("exec() output %q, want %q", out.String(), tt.out)
+			}
+		})
+	}
+}
+
+func TestMaster_selectTimeout(t *testing.T) {
+	var out bytes.Buffer
+	l := &mockLink{}
+	m := newMaster(l, &out, 1, 10*time.Millisecond)
+	m.exec("dc 200 on sbo")
+	if len(l.sent) != 1 || !strings.Contains(out.String(), "select confirm timeout") {
+		t.Errorf("exec() sent %d asdu, output %q", len(l.sent), out.String())
+	}
+}
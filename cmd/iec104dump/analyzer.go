@@ -0,0 +1,88 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+// side 连接一端I帧的发送状态
+type side struct {
+	synced bool
+	next   uint16 // 期望的下一个发送序号
+	acked  uint16 // 对端已确认的序号
+}
+
+// analyzer 按连接检查序号连续性, k窗口及报文格式
+type analyzer struct {
+	k     uint16
+	conns map[string]*[2]side // 以 cs104.Direction 为索引
+}
+
+func newAnalyzer(k uint16) *analyzer {
+	return &analyzer{k: k, conns: make(map[string]*[2]side)}
+}
+
+// check 检查帧f, 返回发现的问题
+func (sf *analyzer) check(conn string, f *cs104.Frame) []string {
+	var warns []string
+	if f.Err != nil {
+		warns = append(warns, fmt.Sprintf("malformed: %v", f.Err))
+		if f.Control.Type != cs104.FrameI || f.Err == cs104.ErrInvalidAPDU {
+			return warns
+		}
+	}
+
+	sides, ok := sf.conns[conn]
+	if !ok {
+		sides = &[2]side{}
+		sf.conns[conn] = sides
+	}
+	s, peer := &sides[f.Direction&1], &sides[(f.Direction+1)&1]
+
+	switch f.Control.Type {
+	case cs104.FrameU:
+		return warns
+	case cs104.FrameI:
+		if !s.synced {
+			s.synced, s.next, s.acked = true, f.Control.SendSN, f.Control.SendSN
+		}
+		if f.Control.SendSN != s.next {
+			warns = append(warns, fmt.Sprintf("sequence gap: expected send number %d, got %d",
+				s.next, f.Control.SendSN))
+			if seqDiff(f.Control.SendSN, s.acked) >= 16384 { // 序号回退, 如重新连接
+				s.acked = f.Control.SendSN
+			}
+		}
+		if n := seqDiff(f.Control.SendSN, s.acked); n >= sf.k {
+			warns = append(warns, fmt.Sprintf("k window exceeded: %d unacknowledged I frames, k=%d", n+1, sf.k))
+		}
+		s.next = seqAdd(f.Control.SendSN, 1)
+	}
+
+	// I帧和S帧确认对端发送的I帧
+	if !peer.synced {
+		peer.synced, peer.next, peer.acked = true, f.Control.RecvSN, f.Control.RecvSN
+	}
+	if seqDiff(f.Control.RecvSN, peer.acked) > seqDiff(peer.next, peer.acked) {
+		warns = append(warns, fmt.Sprintf("acknowledges unsent I frame: recv number %d, next send number %d",
+			f.Control.RecvSN, peer.next))
+	} else {
+		peer.acked = f.Control.RecvSN
+	}
+	return warns
+}
+
+// seqDiff 序号差 a - b, 模32768
+func seqDiff(a, b uint16) uint16 {
+	return (a - b) & 32767
+}
+
+// seqAdd 序号和 a + n, 模32768
+func seqAdd(a, n uint16) uint16 {
+	return (a + n) & 32767
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+func TestAnalyzer_check(t *testing.T) {
+	iFrame := func(send, recv uint16) []byte {
+		return []byte{0x68, 0x0a, byte(send << 1), byte(send >> 7), byte(recv << 1), byte(recv >> 7),
+			0x01, 0x01, 0x03, 0x01, 0x01, 0x01}
+	}
+	sFrame := func(recv uint16) []byte {
+		return []byte{0x68, 0x04, 0x01, 0x00, byte(recv << 1), byte(recv >> 7)}
+	}
+	tests := []struct {
+		name   string
+		frames []cs104.Direction
+		apdus  [][]byte
+		want   []string
+	}{
+		{
+			"in order",
+			[]cs104.Direction{cs104.DirSent, cs104.DirSent, cs104.DirReceived},
+			[][]byte{iFrame(0, 0), iFrame(1, 0), sFrame(2)},
+			nil,
+		},
+		{
+			"sequence gap",
+			[]cs104.Direction{cs104.DirSent, cs104.DirReceived, cs104.DirSent},
+			[][]byte{iFrame(5, 0), sFrame(6), iFrame(7, 0)},
+			[]string{"sequence gap: expected send number 6, got 7"},
+		},
+		{
+			"k window",
+			[]cs104.Direction{cs104.DirSent, cs104.DirSent, cs104.DirSent, cs104.DirReceived, cs104.DirSent},
+			[][]byte{iFrame(0, 0), iFrame(1, 0), iFrame(2, 0), sFrame(2), iFrame(3, 0)},
+			[]string{"k window exceeded: 3 unacknowledged I frames, k=2"},
+		},
+		{
+			"ack unsent",
+			[]cs104.Direction{cs104.DirSent, cs104.DirReceived},
+			[][]byte{iFrame(0, 0), sFrame(3)},
+			[]string{"acknowledges unsent I frame: recv number 3, next send number 1"},
+		},
+		{
+			"malformed",
+			[]cs104.Direction{cs104.DirSent, cs104.DirSent},
+			[][]byte{{0x68, 0x04, 0x01}, {0x68, 0x07, 0x00, 0x00, 0x00, 0x00, 0x01, 0x01, 0x03}},
+			[]string{"malformed: cs104: invalid APDU", "malformed: EOF"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			an := newAnalyzer(2)
+			var got []string
+			for i, apdu := range tt.apdus {
+				got = append(got, an.check("", cs104.NewFrame(asdu.ParamsNarrow, tt.frames[i], time.Time{}, apdu))...)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadHex(t *testing.T) {
+	input := strings.Join([]string{
+		"2020/01/02 10:00:00 [D]: TX Raw[68 04 07 00 00 00]",
+		"2020/01/02 10:00:00 [D]: RX Raw[68 04 0b 00 00 00]",
+		"RX 0x68,0x04,0x43",
+		"RX 0x00,0x00,0x00 ff",
+	}, "\n")
+	var got []record
+	if err := readHex(strings.NewReader(input), func(r *record) { got = append(got, *r) }); err != nil {
+		t.Fatal(err)
+	}
+	want := []record{
+		{dir: cs104.DirSent, apdu: []byte{0x68, 0x04, 0x07, 0x00, 0x00, 0x00}},
+		{dir: cs104.DirReceived, apdu: []byte{0x68, 0x04, 0x0b, 0x00, 0x00, 0x00}},
+		{dir: cs104.DirReceived, apdu: []byte{0x68, 0x04, 0x43, 0x00, 0x00, 0x00}},
+		{dir: cs104.DirReceived, skipped: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readHex() = %+v, want %+v", got, want)
+	}
+}
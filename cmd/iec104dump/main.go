@@ -0,0 +1,168 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+// iec104dump decode IEC 104 traffic offline, from hex dumps, raw byte streams or pcap/pcapng files.
+//
+// It prints every I/S/U frame with the sequence numbers and the decoded ASDU, and flags
+// sequence gaps, k window violations and malformed APDUs or ASDUs.
+//
+// Usage:
+//
+//	iec104dump [flags] [file ...]
+//
+// Read from standard input when no file given. The format is detected from the
+// file extension (.pcap, .pcapng, .bin, .raw) unless -format is set, hex is the default.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+func main() {
+	var (
+		format   = flag.String("format", "", "input format: hex, raw or pcap, detect from file extension if empty")
+		cause    = flag.Int("cause", 2, "cause of transmission size, 1 or 2")
+		ca       = flag.Int("ca", 2, "common address size, 1 or 2")
+		ioa      = flag.Int("ioa", 3, "information object address size, 1, 2 or 3")
+		k        = flag.Uint("k", 12, "max number of unacknowledged I frames")
+		warnOnly = flag.Bool("warn", false, "print only frames with warnings")
+	)
+	flag.Parse()
+
+	params := &asdu.Params{CauseSize: *cause, CommonAddrSize: *ca, InfoObjAddrSize: *ioa, InfoObjTimeZone: time.UTC}
+	if err := params.Valid(); err != nil {
+		fmt.Fprintln(os.Stderr, "iec104dump:", err)
+		os.Exit(2)
+	}
+	if *k < 1 || *k > 32767 {
+		fmt.Fprintln(os.Stderr, "iec104dump: k must in [1, 32767]")
+		os.Exit(2)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	d := &dumper{w: w, params: params, analyzer: newAnalyzer(uint16(*k)), warnOnly: *warnOnly}
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	code := 0
+	for _, name := range files {
+		if err := d.dumpFile(name, *format); err != nil {
+			fmt.Fprintf(os.Stderr, "iec104dump: %s: %v\n", name, err)
+			code = 1
+		}
+	}
+	w.Flush()
+	if code == 0 && d.warnings > 0 {
+		fmt.Fprintf(os.Stderr, "iec104dump: %d frames, %d warnings\n", d.frames, d.warnings)
+	}
+	os.Exit(code)
+}
+
+// dumper 解析并打印帧
+type dumper struct {
+	w        io.Writer
+	params   *asdu.Params
+	analyzer *analyzer
+	warnOnly bool
+	frames   int
+	warnings int
+}
+
+func (sf *dumper) dumpFile(name, format string) error {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".pcap", ".pcapng", ".cap":
+			format = "pcap"
+		case ".bin", ".raw":
+			format = "raw"
+		default:
+			format = "hex"
+		}
+	}
+	// 每个文件的连接状态独立
+	sf.analyzer = newAnalyzer(sf.analyzer.k)
+	switch format {
+	case "hex":
+		return readHex(r, sf.dump)
+	case "raw":
+		return readRaw(r, sf.dump)
+	case "pcap":
+		return readPcap(r, sf.dump)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// dump 打印一条记录
+func (sf *dumper) dump(rec *record) {
+	if rec.apdu == nil {
+		sf.warnings++
+		fmt.Fprintf(sf.w, "! skipped %d bytes without a valid APDU\n", rec.skipped)
+		return
+	}
+	sf.frames++
+	f := cs104.NewFrame(sf.params, rec.dir, rec.time, rec.apdu)
+	warns := sf.analyzer.check(rec.conn, f)
+	if rec.skipped > 0 {
+		warns = append([]string{fmt.Sprintf("skipped %d bytes before the APDU", rec.skipped)}, warns...)
+	}
+	var objs []string
+	if f.ASDU != nil {
+		var err error
+		if objs, err = f.ASDU.InfoObjStrings(); err != nil {
+			warns = append(warns, fmt.Sprintf("malformed information object: %v", err))
+		}
+	}
+	sf.warnings += len(warns)
+	if sf.warnOnly && len(warns) == 0 {
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#%d", sf.frames)
+	if !rec.time.IsZero() {
+		fmt.Fprintf(&b, " %s", rec.time.Format("2006-01-02 15:04:05.000000"))
+	}
+	if rec.src != "" {
+		fmt.Fprintf(&b, " %s -> %s", rec.src, rec.dst)
+	}
+	fmt.Fprintf(&b, " %s", rec.dir)
+	if f.Err == cs104.ErrInvalidAPDU {
+		fmt.Fprintf(&b, " [% x]\n", rec.apdu)
+	} else {
+		fmt.Fprintf(&b, " %s\n", f.Control)
+	}
+	if f.ASDU != nil {
+		fmt.Fprintf(&b, "    %s %s\n", f.ASDU.Identifier, f.ASDU.Variable)
+		for _, o := range objs {
+			fmt.Fprintf(&b, "      %s\n", o)
+		}
+	} else if f.Control.Type == cs104.FrameI && f.Err != cs104.ErrInvalidAPDU {
+		_, raw, _ := cs104.ParseAPDU(rec.apdu)
+		fmt.Fprintf(&b, "    ASDU [% x]\n", raw)
+	}
+	for _, w := range warns {
+		fmt.Fprintf(&b, "  ! %s\n", w)
+	}
+	io.WriteString(sf.w, b.String())
+}
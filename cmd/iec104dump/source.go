@@ -0,0 +1,153 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/capture"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+// record 一个待解析的APDU
+type record struct {
+	time    time.Time // 未知时为零值
+	conn    string    // 连接标识, 序号按连接检查
+	src     string    // 发送端, 未知时为空
+	dst     string    // 接收端, 未知时为空
+	dir     cs104.Direction
+	apdu    []byte
+	skipped int // 此APDU前丢弃的字节数
+}
+
+// splitter 按 cs104.ScanAPDU 从字节流中重组APDU
+type splitter struct {
+	buf []byte
+}
+
+// write 追加数据, 对每个完整的APDU调用emit, 参数为APDU及其前丢弃的字节数
+func (sf *splitter) write(b []byte, atEOF bool, emit func(apdu []byte, skipped int)) {
+	sf.buf = append(sf.buf, b...)
+	skipped := 0
+	for len(sf.buf) > 0 {
+		advance, token, _ := cs104.ScanAPDU(sf.buf, atEOF)
+		if token != nil {
+			emit(append([]byte(nil), token...), skipped+advance-len(token))
+			skipped = 0
+		} else {
+			skipped += advance
+		}
+		sf.buf = sf.buf[advance:]
+		if token == nil && (advance == 0 || atEOF) {
+			break
+		}
+	}
+	if skipped > 0 {
+		emit(nil, skipped)
+	}
+}
+
+// readRaw 读取原始字节流, 视为单一方向
+func readRaw(r io.Reader, emit func(*record)) error {
+	var sp splitter
+	buf := make([]byte, 4096)
+	out := func(apdu []byte, skipped int) {
+		emit(&record{dir: cs104.DirReceived, apdu: apdu, skipped: skipped})
+	}
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			sp.write(buf[:n], false, out)
+		}
+		if err == io.EOF {
+			sp.write(nil, true, out)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readHex 读取十六进制文本, 每行可带 TX 或 RX 标记方向, 如本库的调试日志 "TX Raw[68 04 07 00 00 00]".
+// 行中有方括号时只取最后一对括号内的字节, 否则取所有十六进制的字段. 两个方向分别重组.
+func readHex(r io.Reader, emit func(*record)) error {
+	var sp [2]splitter
+	outs := [2]func([]byte, int){}
+	for i := range outs {
+		dir := cs104.Direction(i)
+		outs[i] = func(apdu []byte, skipped int) {
+			emit(&record{dir: dir, apdu: apdu, skipped: skipped})
+		}
+	}
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		dir := cs104.DirReceived
+		if i := strings.Index(line, "TX"); i >= 0 && (strings.Index(line, "RX") < 0 || i < strings.Index(line, "RX")) {
+			dir = cs104.DirSent
+		}
+		if i := strings.LastIndexByte(line, '['); i >= 0 {
+			if j := strings.IndexByte(line[i:], ']'); j > 0 {
+				line = line[i+1 : i+j]
+			}
+		}
+		sp[dir].write(parseHex(line), false, outs[dir])
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	for i := range sp {
+		sp[i].write(nil, true, outs[i])
+	}
+	return nil
+}
+
+// parseHex 解析一行中所有的十六进制字段, 字段可带0x前缀, 以空白,逗号,冒号或短横线分隔
+func parseHex(line string) []byte {
+	var b []byte
+	fields := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ',' || r == ':' || r == '-'
+	})
+	for _, f := range fields {
+		f = strings.TrimPrefix(strings.TrimPrefix(f, "0x"), "0X")
+		if v, err := hex.DecodeString(f); err == nil {
+			b = append(b, v...)
+		}
+	}
+	return b
+}
+
+// readPcap 读取pcap或pcapng文件, 以端口为 cs104.Port 的一端为本端
+func readPcap(r io.Reader, emit func(*record)) error {
+	rd, err := capture.NewReader(r)
+	if err != nil {
+		return err
+	}
+	for {
+		p, err := rd.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		src, dst := p.Src.String(), p.Dst.String()
+		conn := src + " <-> " + dst
+		if dst < src {
+			conn = dst + " <-> " + src
+		}
+		dir := cs104.DirReceived
+		if p.Src.Port == cs104.Port && p.Dst.Port != cs104.Port {
+			dir = cs104.DirSent
+		}
+		emit(&record{time: p.Time, conn: conn, src: src, dst: dst, dir: dir, apdu: p.APDU})
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+// iec104sim is an IEC 104 outstation simulator driven by a point list.
+//
+// The point list is a CSV file with a header row or a YAML list of maps, the columns are
+//
+//	ca, ioa, type, group, value, deadband, period, pattern, control, feedback
+//
+// Monitor points (M_SP_NA_1, M_DP_NA_1, M_ME_NA_1, M_ME_NB_1, M_ME_NC_1, M_IT_NA_1) answer
+// interrogations and reads, change their value every period following the pattern
+// (ramp min max step, sine amplitude period [offset], walk step min max, steps v ...)
+// and are sent spontaneously when the change exceeds the deadband.
+// Command points (C_SC_NA_1, C_DC_NA_1, C_SE_NA_1, C_SE_NB_1, C_SE_NC_1) are confirmed,
+// rejected or ignored according to control, an executed command updates the feedback point.
+//
+// Usage:
+//
+//	iec104sim [flags] -points file
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+func main() {
+	var (
+		listen  = flag.String("listen", fmt.Sprintf(":%d", cs104.Port), "listen address")
+		file    = flag.String("points", "", "point list file, required")
+		format  = flag.String("format", "", "point list format, csv or yaml, default detected from the file extension")
+		timeTag = flag.Bool("timetag", false, "send spontaneous data with CP56Time2a time tag")
+		cause   = flag.Int("cause", 2, "cause of transmission size, 1 or 2")
+		caSize  = flag.Int("casize", 2, "common address size, 1 or 2")
+		ioa     = flag.Int("ioa", 3, "information object address size, 1, 2 or 3")
+		tick    = flag.Duration("tick", 100*time.Millisecond, "interval checking the point patterns")
+		debug   = flag.Bool("debug", false, "log every frame")
+	)
+	flag.Parse()
+
+	if *file == "" {
+		fatal(fmt.Errorf("missing -points"))
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+	params := &asdu.Params{CauseSize: *cause, CommonAddrSize: *caSize, InfoObjAddrSize: *ioa, InfoObjTimeZone: time.Local}
+	if err := params.Valid(); err != nil {
+		fatal(err)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		fatal(err)
+	}
+	points, err := loadPoints(f, *format)
+	f.Close()
+	if err != nil {
+		fatal(err)
+	}
+	for _, p := range points {
+		if err = params.ValidCommonAddr(p.ca); err != nil {
+			fatal(fmt.Errorf("point %d:%d: %v", p.ca, p.ioa, err))
+		}
+	}
+
+	router := cs104.NewRouter()
+	srv := cs104.NewServer(router)
+	srv.SetParams(params)
+	srv.LogMode(*debug)
+	sim := newSimulator(srv, points, *timeTag)
+	for ca, st := range sim.stations {
+		if err = router.Handle(ca, st); err != nil {
+			fatal(err)
+		}
+	}
+
+	go func() {
+		t := time.NewTicker(*tick)
+		defer t.Stop()
+		for now := range t.C {
+			if err := sim.tick(now); err != nil {
+				fmt.Fprintln(os.Stderr, "iec104sim:", err)
+			}
+		}
+	}()
+	fmt.Printf("simulating %d points of %d stations on %s\n", len(points), len(sim.stations), *listen)
+	srv.ListenAndServer(*listen)
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "iec104sim:", err)
+	os.Exit(1)
+}
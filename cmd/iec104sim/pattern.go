@@ -0,0 +1,151 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pattern 点值的变化模式
+type pattern interface {
+	// next 返回时刻now的值, cur为当前值
+	next(now time.Time, cur float64) float64
+}
+
+// ramp 从min按step递增到max后回到min
+type ramp struct {
+	min, max, step float64
+}
+
+func (sf *ramp) next(_ time.Time, cur float64) float64 {
+	v := cur + sf.step
+	if v > sf.max || v < sf.min {
+		if sf.step >= 0 {
+			return sf.min
+		}
+		return sf.max
+	}
+	return v
+}
+
+// sine 正弦波 offset + amplitude*sin(2π*t/period)
+type sine struct {
+	amplitude float64
+	period    time.Duration
+	offset    float64
+	start     time.Time
+}
+
+func (sf *sine) next(now time.Time, _ float64) float64 {
+	if sf.start.IsZero() {
+		sf.start = now
+	}
+	phase := float64(now.Sub(sf.start)) / float64(sf.period)
+	return sf.offset + sf.amplitude*math.Sin(2*math.Pi*phase)
+}
+
+// walk 随机游走, 每次变化 [-step, step], 限制在 [min, max]
+type walk struct {
+	step, min, max float64
+	rnd            *rand.Rand
+}
+
+func (sf *walk) next(_ time.Time, cur float64) float64 {
+	v := cur + (sf.rnd.Float64()*2-1)*sf.step
+	return math.Max(sf.min, math.Min(sf.max, v))
+}
+
+// steps 依次循环给定的值
+type steps struct {
+	values []float64
+	i      int
+}
+
+func (sf *steps) next(time.Time, float64) float64 {
+	v := sf.values[sf.i]
+	sf.i = (sf.i + 1) % len(sf.values)
+	return v
+}
+
+// parsePattern 解析模式, 参数以空白分隔:
+//
+//	ramp <min> <max> <step>
+//	sine <amplitude> <period> [offset]
+//	walk <step> <min> <max>
+//	steps <value> ...
+func parsePattern(s string) (pattern, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty pattern")
+	}
+	name, args := strings.ToLower(fields[0]), fields[1:]
+	nums := func(n int) ([]float64, error) {
+		if len(args) != n {
+			return nil, fmt.Errorf("pattern %s: expect %d arguments", name, n)
+		}
+		vs := make([]float64, n)
+		for i, a := range args {
+			v, err := strconv.ParseFloat(a, 64)
+			if err != nil {
+				return nil, fmt.Errorf("pattern %s: invalid argument %q", name, a)
+			}
+			vs[i] = v
+		}
+		return vs, nil
+	}
+
+	switch name {
+	case "ramp":
+		vs, err := nums(3)
+		if err != nil {
+			return nil, err
+		}
+		if vs[0] >= vs[1] || vs[2] == 0 {
+			return nil, fmt.Errorf("pattern ramp: need min < max and step != 0")
+		}
+		return &ramp{vs[0], vs[1], vs[2]}, nil
+	case "sine":
+		if len(args) == 2 {
+			args = append(args, "0")
+		}
+		if len(args) != 3 {
+			return nil, fmt.Errorf("pattern sine: expect amplitude period [offset]")
+		}
+		period, err := time.ParseDuration(args[1])
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("pattern sine: invalid period %q", args[1])
+		}
+		args = []string{args[0], args[2]}
+		vs, err := nums(2)
+		if err != nil {
+			return nil, err
+		}
+		return &sine{amplitude: vs[0], period: period, offset: vs[1]}, nil
+	case "walk":
+		vs, err := nums(3)
+		if err != nil {
+			return nil, err
+		}
+		if vs[1] >= vs[2] || vs[0] <= 0 {
+			return nil, fmt.Errorf("pattern walk: need step > 0 and min < max")
+		}
+		return &walk{vs[0], vs[1], vs[2], rand.New(rand.NewSource(time.Now().UnixNano()))}, nil
+	case "steps":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("pattern steps: expect at least one value")
+		}
+		vs, err := nums(len(args))
+		if err != nil {
+			return nil, err
+		}
+		return &steps{values: vs}, nil
+	}
+	return nil, fmt.Errorf("unknown pattern %q", name)
+}
@@ -0,0 +1,273 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// 控制命令的应答行为
+const (
+	controlConfirm = "confirm" // 肯定确认并执行
+	controlReject  = "reject"  // 否定确认
+	controlIgnore  = "ignore"  // 不应答
+)
+
+// monitorTypes 支持的监视方向类型
+var monitorTypes = map[string]asdu.TypeID{
+	"M_SP_NA_1": asdu.M_SP_NA_1,
+	"M_DP_NA_1": asdu.M_DP_NA_1,
+	"M_ME_NA_1": asdu.M_ME_NA_1,
+	"M_ME_NB_1": asdu.M_ME_NB_1,
+	"M_ME_NC_1": asdu.M_ME_NC_1,
+	"M_IT_NA_1": asdu.M_IT_NA_1,
+}
+
+// commandTypes 支持的控制方向类型, 带时标的类型使用同一个点
+var commandTypes = map[string]asdu.TypeID{
+	"C_SC_NA_1": asdu.C_SC_NA_1,
+	"C_DC_NA_1": asdu.C_DC_NA_1,
+	"C_SE_NA_1": asdu.C_SE_NA_1,
+	"C_SE_NB_1": asdu.C_SE_NB_1,
+	"C_SE_NC_1": asdu.C_SE_NC_1,
+}
+
+// point 点表中的一个点
+type point struct {
+	ca       asdu.CommonAddr
+	ioa      asdu.InfoObjAddr
+	typeID   asdu.TypeID
+	group    int           // 召唤组 1..16, 计数量为 1..4, 0 只响应站召唤
+	initial  float64       // 初始值
+	deadband float64       // 突发上送的死区, 0 为任何变化都上送
+	pattern  pattern       // 值变化的模式, nil 为固定值
+	period   time.Duration // 按模式更新的周期
+	control  string        // 控制命令的应答行为
+	feedback asdu.InfoObjAddr
+
+	value float64   // 当前值
+	sent  float64   // 最后上送的值
+	next  time.Time // 下次按模式更新的时间
+}
+
+// isCommand 是否是控制命令点
+func (sf *point) isCommand() bool {
+	return sf.typeID >= asdu.C_SC_NA_1
+}
+
+// loadPoints 读取点表, format 为 csv 或 yaml
+func loadPoints(r io.Reader, format string) ([]*point, error) {
+	var records []map[string]string
+	var err error
+	switch format {
+	case "csv":
+		records, err = readCSV(r)
+	case "yaml", "yml":
+		records, err = readYAML(r)
+	default:
+		return nil, fmt.Errorf("unknown point list format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]*point, 0, len(records))
+	seen := make(map[asdu.CommonAddr]map[asdu.InfoObjAddr]*point)
+	for i, rec := range records {
+		p, err := parsePoint(rec)
+		if err != nil {
+			return nil, fmt.Errorf("point %d: %v", i+1, err)
+		}
+		if seen[p.ca] == nil {
+			seen[p.ca] = make(map[asdu.InfoObjAddr]*point)
+		}
+		if _, ok := seen[p.ca][p.ioa]; ok {
+			return nil, fmt.Errorf("point %d: duplicate address %d:%d", i+1, p.ca, p.ioa)
+		}
+		seen[p.ca][p.ioa] = p
+		points = append(points, p)
+	}
+	for _, p := range points {
+		if p.feedback == 0 {
+			continue
+		}
+		if fb, ok := seen[p.ca][p.feedback]; !ok || fb.isCommand() {
+			return nil, fmt.Errorf("point %d:%d: feedback %d is not a monitor point", p.ca, p.ioa, p.feedback)
+		}
+	}
+	return points, nil
+}
+
+// parsePoint 解析一个点
+func parsePoint(rec map[string]string) (*point, error) {
+	p := &point{control: controlConfirm, period: time.Second}
+
+	ca, err := strconv.ParseUint(rec["ca"], 0, 16)
+	if err != nil || ca == 0 || ca == uint64(asdu.GlobalCommonAddr) {
+		return nil, fmt.Errorf("invalid ca %q", rec["ca"])
+	}
+	p.ca = asdu.CommonAddr(ca)
+	ioa, err := strconv.ParseUint(rec["ioa"], 0, 24)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ioa %q", rec["ioa"])
+	}
+	p.ioa = asdu.InfoObjAddr(ioa)
+
+	name := strings.ToUpper(rec["type"])
+	if t, ok := monitorTypes[name]; ok {
+		p.typeID = t
+	} else if t, ok = commandTypes[name]; ok {
+		p.typeID = t
+	} else {
+		return nil, fmt.Errorf("unsupported type %q", rec["type"])
+	}
+
+	if v := rec["group"]; v != "" {
+		if p.group, err = strconv.Atoi(v); err != nil || p.group < 0 || p.group > 16 ||
+			(p.typeID == asdu.M_IT_NA_1 && p.group > 4) {
+			return nil, fmt.Errorf("invalid group %q", v)
+		}
+	}
+	if v := rec["value"]; v != "" {
+		if p.initial, err = parseValue(p.typeID, v); err != nil {
+			return nil, err
+		}
+	}
+	if v := rec["deadband"]; v != "" {
+		if p.deadband, err = strconv.ParseFloat(v, 64); err != nil || p.deadband < 0 {
+			return nil, fmt.Errorf("invalid deadband %q", v)
+		}
+	}
+	if v := rec["period"]; v != "" {
+		if p.period, err = time.ParseDuration(v); err != nil || p.period <= 0 {
+			return nil, fmt.Errorf("invalid period %q", v)
+		}
+	}
+	if v := rec["pattern"]; v != "" {
+		if p.isCommand() {
+			return nil, fmt.Errorf("pattern is not allowed for command type %s", name)
+		}
+		if p.pattern, err = parsePattern(v); err != nil {
+			return nil, err
+		}
+	}
+	if v := strings.ToLower(rec["control"]); v != "" {
+		if v != controlConfirm && v != controlReject && v != controlIgnore {
+			return nil, fmt.Errorf("invalid control %q", rec["control"])
+		}
+		p.control = v
+	}
+	if v := rec["feedback"]; v != "" {
+		if !p.isCommand() {
+			return nil, fmt.Errorf("feedback is only allowed for command type")
+		}
+		fb, err := strconv.ParseUint(v, 0, 24)
+		if err != nil || fb == 0 {
+			return nil, fmt.Errorf("invalid feedback %q", v)
+		}
+		p.feedback = asdu.InfoObjAddr(fb)
+	}
+	p.value, p.sent = p.initial, p.initial
+	return p, nil
+}
+
+// parseValue 解析点值, 单点可用 on/off, 双点可用 off/on/intermediate
+func parseValue(t asdu.TypeID, s string) (float64, error) {
+	switch strings.ToLower(s) {
+	case "on", "true":
+		if t == asdu.M_DP_NA_1 {
+			return float64(asdu.DPIDeterminedOn), nil
+		}
+		return 1, nil
+	case "off", "false":
+		if t == asdu.M_DP_NA_1 {
+			return float64(asdu.DPIDeterminedOff), nil
+		}
+		return 0, nil
+	case "intermediate":
+		return float64(asdu.DPIIndeterminateOrIntermediate), nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+// readCSV 读取带表头的CSV, 以#开头的行为注释
+func readCSV(r io.Reader) ([]map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	header := rows[0]
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	records := make([]map[string]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		rec := make(map[string]string, len(header))
+		for i, v := range row {
+			rec[header[i]] = strings.TrimSpace(v)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// readYAML 读取YAML点表, 只支持由平铺映射组成的列表, 可以有顶层的 points: 键, 例:
+//
+//	points:
+//	  - ca: 1
+//	    ioa: 100
+//	    type: M_SP_NA_1
+func readYAML(r io.Reader) ([]map[string]string, error) {
+	var records []map[string]string
+	var rec map[string]string
+
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line == "---" || line == "points:" {
+			continue
+		}
+		if strings.HasPrefix(line, "- ") || line == "-" {
+			rec = make(map[string]string)
+			records = append(records, rec)
+			line = strings.TrimSpace(strings.TrimPrefix(line, "-"))
+			if line == "" {
+				continue
+			}
+		}
+		i := strings.IndexByte(line, ':')
+		if rec == nil || i <= 0 {
+			return nil, fmt.Errorf("yaml line %d: expect key: value in a list item", n)
+		}
+		key, value := strings.ToLower(strings.TrimSpace(line[:i])), strings.TrimSpace(line[i+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		rec[key] = value
+	}
+	return records, sc.Err()
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// mockConn record the sent asdu
+type mockConn struct {
+	sent []string
+}
+
+func (sf *mockConn) Params() *asdu.Params     { return asdu.ParamsWide }
+func (sf *mockConn) UnderlyingConn() net.Conn { return nil }
+func (sf *mockConn) Send(a *asdu.ASDU) error {
+	sf.sent = append(sf.sent, a.String())
+	return nil
+}
+
+// packConn keep the last sent asdu, used to build the request
+type packConn struct {
+	a *asdu.ASDU
+}
+
+func (sf *packConn) Params() *asdu.Params     { return asdu.ParamsWide }
+func (sf *packConn) UnderlyingConn() net.Conn { return nil }
+func (sf *packConn) Send(a *asdu.ASDU) error {
+	sf.a = a
+	return nil
+}
+
+const testCSV = `# test point list
+ca,ioa,type,group,value,deadband,pattern,control,feedback
+1,100,M_SP_NA_1,1,on,,,,
+1,101,M_DP_NA_1,2,off,,,,
+1,200,M_ME_NC_1,1,10.5,0.5,ramp 0 100 1,,
+1,300,M_IT_NA_1,1,42,,,,
+1,1000,C_SC_NA_1,,,,,confirm,100
+1,1001,C_DC_NA_1,,,,,reject,
+`
+
+const testYAML = `points:
+  - ca: 1
+    ioa: 100
+    type: M_SP_NA_1
+    value: on # comment
+  - ca: 2
+    ioa: 200
+    type: m_me_nc_1
+    pattern: "sine 10 1m"
+`
+
+func TestLoadPoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		data    string
+		want    int
+		wantErr string
+	}{
+		{"csv", "csv", testCSV, 6, ""},
+		{"yaml", "yaml", testYAML, 2, ""},
+		{"duplicate", "csv", "ca,ioa,type\n1,1,M_SP_NA_1\n1,1,M_DP_NA_1\n", 0, "duplicate address"},
+		{"type", "csv", "ca,ioa,type\n1,1,M_XX_NA_1\n", 0, "unsupported type"},
+		{"group", "csv", "ca,ioa,type,group\n1,1,M_IT_NA_1,5\n", 0, "invalid group"},
+		{"pattern", "csv", "ca,ioa,type,pattern\n1,1,M_ME_NC_1,ramp 1\n", 0, "pattern ramp"},
+		{"feedback", "csv", "ca,ioa,type,feedback\n1,1,C_SC_NA_1,2\n", 0, "feedback 2"},
+		{"yaml key", "yaml", "- ca\n", 0, "yaml line 1"},
+		{"format", "json", "", 0, "unknown point list format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadPoints(strings.NewReader(tt.data), tt.format)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadPoints() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || len(got) != tt.want {
+				t.Errorf("loadPoints() got %d points, error = %v, want %d", len(got), err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		s    string
+		cur  float64
+		want []float64
+	}{
+		{"ramp", "ramp 0 2 1", 0, []float64{1, 2, 0, 1}},
+		{"ramp down", "ramp 0 2 -1", 1, []float64{0, 2, 1}},
+		{"steps", "steps 1 0 3", 0, []float64{1, 0, 3, 1}},
+		{"sine", "sine 10 4s 5", 0, []float64{5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := parsePattern(tt.s)
+			if err != nil {
+				t.Fatalf("parsePattern() error = %v", err)
+			}
+			cur := tt.cur
+			for i, want := range tt.want {
+				if cur = p.next(now, cur); cur != want {
+					t.Errorf("next() #%d = %v, want %v", i, cur, want)
+				}
+			}
+		})
+	}
+
+	p, _ := parsePattern("walk 1 -2 2")
+	cur := 0.0
+	for i := 0; i < 100; i++ {
+		if cur = p.next(now, cur); cur < -2 || cur > 2 {
+			t.Fatalf("walk next() = %v, out of range", cur)
+		}
+	}
+}
+
+func TestStation(t *testing.T) {
+	req := &packConn{}
+	act := asdu.CauseOfTransmission{Cause: asdu.Activation}
+	tests := []struct {
+		name  string
+		build func() error
+		want  []string
+	}{
+		{
+			"interrogation",
+			func() error { return asdu.InterrogationCmd(req, act, 1, asdu.QOIStation) },
+			[]string{
+				"TID<C_IC_NA_1> COT<ActivationCon> @1: 0: qoi=20",
+				"TID<M_SP_NA_1> COT<InterrogatedByStation> @1: 100: on",
+				"TID<M_DP_NA_1> COT<InterrogatedByStation> @1: 101: 1",
+				"TID<M_ME_NC_1> COT<InterrogatedByStation> @1: 200: 10.5",
+				"TID<C_IC_NA_1> COT<ActivationTerm> @1: 0: qoi=20",
+			},
+		},
+		{
+			"group",
+			func() error { return asdu.InterrogationCmd(req, act, 1, asdu.QOIGroup2) },
+			[]string{
+				"TID<C_IC_NA_1> COT<ActivationCon> @1: 0: qoi=22",
+				"TID<M_DP_NA_1> COT<InterrogatedByGroup2> @1: 101: 1",
+				"TID<C_IC_NA_1> COT<ActivationTerm> @1: 0: qoi=22",
+			},
+		},
+		{
+			"counter",
+			func() error {
+				return asdu.CounterInterrogationCmd(req, act, 1, asdu.QualifierCountCall{Request: asdu.QCCTotal})
+			},
+			[]string{
+				"TID<C_CI_NA_1> COT<ActivationCon> @1: 0: qcc=0x05",
+				"TID<M_IT_NA_1> COT<RequestByGeneralCounter> @1: 300: 42 sq=0 cy=false ca=false iv=false",
+				"TID<C_CI_NA_1> COT<ActivationTerm> @1: 0: qcc=0x05",
+			},
+		},
+		{
+			"read unknown",
+			func() error { return asdu.ReadCmd(req, asdu.CauseOfTransmission{Cause: asdu.Request}, 1, 999) },
+			[]string{"TID<C_RD_NA_1> COT<UnknownIOA> @1: 999: read"},
+		},
+		{
+			"select",
+			func() error {
+				return asdu.SingleCmd(req, asdu.C_SC_NA_1, act, 1,
+					asdu.SingleCommandInfo{Ioa: 1000, Value: false, Qoc: asdu.QualifierOfCommand{InSelect: true}})
+			},
+			[]string{"TID<C_SC_NA_1> COT<ActivationCon> @1: 1000: off qoc=0x80"},
+		},
+		{
+			"execute",
+			func() error {
+				return asdu.SingleCmd(req, asdu.C_SC_NA_1, act, 1, asdu.SingleCommandInfo{Ioa: 1000, Value: false})
+			},
+			[]string{
+				"TID<C_SC_NA_1> COT<ActivationCon> @1: 1000: off qoc=0x00",
+				"TID<M_SP_NA_1> COT<ReturnInfoRemote> @1: 100: off",
+				"TID<C_SC_NA_1> COT<ActivationTerm> @1: 1000: off qoc=0x00",
+			},
+		},
+		{
+			"reject",
+			func() error {
+				return asdu.DoubleCmd(req, asdu.C_DC_NA_1, act, 1, asdu.DoubleCommandInfo{Ioa: 1001, Value: asdu.DCOOn})
+			},
+			[]string{"TID<C_DC_NA_1> COT<ActivationCon,neg> @1: 1001: 1 qoc=0x00"},
+		},
+		{
+			"type mismatch",
+			func() error {
+				return asdu.DoubleCmd(req, asdu.C_DC_NA_1, act, 1, asdu.DoubleCommandInfo{Ioa: 1000, Value: asdu.DCOOn})
+			},
+			[]string{"TID<C_DC_NA_1> COT<UnknownIOA> @1: 1000: 1 qoc=0x00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := loadPoints(strings.NewReader(testCSV), "csv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &mockConn{}
+			sim := newSimulator(c, points, false)
+			if err = tt.build(); err != nil {
+				t.Fatal(err)
+			}
+			pack := req.a
+			st := sim.stations[1]
+			switch pack.Type {
+			case asdu.C_IC_NA_1:
+				_, qoi := pack.Clone().GetInterrogationCmd()
+				err = st.InterrogationHandler(c, pack, qoi)
+			case asdu.C_CI_NA_1:
+				_, qcc := pack.Clone().GetCounterInterrogationCmd()
+				err = st.CounterInterrogationHandler(c, pack, qcc)
+			case asdu.C_RD_NA_1:
+				err = st.ReadHandler(c, pack, pack.Clone().GetReadCmd())
+			default:
+				err = st.ASDUHandler(c, pack)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(c.sent, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("sent\n%s\nwant\n%s", strings.Join(c.sent, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestSimulator_tick(t *testing.T) {
+	points, err := loadPoints(strings.NewReader(testCSV), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &mockConn{}
+	sim := newSimulator(c, points, false)
+	now := time.Now()
+	// ramp 10.5 -> 11.5 -> 12.5, deadband 0.5 exceeded every step
+	for i := 0; i < 2; i++ {
+		if err = sim.tick(now); err != nil {
+			t.Fatal(err)
+		}
+		_ = sim.tick(now) // not due yet
+		now = now.Add(time.Second)
+	}
+	want := []string{
+		"TID<M_ME_NC_1> COT<Spontaneous> @1: 200: 11.5",
+		"TID<M_ME_NC_1> COT<Spontaneous> @1: 200: 12.5",
+	}
+	if strings.Join(c.sent, "\n") != strings.Join(want, "\n") {
+		t.Errorf("tick() sent %q, want %q", c.sent, want)
+	}
+}
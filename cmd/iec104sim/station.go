@@ -0,0 +1,419 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+// commandBase 带时标的控制命令映射到不带时标的类型
+var commandBase = map[asdu.TypeID]asdu.TypeID{
+	asdu.C_SC_NA_1: asdu.C_SC_NA_1,
+	asdu.C_SC_TA_1: asdu.C_SC_NA_1,
+	asdu.C_DC_NA_1: asdu.C_DC_NA_1,
+	asdu.C_DC_TA_1: asdu.C_DC_NA_1,
+	asdu.C_SE_NA_1: asdu.C_SE_NA_1,
+	asdu.C_SE_TA_1: asdu.C_SE_NA_1,
+	asdu.C_SE_NB_1: asdu.C_SE_NB_1,
+	asdu.C_SE_TB_1: asdu.C_SE_NB_1,
+	asdu.C_SE_NC_1: asdu.C_SE_NC_1,
+	asdu.C_SE_TC_1: asdu.C_SE_NC_1,
+}
+
+// simulator 按点表模拟的子站, 每个公共地址一个站
+type simulator struct {
+	mu       sync.Mutex
+	stations map[asdu.CommonAddr]*station
+	conn     asdu.Connect // 突发上送的连接, 通常是 cs104.Server
+	timeTag  bool         // 突发上送带CP56Time2a时标
+}
+
+// station 一个公共地址的站, imp cs104.ServerHandlerInterface
+type station struct {
+	sim    *simulator
+	ca     asdu.CommonAddr
+	points []*point // 信息对象地址升序
+	byIOA  map[asdu.InfoObjAddr]*point
+}
+
+var _ cs104.ServerHandlerInterface = (*station)(nil)
+
+func newSimulator(conn asdu.Connect, points []*point, timeTag bool) *simulator {
+	sim := &simulator{
+		stations: make(map[asdu.CommonAddr]*station),
+		conn:     conn,
+		timeTag:  timeTag,
+	}
+	for _, p := range points {
+		st, ok := sim.stations[p.ca]
+		if !ok {
+			st = &station{sim: sim, ca: p.ca, byIOA: make(map[asdu.InfoObjAddr]*point)}
+			sim.stations[p.ca] = st
+		}
+		st.points = append(st.points, p)
+		st.byIOA[p.ioa] = p
+	}
+	for _, st := range sim.stations {
+		sort.Slice(st.points, func(i, j int) bool { return st.points[i].ioa < st.points[j].ioa })
+	}
+	return sim
+}
+
+// tick 按模式更新点值, 超出死区的变化突发上送
+func (sf *simulator) tick(now time.Time) error {
+	changed := make(map[asdu.CommonAddr][]*point)
+	sf.mu.Lock()
+	for ca, st := range sf.stations {
+		for _, p := range st.points {
+			if p.pattern == nil || now.Before(p.next) {
+				continue
+			}
+			p.next = now.Add(p.period)
+			p.value = normalize(p.typeID, p.pattern.next(now, p.value))
+			if exceeds(p) {
+				p.sent = p.value
+				changed[ca] = append(changed[ca], p)
+			}
+		}
+	}
+	sf.mu.Unlock()
+	return sf.spontaneous(changed, asdu.Spontaneous, now)
+}
+
+// spontaneous 上送变化的点
+func (sf *simulator) spontaneous(changed map[asdu.CommonAddr][]*point, cause asdu.Cause, now time.Time) error {
+	if !sf.timeTag {
+		now = time.Time{}
+	}
+	cas := make([]asdu.CommonAddr, 0, len(changed))
+	for ca := range changed {
+		cas = append(cas, ca)
+	}
+	sort.Slice(cas, func(i, j int) bool { return cas[i] < cas[j] })
+	for _, ca := range cas {
+		if err := sendPoints(sf.conn, asdu.CauseOfTransmission{Cause: cause}, ca, changed[ca], now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exceeds 点值的变化是否超出死区, 计数量不突发上送
+func exceeds(p *point) bool {
+	switch p.typeID {
+	case asdu.M_IT_NA_1:
+		return false
+	case asdu.M_SP_NA_1, asdu.M_DP_NA_1:
+		return p.value != p.sent
+	}
+	return math.Abs(p.value-p.sent) > p.deadband || (p.deadband == 0 && p.value != p.sent)
+}
+
+// normalize 将值限制在类型的取值范围内
+func normalize(t asdu.TypeID, v float64) float64 {
+	switch t {
+	case asdu.M_SP_NA_1:
+		if v >= 0.5 {
+			return 1
+		}
+		return 0
+	case asdu.M_DP_NA_1:
+		return math.Max(0, math.Min(3, math.Round(v)))
+	case asdu.M_ME_NA_1:
+		return math.Max(-1, math.Min(32767.0/32768, v))
+	case asdu.M_ME_NB_1:
+		return math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(v)))
+	case asdu.M_IT_NA_1:
+		return math.Max(math.MinInt32, math.Min(math.MaxInt32, math.Round(v)))
+	}
+	return v
+}
+
+// snapshot 复制满足条件的监视点
+func (sf *station) snapshot(match func(p *point) bool) []*point {
+	sf.sim.mu.Lock()
+	defer sf.sim.mu.Unlock()
+	var r []*point
+	for _, p := range sf.points {
+		if !p.isCommand() && match(p) {
+			v := *p
+			r = append(r, &v)
+		}
+	}
+	return r
+}
+
+// InterrogationHandler imp cs104.ServerHandlerInterface, 计数量不响应总召唤
+func (sf *station) InterrogationHandler(c asdu.Connect, pack *asdu.ASDU, qoi asdu.QualifierOfInterrogation) error {
+	if qoi < asdu.QOIStation || qoi > asdu.QOIGroup16 {
+		return pack.SendReplyMirror(c, asdu.UnknownIOA)
+	}
+	if pack.Coa.Cause == asdu.Deactivation {
+		return pack.SendReplyMirror(c, asdu.DeactivationCon)
+	}
+	group := int(qoi - asdu.QOIStation)
+	points := sf.snapshot(func(p *point) bool {
+		return p.typeID != asdu.M_IT_NA_1 && (group == 0 || p.group == group)
+	})
+	if err := pack.SendReplyMirror(c, asdu.ActivationCon); err != nil {
+		return err
+	}
+	cause := asdu.InterrogatedByStation + asdu.Cause(group)
+	if err := sendPoints(c, asdu.CauseOfTransmission{Cause: cause}, sf.ca, points, time.Time{}); err != nil {
+		return err
+	}
+	return pack.SendReplyMirror(c, asdu.ActivationTerm)
+}
+
+// CounterInterrogationHandler imp cs104.ServerHandlerInterface
+func (sf *station) CounterInterrogationHandler(c asdu.Connect, pack *asdu.ASDU, qcc asdu.QualifierCountCall) error {
+	if qcc.Request < asdu.QCCGroup1 || qcc.Request > asdu.QCCTotal {
+		return pack.SendReplyMirror(c, asdu.UnknownIOA)
+	}
+	group := int(qcc.Request)
+	if qcc.Request == asdu.QCCTotal {
+		group = 0
+	}
+	match := func(p *point) bool {
+		return p.typeID == asdu.M_IT_NA_1 && (group == 0 || p.group == group)
+	}
+	points := sf.snapshot(match)
+	if qcc.Freeze == asdu.QCCFrzFreezeReset || qcc.Freeze == asdu.QCCFrzReset {
+		sf.sim.mu.Lock()
+		for _, p := range sf.points {
+			if match(p) {
+				p.value, p.sent = 0, 0
+			}
+		}
+		sf.sim.mu.Unlock()
+	}
+
+	if err := pack.SendReplyMirror(c, asdu.ActivationCon); err != nil {
+		return err
+	}
+	if qcc.Freeze == asdu.QCCFrzRead || qcc.Freeze == asdu.QCCFrzFreezeReset {
+		cause := asdu.RequestByGeneralCounter + asdu.Cause(group)
+		if err := sendPoints(c, asdu.CauseOfTransmission{Cause: cause}, sf.ca, points, time.Time{}); err != nil {
+			return err
+		}
+	}
+	return pack.SendReplyMirror(c, asdu.ActivationTerm)
+}
+
+// ReadHandler imp cs104.ServerHandlerInterface
+func (sf *station) ReadHandler(c asdu.Connect, pack *asdu.ASDU, ioa asdu.InfoObjAddr) error {
+	points := sf.snapshot(func(p *point) bool { return p.ioa == ioa })
+	if len(points) == 0 {
+		return pack.SendReplyMirror(c, asdu.UnknownIOA)
+	}
+	return sendPoints(c, asdu.CauseOfTransmission{Cause: asdu.Request}, sf.ca, points, time.Time{})
+}
+
+// ClockSyncHandler imp cs104.ServerHandlerInterface
+func (sf *station) ClockSyncHandler(c asdu.Connect, pack *asdu.ASDU, _ time.Time) error {
+	return pack.SendReplyMirror(c, asdu.ActivationCon)
+}
+
+// ResetProcessHandler imp cs104.ServerHandlerInterface, 所有点恢复初始值
+func (sf *station) ResetProcessHandler(c asdu.Connect, pack *asdu.ASDU, _ asdu.QualifierOfResetProcessCmd) error {
+	sf.sim.mu.Lock()
+	for _, p := range sf.points {
+		p.value, p.sent = p.initial, p.initial
+	}
+	sf.sim.mu.Unlock()
+	return pack.SendReplyMirror(c, asdu.ActivationCon)
+}
+
+// DelayAcquisitionHandler imp cs104.ServerHandlerInterface
+func (sf *station) DelayAcquisitionHandler(c asdu.Connect, pack *asdu.ASDU, _ uint16) error {
+	return pack.SendReplyMirror(c, asdu.ActivationCon)
+}
+
+// ASDUHandler imp cs104.ServerHandlerInterface, 处理控制命令
+func (sf *station) ASDUHandler(c asdu.Connect, pack *asdu.ASDU) error {
+	base, ok := commandBase[pack.Type]
+	if !ok {
+		return pack.SendReplyMirror(c, asdu.UnknownTypeID)
+	}
+	if pack.Coa.Cause != asdu.Activation && pack.Coa.Cause != asdu.Deactivation {
+		return pack.SendReplyMirror(c, asdu.UnknownCOT)
+	}
+	ioa, value, inSelect := decodeCommand(pack)
+	p, ok := sf.byIOA[ioa]
+	if !ok || p.typeID != base {
+		return pack.SendReplyMirror(c, asdu.UnknownIOA)
+	}
+
+	switch p.control {
+	case controlIgnore:
+		return nil
+	case controlReject:
+		neg := pack.Clone()
+		neg.Coa.Cause = asdu.ActivationCon
+		if pack.Coa.Cause == asdu.Deactivation {
+			neg.Coa.Cause = asdu.DeactivationCon
+		}
+		neg.Coa.IsNegative = true
+		return c.Send(neg)
+	}
+	if pack.Coa.Cause == asdu.Deactivation {
+		return pack.SendReplyMirror(c, asdu.DeactivationCon)
+	}
+	if err := pack.SendReplyMirror(c, asdu.ActivationCon); err != nil {
+		return err
+	}
+	if inSelect {
+		return nil
+	}
+
+	now := time.Now()
+	var changed map[asdu.CommonAddr][]*point
+	sf.sim.mu.Lock()
+	p.value = value
+	if fb, ok := sf.byIOA[p.feedback]; ok && p.feedback != 0 {
+		fb.value = normalize(fb.typeID, value)
+		fb.sent = fb.value
+		v := *fb
+		changed = map[asdu.CommonAddr][]*point{sf.ca: {&v}}
+	}
+	sf.sim.mu.Unlock()
+	if err := sf.sim.spontaneous(changed, asdu.ReturnInfoRemote, now); err != nil {
+		return err
+	}
+	return pack.SendReplyMirror(c, asdu.ActivationTerm)
+}
+
+// decodeCommand 解码控制命令的信息对象地址, 值和是否选择
+func decodeCommand(a *asdu.ASDU) (asdu.InfoObjAddr, float64, bool) {
+	switch a.Type {
+	case asdu.C_SC_NA_1, asdu.C_SC_TA_1:
+		cmd := a.GetSingleCmd()
+		v := 0.0
+		if cmd.Value {
+			v = 1
+		}
+		return cmd.Ioa, v, cmd.Qoc.InSelect
+	case asdu.C_DC_NA_1, asdu.C_DC_TA_1:
+		cmd := a.GetDoubleCmd()
+		return cmd.Ioa, float64(cmd.Value), cmd.Qoc.InSelect
+	case asdu.C_SE_NA_1, asdu.C_SE_TA_1:
+		cmd := a.GetSetpointNormalCmd()
+		return cmd.Ioa, cmd.Value.Float64(), cmd.Qos.InSelect
+	case asdu.C_SE_NB_1, asdu.C_SE_TB_1:
+		cmd := a.GetSetpointCmdScaled()
+		return cmd.Ioa, float64(cmd.Value), cmd.Qos.InSelect
+	case asdu.C_SE_NC_1, asdu.C_SE_TC_1:
+		cmd := a.GetSetpointFloatCmd()
+		return cmd.Ioa, float64(cmd.Value), cmd.Qos.InSelect
+	}
+	return 0, 0, false
+}
+
+// sendPoints 按类型分组发送点值, t不为零值时使用带CP56Time2a时标的类型
+func sendPoints(c asdu.Connect, coa asdu.CauseOfTransmission, ca asdu.CommonAddr, points []*point, t time.Time) error {
+	byType := make(map[asdu.TypeID][]*point)
+	var types []asdu.TypeID
+	for _, p := range points {
+		if _, ok := byType[p.typeID]; !ok {
+			types = append(types, p.typeID)
+		}
+		byType[p.typeID] = append(byType[p.typeID], p)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	for _, typeID := range types {
+		ps := byType[typeID]
+		size, _ := asdu.GetInfoObjSize(typeID)
+		if !t.IsZero() {
+			size += 7
+		}
+		n := (asdu.ASDUSizeMax - c.Params().IdentifierSize()) / (c.Params().InfoObjAddrSize + size)
+		if n > 127 {
+			n = 127
+		}
+		for len(ps) > 0 {
+			chunk := ps
+			if len(chunk) > n {
+				chunk = chunk[:n]
+			}
+			ps = ps[len(chunk):]
+			if err := sendChunk(c, typeID, coa, ca, chunk, t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sendChunk 发送同一类型的点值
+func sendChunk(c asdu.Connect, typeID asdu.TypeID, coa asdu.CauseOfTransmission, ca asdu.CommonAddr, points []*point, t time.Time) error {
+	switch typeID {
+	case asdu.M_SP_NA_1:
+		infos := make([]asdu.SinglePointInfo, 0, len(points))
+		for _, p := range points {
+			infos = append(infos, asdu.SinglePointInfo{Ioa: p.ioa, Value: p.value != 0, Time: t})
+		}
+		if t.IsZero() {
+			return asdu.Single(c, false, coa, ca, infos...)
+		}
+		return asdu.SingleCP56Time2a(c, coa, ca, infos...)
+	case asdu.M_DP_NA_1:
+		infos := make([]asdu.DoublePointInfo, 0, len(points))
+		for _, p := range points {
+			infos = append(infos, asdu.DoublePointInfo{Ioa: p.ioa, Value: asdu.DoublePoint(p.value), Time: t})
+		}
+		if t.IsZero() {
+			return asdu.Double(c, false, coa, ca, infos...)
+		}
+		return asdu.DoubleCP56Time2a(c, coa, ca, infos...)
+	case asdu.M_ME_NA_1:
+		infos := make([]asdu.MeasuredValueNormalInfo, 0, len(points))
+		for _, p := range points {
+			infos = append(infos, asdu.MeasuredValueNormalInfo{Ioa: p.ioa, Value: asdu.Normalize(p.value * 32768), Time: t})
+		}
+		if t.IsZero() {
+			return asdu.MeasuredValueNormal(c, false, coa, ca, infos...)
+		}
+		return asdu.MeasuredValueNormalCP56Time2a(c, coa, ca, infos...)
+	case asdu.M_ME_NB_1:
+		infos := make([]asdu.MeasuredValueScaledInfo, 0, len(points))
+		for _, p := range points {
+			infos = append(infos, asdu.MeasuredValueScaledInfo{Ioa: p.ioa, Value: int16(p.value), Time: t})
+		}
+		if t.IsZero() {
+			return asdu.MeasuredValueScaled(c, false, coa, ca, infos...)
+		}
+		return asdu.MeasuredValueScaledCP56Time2a(c, coa, ca, infos...)
+	case asdu.M_ME_NC_1:
+		infos := make([]asdu.MeasuredValueFloatInfo, 0, len(points))
+		for _, p := range points {
+			infos = append(infos, asdu.MeasuredValueFloatInfo{Ioa: p.ioa, Value: float32(p.value), Time: t})
+		}
+		if t.IsZero() {
+			return asdu.MeasuredValueFloat(c, false, coa, ca, infos...)
+		}
+		return asdu.MeasuredValueFloatCP56Time2a(c, coa, ca, infos...)
+	case asdu.M_IT_NA_1:
+		infos := make([]asdu.BinaryCounterReadingInfo, 0, len(points))
+		for _, p := range points {
+			infos = append(infos, asdu.BinaryCounterReadingInfo{
+				Ioa:   p.ioa,
+				Value: asdu.BinaryCounterReading{CounterReading: int32(p.value)},
+				Time:  t,
+			})
+		}
+		if t.IsZero() {
+			return asdu.IntegratedTotals(c, false, coa, ca, infos...)
+		}
+		return asdu.IntegratedTotalsCP56Time2a(c, coa, ca, infos...)
+	}
+	return asdu.ErrTypeIDNotMatch
+}
@@ -0,0 +1,410 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package conformance
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+// Cases 返回用例库中的所有用例
+func Cases() []Case {
+	return []Case{
+		{"startdt", "STARTDT act is confirmed within t1", caseStartDt},
+		{"stopdt", "STOPDT act is confirmed within t1, TESTFR is still answered after STOPDT", caseStopDt},
+		{"stopdt-drain", "STOPDT con is sent after all sent I frames are acknowledged, sequence numbers continue after STARTDT", caseStopDtDrain},
+		{"data-before-startdt", "no I frame is sent before STARTDT", caseDataBeforeStartDt},
+		{"testfr", "TESTFR act is confirmed within t1", caseTestFr},
+		{"t1-ack-timeout", "the connection is closed when sent I frames are not acknowledged within t1", caseT1},
+		{"t2-ack", "received I frames are acknowledged within t2", caseT2},
+		{"t3-idle-testfr", "TESTFR act is sent after t3 idle and the connection stays open after the confirm", caseT3},
+		{"k-window", "no more than k unacknowledged I frames are sent", caseK},
+		{"w-window", "w received I frames are acknowledged without waiting t2", caseW},
+		{"sequence-wraparound", "send and receive sequence numbers wrap from 32767 to 0", caseWraparound},
+		{"unknown-typeid", "an unsupported type identification is mirrored with cause unknown type", caseUnknownTypeID},
+		{"unknown-cot", "interrogation with cause spontaneous is mirrored with cause unknown cause", caseUnknownCOT},
+		{"unknown-ca", "interrogation of an unknown common address is mirrored with cause unknown common address", caseUnknownCA},
+		{"unknown-ioa", "interrogation with a non-zero object address is mirrored with cause unknown object address", caseUnknownIOA},
+		{"interrogation", "station interrogation is confirmed and terminated", caseInterrogation},
+	}
+}
+
+// isU 匹配U帧
+func isU(function byte) func(f *cs104.Frame) bool {
+	return func(f *cs104.Frame) bool {
+		return f.Control.Type == cs104.FrameU && f.Control.Function == function
+	}
+}
+
+// isReply 匹配请求的应答, 类型标识及公共地址相同
+func isReply(req *asdu.ASDU, cause asdu.Cause) func(f *cs104.Frame) bool {
+	return func(f *cs104.Frame) bool {
+		return f.ASDU != nil && f.ASDU.Type == req.Type && f.ASDU.CommonAddr == req.CommonAddr &&
+			f.ASDU.Coa.Cause == cause
+	}
+}
+
+// startDt 启动数据传输
+func startDt(c *Conn, t *Target) error {
+	if err := c.SendU(cs104.UStartDtActive); err != nil {
+		return err
+	}
+	if _, err := c.Wait(t.Config.SendUnAckTimeout1, isU(cs104.UStartDtConfirm)); err != nil {
+		return fmt.Errorf("no STARTDT con within t1: %v", err)
+	}
+	return nil
+}
+
+// testCommand 发送测试命令
+func testCommand(c *Conn, t *Target) error {
+	return asdu.TestCommand(c, asdu.CauseOfTransmission{Cause: asdu.Activation}, t.CommonAddr)
+}
+
+// newCommand 创建一个信息对象的命令, 信息元素以0填充
+func newCommand(t *Target, typeID asdu.TypeID, cause asdu.Cause, ca asdu.CommonAddr, ioa asdu.InfoObjAddr, b ...byte) *asdu.ASDU {
+	a := asdu.NewASDU(t.Params, asdu.Identifier{
+		Type:       typeID,
+		Variable:   asdu.VariableStruct{Number: 1},
+		Coa:        asdu.CauseOfTransmission{Cause: cause},
+		CommonAddr: ca,
+	})
+	_ = a.AppendInfoObjAddr(ioa)
+	if b == nil {
+		size, _ := asdu.GetInfoObjSize(typeID)
+		b = make([]byte, size)
+	}
+	a.AppendBytes(b...)
+	return a
+}
+
+// expectMirror 发送请求并等待指定原因的镜像应答
+func expectMirror(c *Conn, t *Target, req *asdu.ASDU, cause asdu.Cause) (*asdu.ASDU, error) {
+	if err := startDt(c, t); err != nil {
+		return nil, err
+	}
+	if err := c.Send(req); err != nil {
+		return nil, err
+	}
+	f, err := c.Wait(t.Config.SendUnAckTimeout1, isReply(req, cause))
+	if err != nil {
+		return nil, fmt.Errorf("no %s reply with cause %s within t1: %v", req.Type, cause, err)
+	}
+	return f.ASDU, nil
+}
+
+func caseStartDt(c *Conn, t *Target) error {
+	return startDt(c, t)
+}
+
+func caseStopDt(c *Conn, t *Target) error {
+	if err := startDt(c, t); err != nil {
+		return err
+	}
+	if err := c.SendU(cs104.UStopDtActive); err != nil {
+		return err
+	}
+	if _, err := c.Wait(t.Config.SendUnAckTimeout1, isU(cs104.UStopDtConfirm)); err != nil {
+		return fmt.Errorf("no STOPDT con within t1: %v", err)
+	}
+	if err := c.SendU(cs104.UTestFrActive); err != nil {
+		return err
+	}
+	if _, err := c.Wait(t.Config.SendUnAckTimeout1, isU(cs104.UTestFrConfirm)); err != nil {
+		return fmt.Errorf("no TESTFR con after STOPDT within t1: %v", err)
+	}
+	return nil
+}
+
+func caseStopDtDrain(c *Conn, t *Target) error {
+	c.AutoAck = false
+	isTestCon := func(f *cs104.Frame) bool {
+		return f.ASDU != nil && f.ASDU.Type == asdu.C_TS_NA_1 && f.ASDU.Coa.Cause == asdu.ActivationCon
+	}
+	if err := startDt(c, t); err != nil {
+		return err
+	}
+	if err := testCommand(c, t); err != nil {
+		return err
+	}
+	if _, err := c.Wait(t.Config.SendUnAckTimeout1, isTestCon); err != nil {
+		return fmt.Errorf("no test command con within t1: %v", err)
+	}
+	if err := c.SendU(cs104.UStopDtActive); err != nil {
+		return err
+	}
+	// 在t₁超时前确认, 确认前不应回复STOPDT确认
+	_, err := c.Wait(t.Config.SendUnAckTimeout1/2, isU(cs104.UStopDtConfirm))
+	switch {
+	case err == nil:
+		return fmt.Errorf("STOPDT con with %d unacknowledged I frames", c.Unacked())
+	case err != ErrTimeout:
+		return err
+	}
+	if err = c.SendS(); err != nil {
+		return err
+	}
+	if _, err = c.Wait(t.Config.SendUnAckTimeout1, isU(cs104.UStopDtConfirm)); err != nil {
+		return fmt.Errorf("no STOPDT con after acknowledge within t1: %v", err)
+	}
+
+	// 序号在STOPDT/STARTDT后继续, Recv检查发送序号
+	if err = startDt(c, t); err != nil {
+		return err
+	}
+	if err = testCommand(c, t); err != nil {
+		return err
+	}
+	f, err := c.Wait(t.Config.SendUnAckTimeout1, isTestCon)
+	if err != nil {
+		return fmt.Errorf("no test command con after STARTDT within t1: %v", err)
+	}
+	if f.Control.RecvSN != c.SendSN() {
+		return fmt.Errorf("receive number %d after STARTDT, want %d", f.Control.RecvSN, c.SendSN())
+	}
+	return c.SendS()
+}
+
+func caseDataBeforeStartDt(c *Conn, t *Target) error {
+	if err := testCommand(c, t); err != nil {
+		return err
+	}
+	f, err := c.Wait(t.Tolerance, func(f *cs104.Frame) bool { return f.Control.Type == cs104.FrameI })
+	switch {
+	case err == ErrTimeout || err == ErrClosed:
+		return nil
+	case err != nil:
+		return err
+	}
+	return fmt.Errorf("I frame %s received before STARTDT", f.Control)
+}
+
+func caseTestFr(c *Conn, t *Target) error {
+	if err := c.SendU(cs104.UTestFrActive); err != nil {
+		return err
+	}
+	if _, err := c.Wait(t.Config.SendUnAckTimeout1, isU(cs104.UTestFrConfirm)); err != nil {
+		return fmt.Errorf("no TESTFR con within t1: %v", err)
+	}
+	return nil
+}
+
+func caseT1(c *Conn, t *Target) error {
+	c.AutoAck = false
+	if err := startDt(c, t); err != nil {
+		return err
+	}
+	if err := testCommand(c, t); err != nil {
+		return err
+	}
+	f, err := c.Wait(t.Config.SendUnAckTimeout1, func(f *cs104.Frame) bool { return f.Control.Type == cs104.FrameI })
+	if err != nil {
+		return fmt.Errorf("no I frame received: %v", err)
+	}
+	t1 := t.Config.SendUnAckTimeout1
+	if err = c.WaitClosed(t1 + t.Tolerance); err != nil {
+		return fmt.Errorf("connection not closed within t1 after the unacknowledged I frame: %v", err)
+	}
+	if d := time.Since(f.Time); d < t1-t.Tolerance {
+		return fmt.Errorf("connection closed after %v, earlier than t1 %v", d, t1)
+	}
+	return nil
+}
+
+func caseT2(c *Conn, t *Target) error {
+	if err := startDt(c, t); err != nil {
+		return err
+	}
+	if err := testCommand(c, t); err != nil {
+		return err
+	}
+	sendSN := c.SendSN()
+	_, err := c.Wait(t.Config.RecvUnAckTimeout2+t.Tolerance, func(f *cs104.Frame) bool {
+		return f.Control.Type != cs104.FrameU && f.Control.RecvSN == sendSN
+	})
+	if err != nil {
+		return fmt.Errorf("I frame not acknowledged within t2: %v", err)
+	}
+	return nil
+}
+
+func caseT3(c *Conn, t *Target) error {
+	c.W = 1 // 立即确认突发数据, 避免被测端t1超时
+	if err := startDt(c, t); err != nil {
+		return err
+	}
+	if _, err := c.Wait(t.Config.IdleTimeout3+t.Tolerance, isU(cs104.UTestFrActive)); err != nil {
+		return fmt.Errorf("no TESTFR act after t3 idle: %v", err)
+	}
+	if err := c.WaitClosed(t.Tolerance); err != ErrTimeout {
+		return fmt.Errorf("connection closed after TESTFR con: %v", err)
+	}
+	return nil
+}
+
+func caseK(c *Conn, t *Target) error {
+	c.AutoAck = false
+	if err := startDt(c, t); err != nil {
+		return err
+	}
+	k := int(t.Config.SendUnAckLimitK)
+	n := k + 2
+	for i := 0; i < n; i++ {
+		if err := testCommand(c, t); err != nil {
+			return err
+		}
+	}
+
+	var unacked, confirmed int
+	count := func(f *cs104.Frame) {
+		if f.Control.Type == cs104.FrameI {
+			unacked++
+			if f.ASDU.Type == asdu.C_TS_NA_1 && f.ASDU.Coa.Cause == asdu.ActivationCon {
+				confirmed++
+			}
+		}
+	}
+	for {
+		f, err := c.Recv(t.Tolerance)
+		if err == ErrTimeout {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		count(f)
+	}
+	if unacked > k {
+		return fmt.Errorf("%d unacknowledged I frames sent, k=%d", unacked, k)
+	}
+	if err := c.SendS(); err != nil {
+		return err
+	}
+	for confirmed < n {
+		f, err := c.Recv(t.Config.SendUnAckTimeout1)
+		if err != nil {
+			return fmt.Errorf("%d of %d test commands confirmed after acknowledge: %v", confirmed, n, err)
+		}
+		count(f)
+	}
+	return nil
+}
+
+func caseW(c *Conn, t *Target) error {
+	if err := startDt(c, t); err != nil {
+		return err
+	}
+	w := t.Config.RecvUnAckLimitW
+	for i := uint16(0); i < w; i++ {
+		if err := testCommand(c, t); err != nil {
+			return err
+		}
+	}
+	_, err := c.Wait(t.Tolerance, func(f *cs104.Frame) bool {
+		return f.Control.Type != cs104.FrameU && f.Control.RecvSN == w
+	})
+	if err != nil {
+		return fmt.Errorf("%d I frames not acknowledged: %v", w, err)
+	}
+	return nil
+}
+
+func caseWraparound(c *Conn, t *Target) error {
+	if err := startDt(c, t); err != nil {
+		return err
+	}
+	k := t.Config.SendUnAckLimitK
+	n := 32768 + int(k)
+	var sent, confirmed int
+	var acked uint16 // 被测端确认的发送序号
+	for confirmed < n {
+		if sent < n && (c.SendSN()-acked)&32767 < k {
+			if err := testCommand(c, t); err != nil {
+				return err
+			}
+			sent++
+			continue
+		}
+		f, err := c.Recv(t.Config.SendUnAckTimeout1)
+		if err != nil {
+			return fmt.Errorf("after %d sent and %d confirmed: %v", sent, confirmed, err)
+		}
+		if f.Control.Type == cs104.FrameU {
+			continue
+		}
+		if (c.SendSN()-f.Control.RecvSN)&32767 > (c.SendSN()-acked)&32767 {
+			return fmt.Errorf("invalid acknowledge %d, acknowledged %d, next send number %d", f.Control.RecvSN, acked, c.SendSN())
+		}
+		acked = f.Control.RecvSN
+		if f.ASDU != nil && f.ASDU.Type == asdu.C_TS_NA_1 && f.ASDU.Coa.Cause == asdu.ActivationCon {
+			confirmed++
+		}
+	}
+	if acked != c.SendSN() {
+		sendSN := c.SendSN()
+		_, err := c.Wait(t.Config.RecvUnAckTimeout2+t.Tolerance, func(f *cs104.Frame) bool {
+			return f.Control.Type != cs104.FrameU && f.Control.RecvSN == sendSN
+		})
+		if err != nil {
+			return fmt.Errorf("last I frame %d not acknowledged: %v", sendSN, err)
+		}
+	}
+	return c.SendS()
+}
+
+func caseUnknownTypeID(c *Conn, t *Target) error {
+	_, err := expectMirror(c, t, newCommand(t, t.UnknownTypeID, asdu.Activation, t.CommonAddr, 1), asdu.UnknownTypeID)
+	return err
+}
+
+func caseUnknownCOT(c *Conn, t *Target) error {
+	req := newCommand(t, asdu.C_IC_NA_1, asdu.Spontaneous, t.CommonAddr, 0, byte(asdu.QOIStation))
+	_, err := expectMirror(c, t, req, asdu.UnknownCOT)
+	return err
+}
+
+func caseUnknownCA(c *Conn, t *Target) error {
+	if t.UnknownCommonAddr == 0 {
+		return fmt.Errorf("%w: no unknown common address configured", ErrSkip)
+	}
+	req := newCommand(t, asdu.C_IC_NA_1, asdu.Activation, t.UnknownCommonAddr, 0, byte(asdu.QOIStation))
+	_, err := expectMirror(c, t, req, asdu.UnknownCA)
+	return err
+}
+
+func caseUnknownIOA(c *Conn, t *Target) error {
+	req := newCommand(t, asdu.C_IC_NA_1, asdu.Activation, t.CommonAddr, 1, byte(asdu.QOIStation))
+	_, err := expectMirror(c, t, req, asdu.UnknownIOA)
+	return err
+}
+
+func caseInterrogation(c *Conn, t *Target) error {
+	req := newCommand(t, asdu.C_IC_NA_1, asdu.Activation, t.CommonAddr, 0, byte(asdu.QOIStation))
+	con, err := expectMirror(c, t, req, asdu.ActivationCon)
+	if err != nil {
+		return err
+	}
+	if con.Coa.IsNegative {
+		return fmt.Errorf("negative %s", con.Identifier)
+	}
+	deadline := time.Now().Add(t.InterrogationTimeout)
+	for {
+		f, err := c.Recv(time.Until(deadline))
+		if err != nil {
+			return fmt.Errorf("no interrogation termination: %v", err)
+		}
+		if f.ASDU == nil || f.ASDU.CommonAddr != t.CommonAddr {
+			continue
+		}
+		if isReply(req, asdu.ActivationTerm)(f) {
+			return c.SendS()
+		}
+		if f.ASDU.Type == asdu.C_IC_NA_1 {
+			return fmt.Errorf("unexpected %s", f.ASDU.Identifier)
+		}
+	}
+}
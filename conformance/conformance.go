@@ -0,0 +1,195 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+// Package conformance run IEC 60870-5-104 protocol test cases against an outstation
+// reachable over TCP and report pass or fail for each case.
+//
+// The harness acts as the controlling station, every case uses a new connection and
+// drives the frames itself, so the outstation timers, k/w windows and sequence numbers
+// can be checked. The expected t1, t2, t3, k and w are taken from Target.Config and
+// must match the configuration of the outstation.
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+// ErrSkip 用例返回ErrSkip表示目标未配置用例所需的参数
+var ErrSkip = errors.New("conformance: skipped")
+
+// Target 被测端
+type Target struct {
+	// Addr 被测端地址 host:port
+	Addr string
+	// Params asdu参数, nil 使用 asdu.ParamsWide
+	Params *asdu.Params
+	// Config 被测端的超时及k/w配置, 未指定的值使用标准默认值
+	Config cs104.Config
+	// CommonAddr 被测端已配置的公共地址, 0 使用 1
+	CommonAddr asdu.CommonAddr
+	// UnknownCommonAddr 被测端未配置的公共地址, 0 跳过未知公共地址的用例
+	UnknownCommonAddr asdu.CommonAddr
+	// UnknownTypeID 被测端不支持的类型标识, 0 使用 C_BO_NA_1
+	UnknownTypeID asdu.TypeID
+	// Tolerance 超时判定的容差, 0 使用 1s
+	Tolerance time.Duration
+	// InterrogationTimeout 等待总召唤结束的最长时间, 0 使用 30s
+	InterrogationTimeout time.Duration
+}
+
+// valid 检查目标并应用默认值
+func (sf *Target) valid() error {
+	if sf.Addr == "" {
+		return errors.New("conformance: missing target address")
+	}
+	if sf.Params == nil {
+		sf.Params = asdu.ParamsWide
+	}
+	if err := sf.Params.Valid(); err != nil {
+		return err
+	}
+	if err := sf.Config.Valid(); err != nil {
+		return err
+	}
+	if sf.CommonAddr == 0 {
+		sf.CommonAddr = 1
+	}
+	if sf.UnknownTypeID == 0 {
+		sf.UnknownTypeID = asdu.C_BO_NA_1
+	}
+	if sf.Tolerance == 0 {
+		sf.Tolerance = time.Second
+	}
+	if sf.InterrogationTimeout == 0 {
+		sf.InterrogationTimeout = 30 * time.Second
+	}
+	return nil
+}
+
+// Case 一个测试用例, Run 返回nil为通过, ErrSkip为跳过
+type Case struct {
+	Name        string
+	Description string
+	Run         func(c *Conn, t *Target) error
+}
+
+// Status 用例结果
+type Status byte
+
+// 用例结果
+const (
+	Pass Status = iota
+	Fail
+	Skip
+)
+
+func (sf Status) String() string {
+	switch sf {
+	case Pass:
+		return "PASS"
+	case Fail:
+		return "FAIL"
+	}
+	return "SKIP"
+}
+
+// Result 一个用例的结果
+type Result struct {
+	Case     string
+	Status   Status
+	Err      error
+	Duration time.Duration
+}
+
+// Report 测试报告
+type Report struct {
+	Target  string
+	Start   time.Time
+	Results []Result
+}
+
+// Passed 没有失败的用例
+func (sf *Report) Passed() bool {
+	for _, r := range sf.Results {
+		if r.Status == Fail {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteTo 输出文本格式的报告
+func (sf *Report) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	var count [3]int
+	fmt.Fprintf(&b, "IEC 104 conformance report for %s, %s\n", sf.Target, sf.Start.Format(time.RFC3339))
+	for _, r := range sf.Results {
+		count[r.Status]++
+		fmt.Fprintf(&b, "%-4s  %-24s %8s", r.Status, r.Case, r.Duration.Round(time.Millisecond))
+		if r.Err != nil && r.Status == Fail {
+			fmt.Fprintf(&b, "  %v", r.Err)
+		}
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "%d passed, %d failed, %d skipped\n", count[Pass], count[Fail], count[Skip])
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// Run 依次运行用例, 每个用例使用新的连接, cases 为空时运行 Cases()
+func Run(ctx context.Context, target Target, cases ...Case) (*Report, error) {
+	if err := target.valid(); err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		cases = Cases()
+	}
+	report := &Report{Target: target.Addr, Start: time.Now()}
+	for _, cs := range cases {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		report.Results = append(report.Results, runCase(ctx, &target, cs))
+	}
+	return report, nil
+}
+
+// runCase 运行一个用例, ctx取消时关闭连接
+func runCase(ctx context.Context, t *Target, cs Case) Result {
+	r := Result{Case: cs.Name}
+	start := time.Now()
+
+	c, err := Dial(ctx, t)
+	if err == nil {
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = c.conn.Close()
+			case <-done:
+			}
+		}()
+		err = cs.Run(c, t)
+		close(done)
+		_ = c.Close()
+	}
+	switch {
+	case err == nil:
+		r.Status = Pass
+	case errors.Is(err, ErrSkip):
+		r.Status, r.Err = Skip, err
+	default:
+		r.Status, r.Err = Fail, err
+	}
+	r.Duration = time.Since(start)
+	return r
+}
@@ -0,0 +1,149 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+// station answer interrogation with one point, other commands are not supported
+type station struct{}
+
+func (station) InterrogationHandler(c asdu.Connect, a *asdu.ASDU, _ asdu.QualifierOfInterrogation) error {
+	_ = a.SendReplyMirror(c, asdu.ActivationCon)
+	_ = asdu.Single(c, false, asdu.CauseOfTransmission{Cause: asdu.InterrogatedByStation}, a.CommonAddr,
+		asdu.SinglePointInfo{Ioa: 100, Value: true})
+	return a.SendReplyMirror(c, asdu.ActivationTerm)
+}
+func (station) CounterInterrogationHandler(asdu.Connect, *asdu.ASDU, asdu.QualifierCountCall) error {
+	return nil
+}
+func (station) ReadHandler(asdu.Connect, *asdu.ASDU, asdu.InfoObjAddr) error { return nil }
+func (station) ClockSyncHandler(asdu.Connect, *asdu.ASDU, time.Time) error   { return nil }
+func (station) ResetProcessHandler(asdu.Connect, *asdu.ASDU, asdu.QualifierOfResetProcessCmd) error {
+	return nil
+}
+func (station) DelayAcquisitionHandler(asdu.Connect, *asdu.ASDU, uint16) error { return nil }
+func (station) ASDUHandler(asdu.Connect, *asdu.ASDU) error                     { return errors.New("not supported") }
+
+var testConfig = cs104.Config{
+	SendUnAckTimeout1: time.Second,
+	RecvUnAckTimeout2: time.Second,
+	IdleTimeout3:      time.Second,
+}
+
+func startServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	router := cs104.NewRouter()
+	_ = router.Handle(1, station{})
+	srv := cs104.NewServer(router)
+	srv.SetConfig(testConfig)
+	go srv.ListenAndServer(addr)
+	t.Cleanup(func() { _ = srv.Close() })
+
+	for i := 0; i < 50; i++ {
+		if c, err := net.Dial("tcp", addr); err == nil {
+			c.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return addr
+}
+
+func TestRun(t *testing.T) {
+	addr := startServer(t)
+	cases := Cases()
+	if testing.Short() {
+		for i, cs := range cases {
+			if cs.Name == "sequence-wraparound" {
+				cases = append(cases[:i], cases[i+1:]...)
+				break
+			}
+		}
+	}
+	report, err := Run(context.Background(), Target{
+		Addr:              addr,
+		Config:            testConfig,
+		UnknownCommonAddr: 2,
+		Tolerance:         500 * time.Millisecond,
+	}, cases...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if _, err = report.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Passed() || len(report.Results) != len(cases) {
+		t.Errorf("Run() report\n%s", b.String())
+	}
+}
+
+func TestRun_fail(t *testing.T) {
+	addr := startServer(t)
+	tests := []struct {
+		name   string
+		target Target
+		want   Status
+		err    string
+	}{
+		{"k mismatch", Target{Config: cs104.Config{SendUnAckLimitK: 4}}, Fail, "unacknowledged I frames sent, k=4"},
+		{"unknown ca", Target{}, Skip, "no unknown common address"},
+		{"closed", Target{Addr: "127.0.0.1:1"}, Fail, ""},
+	}
+	cases := map[string]string{
+		"k mismatch": "k-window",
+		"unknown ca": "unknown-ca",
+		"closed":     "startdt",
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cs Case
+			for _, c := range Cases() {
+				if c.Name == cases[tt.name] {
+					cs = c
+				}
+			}
+			target := tt.target
+			if target.Addr == "" {
+				target.Addr = addr
+			}
+			if target.Config.SendUnAckTimeout1 == 0 {
+				target.Config.SendUnAckTimeout1 = testConfig.SendUnAckTimeout1
+			}
+			target.Tolerance = 500 * time.Millisecond
+			report, err := Run(context.Background(), target, cs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := report.Results[0]
+			if r.Status != tt.want || (tt.err != "" && !strings.Contains(r.Err.Error(), tt.err)) {
+				t.Errorf("Run() %s %v, want %s %q", r.Status, r.Err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestRun_cancel(t *testing.T) {
+	addr := startServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	report, err := Run(ctx, Target{Addr: addr, Config: cs104.Config{IdleTimeout3: time.Minute}}, Cases()...)
+	if err != context.DeadlineExceeded || len(report.Results) == len(Cases()) {
+		t.Errorf("Run() %d results, error = %v", len(report.Results), err)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package conformance
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+// 测试连接的错误
+var (
+	ErrTimeout = errors.New("conformance: timeout")
+	ErrClosed  = errors.New("conformance: connection closed by peer")
+)
+
+// Conn 测试用的104主站连接, 由用例控制每一帧的收发, 只自动回复测试确认帧.
+// imp asdu.Connect, 发送的ASDU使用I帧
+type Conn struct {
+	conn   net.Conn
+	params *asdu.Params
+	frames chan *cs104.Frame
+	done   chan struct{}
+	wg     sync.WaitGroup
+	err    error // 接收结束的原因, frames关闭后有效
+
+	mu     sync.Mutex
+	sendSN uint16 // 下一个发送序号
+	recvSN uint16 // 下一个期望的接收序号
+	ackSN  uint16 // 已确认的接收序号
+
+	// AutoAck 未确认的接收I帧达到W时自动发送S帧
+	AutoAck bool
+	W       uint16
+}
+
+// Dial 连接到被测端
+func Dial(ctx context.Context, t *Target) (*Conn, error) {
+	d := net.Dialer{Timeout: t.Config.ConnectTimeout0}
+	conn, err := d.DialContext(ctx, "tcp", t.Addr)
+	if err != nil {
+		return nil, err
+	}
+	return newConn(conn, t), nil
+}
+
+func newConn(conn net.Conn, t *Target) *Conn {
+	c := &Conn{
+		conn:    conn,
+		params:  t.Params,
+		frames:  make(chan *cs104.Frame, 1024),
+		done:    make(chan struct{}),
+		AutoAck: true,
+		W:       t.Config.RecvUnAckLimitW,
+	}
+	c.wg.Add(1)
+	go c.recvLoop()
+	return c
+}
+
+// recvLoop 接收并解析APDU, 连接关闭时关闭frames
+func (sf *Conn) recvLoop() {
+	defer sf.wg.Done()
+	sc := bufio.NewScanner(sf.conn)
+	sc.Split(cs104.ScanAPDU)
+	for sc.Scan() {
+		apdu := append([]byte(nil), sc.Bytes()...)
+		f := cs104.NewFrame(sf.params, cs104.DirReceived, time.Now(), apdu)
+		f.Local, f.Remote = sf.conn.LocalAddr(), sf.conn.RemoteAddr()
+		if f.Control.Type == cs104.FrameU && f.Control.Function == cs104.UTestFrActive {
+			_ = sf.SendU(cs104.UTestFrConfirm)
+		}
+		select {
+		case sf.frames <- f:
+		case <-sf.done:
+			sf.err = ErrClosed
+			close(sf.frames)
+			return
+		}
+	}
+	sf.err = sc.Err()
+	if sf.err == nil {
+		sf.err = ErrClosed
+	}
+	close(sf.frames)
+}
+
+// Params imp asdu.Connect
+func (sf *Conn) Params() *asdu.Params { return sf.params }
+
+// UnderlyingConn imp asdu.Connect
+func (sf *Conn) UnderlyingConn() net.Conn { return sf.conn }
+
+// Send imp asdu.Connect, 以I帧发送ASDU, 同时确认所有已接收的I帧
+func (sf *Conn) Send(a *asdu.ASDU) error {
+	data, err := a.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	b := make([]byte, 0, len(data)+6)
+	b = append(b, 0x68, byte(len(data)+4),
+		byte(sf.sendSN<<1), byte(sf.sendSN>>7), byte(sf.recvSN<<1), byte(sf.recvSN>>7))
+	if _, err = sf.conn.Write(append(b, data...)); err != nil {
+		return err
+	}
+	sf.sendSN = (sf.sendSN + 1) & 32767
+	sf.ackSN = sf.recvSN
+	return nil
+}
+
+// SendU 发送U帧, function 见 cs104.UStartDtActive 等
+func (sf *Conn) SendU(function byte) error {
+	_, err := sf.conn.Write([]byte{0x68, 4, function | 0x03, 0, 0, 0})
+	return err
+}
+
+// SendS 发送S帧, 确认所有已接收的I帧
+func (sf *Conn) SendS() error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	return sf.sendS()
+}
+
+func (sf *Conn) sendS() error {
+	_, err := sf.conn.Write([]byte{0x68, 4, 0x01, 0, byte(sf.recvSN << 1), byte(sf.recvSN >> 7)})
+	sf.ackSN = sf.recvSN
+	return err
+}
+
+// SendSN 返回下一个发送序号
+func (sf *Conn) SendSN() uint16 {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	return sf.sendSN
+}
+
+// Unacked 返回已接收未确认的I帧数
+func (sf *Conn) Unacked() uint16 {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	return (sf.recvSN - sf.ackSN) & 32767
+}
+
+// Recv 接收下一帧, 检查I帧的发送序号, 超时返回ErrTimeout, 对端关闭返回ErrClosed
+func (sf *Conn) Recv(timeout time.Duration) (*cs104.Frame, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case f, ok := <-sf.frames:
+		if !ok {
+			return nil, sf.err
+		}
+		if f.Err != nil {
+			return f, fmt.Errorf("malformed %s: %v", f.Control, f.Err)
+		}
+		if f.Control.Type != cs104.FrameI {
+			return f, nil
+		}
+		sf.mu.Lock()
+		defer sf.mu.Unlock()
+		if f.Control.SendSN != sf.recvSN {
+			return f, fmt.Errorf("sequence error: expected send number %d, got %d", sf.recvSN, f.Control.SendSN)
+		}
+		sf.recvSN = (sf.recvSN + 1) & 32767
+		if sf.AutoAck && (sf.recvSN-sf.ackSN)&32767 >= sf.W {
+			if err := sf.sendS(); err != nil {
+				return f, err
+			}
+		}
+		return f, nil
+	case <-timer.C:
+		return nil, ErrTimeout
+	}
+}
+
+// Wait 接收直到match返回true, 超时返回ErrTimeout
+func (sf *Conn) Wait(timeout time.Duration, match func(f *cs104.Frame) bool) (*cs104.Frame, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := sf.Recv(time.Until(deadline))
+		if err != nil {
+			return f, err
+		}
+		if match(f) {
+			return f, nil
+		}
+	}
+}
+
+// WaitClosed 等待对端关闭连接, 超时返回ErrTimeout
+func (sf *Conn) WaitClosed(timeout time.Duration) error {
+	_, err := sf.Wait(timeout, func(*cs104.Frame) bool { return false })
+	if err == ErrClosed {
+		return nil
+	}
+	if err == nil || err == ErrTimeout {
+		return ErrTimeout
+	}
+	// 连接被复位等同于关闭
+	if _, ok := err.(net.Error); ok {
+		return nil
+	}
+	return err
+}
+
+// Close 关闭连接
+func (sf *Conn) Close() error {
+	err := sf.conn.Close()
+	close(sf.done)
+	sf.wg.Wait()
+	return err
+}
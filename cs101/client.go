@@ -3,3 +3,461 @@
 // Public License, license that can be found in the LICENSE file.
 
 package cs101
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/clog"
+)
+
+const (
+	offline uint32 = iota
+	online
+)
+
+// station 被轮询的从动站
+type station struct {
+	linkAddr uint16
+	ca       asdu.CommonAddr
+	fcb      bool        // 下一次FCV=1的报文所用的帧计数位
+	acd      bool        // 从动站有1级用户数据
+	status   uint32      // 链路状态
+	sendASDU chan []byte // for send asdu
+}
+
+// Client is an IEC101 master in unbalanced transmission, it polls one or more stations
+// on the same link by turns.
+type Client struct {
+	option   ClientOption
+	rw       io.ReadWriter
+	handler  ClientHandlerInterface
+	stations []*station
+
+	rcvFrame chan Ft12 // for readLoop frame
+
+	clog.Clog
+
+	rwMux   sync.Mutex
+	started bool
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	onLinkStatus func(c *Client, ca asdu.CommonAddr, isOnline bool)
+}
+
+// NewClient returns an IEC101 master which communicate with stations on rw
+func NewClient(rw io.ReadWriter, handler ClientHandlerInterface, o *ClientOption) *Client {
+	sf := &Client{
+		option:       *o,
+		rw:           rw,
+		handler:      handler,
+		rcvFrame:     make(chan Ft12, 16),
+		Clog:         clog.NewLogger("cs101 client => "),
+		onLinkStatus: func(*Client, asdu.CommonAddr, bool) {},
+	}
+	for _, v := range o.stations {
+		sf.stations = append(sf.stations, &station{
+			linkAddr: v.linkAddr,
+			ca:       v.ca,
+			sendASDU: make(chan []byte, 64),
+		})
+	}
+	return sf
+}
+
+// SetLinkStatusHandler set the handler called when the link of a station changes to online or offline
+func (sf *Client) SetLinkStatusHandler(f func(c *Client, ca asdu.CommonAddr, isOnline bool)) *Client {
+	if f != nil {
+		sf.onLinkStatus = f
+	}
+	return sf
+}
+
+// Start start polling the stations background and return quickly
+func (sf *Client) Start() error {
+	if len(sf.stations) == 0 {
+		return errors.New("empty station")
+	}
+
+	sf.rwMux.Lock()
+	defer sf.rwMux.Unlock()
+	if sf.started {
+		return errors.New("client already started")
+	}
+	sf.started = true
+	sf.ctx, sf.cancel = context.WithCancel(context.Background())
+	sf.wg.Add(2)
+	go sf.readLoop()
+	go sf.pollLoop()
+	return nil
+}
+
+// Close stop polling, it will close the underlying io.ReadWriter if it is an io.Closer
+func (sf *Client) Close() error {
+	var err error
+
+	sf.rwMux.Lock()
+	if sf.started {
+		sf.cancel()
+		if c, ok := sf.rw.(io.Closer); ok {
+			err = c.Close()
+		}
+	}
+	sf.rwMux.Unlock()
+	sf.wg.Wait()
+	return err
+}
+
+// readLoop feeds sf.rcvFrame
+func (sf *Client) readLoop() {
+	sf.Debug("readLoop started")
+	defer func() {
+		sf.cancel()
+		sf.wg.Done()
+		sf.Debug("readLoop stopped")
+	}()
+
+	rd := bufio.NewReader(sf.rw)
+	for {
+		f, err := DecodeFt12(rd, sf.option.linkAddrSize)
+		if err != nil {
+			if err == ErrFrameChecksum || err == ErrFrameEnd || err == ErrFrameLength {
+				sf.Warn("receive invalid frame, %v", err)
+				continue
+			}
+			sf.Error("receive failed, %v", err)
+			return
+		}
+		sf.Debug("RX %v", f)
+		select {
+		case <-sf.ctx.Done():
+			return
+		case sf.rcvFrame <- f:
+		}
+	}
+}
+
+// pollLoop is the big fat state machine.
+func (sf *Client) pollLoop() {
+	sf.Debug("pollLoop started")
+	defer func() {
+		sf.cancel()
+		for _, s := range sf.stations {
+			sf.setStationStatus(s, offline)
+		}
+		sf.wg.Done()
+		sf.Debug("pollLoop stopped")
+	}()
+
+	timer := time.NewTimer(sf.option.pollInterval)
+	defer timer.Stop()
+	for {
+		busy := false
+		for _, s := range sf.stations {
+			select {
+			case <-sf.ctx.Done():
+				return
+			default:
+			}
+
+			if atomic.LoadUint32(&s.status) == offline {
+				if err := sf.resetLink(s); err != nil {
+					sf.Debug("station %d reset link failed, %v", s.ca, err)
+					continue
+				}
+				sf.setStationStatus(s, online)
+			}
+			if err := sf.serviceStation(s); err != nil {
+				sf.Error("station %d service failed, %v", s.ca, err)
+				sf.setStationStatus(s, offline)
+				continue
+			}
+			if s.acd || len(s.sendASDU) > 0 {
+				busy = true
+			}
+		}
+		if busy {
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(sf.option.pollInterval)
+		select {
+		case <-sf.ctx.Done():
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// resetLink 请求链路状态并复位远方链路
+func (sf *Client) resetLink(s *station) error {
+	resp, err := sf.request(s, Ft12{Ctrl: RPM | FccLinkStatus, LinkAddr: s.linkAddr})
+	if err != nil {
+		return err
+	}
+	if resp.IsSingleChar || resp.Function() != FcsStatus {
+		return errors.New("unexpected link status response")
+	}
+	resp, err = sf.request(s, Ft12{Ctrl: RPM | FccResetRemoteLink, LinkAddr: s.linkAddr})
+	if err != nil {
+		return err
+	}
+	if !resp.IsSingleChar && resp.Function() != FcsConfirmed {
+		return errors.New("reset remote link not confirmed")
+	}
+	s.fcb = false
+	s.acd = resp.HasACD()
+	return nil
+}
+
+// serviceStation 发送一个待发送的ASDU, 然后请求1级或2级用户数据
+func (sf *Client) serviceStation(s *station) error {
+	select {
+	case data := <-s.sendASDU:
+		resp, err := sf.request(s, Ft12{Ctrl: sf.fcvCtrl(s, FccUserDataWithConfirmed), LinkAddr: s.linkAddr, ASDU: data})
+		if err != nil {
+			return err
+		}
+		if !resp.IsSingleChar && resp.Function() == FcsNConfirmed {
+			sf.Warn("station %d link busy, user data discarded", s.ca)
+		}
+		s.acd = resp.HasACD()
+	default:
+	}
+
+	fc := byte(FccUnbalanceLevel2UserData)
+	if s.acd {
+		fc = FccUnbalanceLevel1UserData
+	}
+	resp, err := sf.request(s, Ft12{Ctrl: sf.fcvCtrl(s, fc), LinkAddr: s.linkAddr})
+	if err != nil {
+		return err
+	}
+	s.acd = resp.HasACD()
+	if !resp.IsSingleChar && resp.Function() == FcsUnbalanceResponse && len(resp.ASDU) > 0 {
+		sf.handleASDU(resp.ASDU)
+	}
+	return nil
+}
+
+// fcvCtrl 帧计数有效的控制域, 翻转帧计数位
+func (sf *Client) fcvCtrl(s *station, fc byte) byte {
+	s.fcb = !s.fcb
+	ctrl := RPM | FCV | fc
+	if s.fcb {
+		ctrl |= FCB
+	}
+	return ctrl
+}
+
+// request 发送一个帧并等待从动站响应,超时重发
+func (sf *Client) request(s *station, f Ft12) (Ft12, error) {
+	frame, err := f.Encode(sf.option.linkAddrSize)
+	if err != nil {
+		return Ft12{}, err
+	}
+
+	// discard stale frame
+loop:
+	for {
+		select {
+		case <-sf.rcvFrame:
+		default:
+			break loop
+		}
+	}
+
+	for i := 0; i <= sf.option.retries; i++ {
+		sf.Debug("TX %v", f)
+		if _, err = sf.rw.Write(frame); err != nil {
+			return Ft12{}, err
+		}
+		if resp, ok := sf.waitResponse(s); ok {
+			return resp, nil
+		}
+		if sf.ctx.Err() != nil {
+			return Ft12{}, ErrUseClosedConnection
+		}
+	}
+	return Ft12{}, ErrResponseTimeout
+}
+
+// waitResponse 等待从动站的响应帧, 超时或关闭时返回false
+func (sf *Client) waitResponse(s *station) (Ft12, bool) {
+	timer := time.NewTimer(sf.option.respTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-sf.ctx.Done():
+			return Ft12{}, false
+		case <-timer.C:
+			return Ft12{}, false
+		case resp := <-sf.rcvFrame:
+			if resp.IsSingleChar || (!resp.IsPrimary() && resp.LinkAddr == s.linkAddr) {
+				return resp, true
+			}
+		}
+	}
+}
+
+func (sf *Client) setStationStatus(s *station, status uint32) {
+	if atomic.SwapUint32(&s.status, status) != status {
+		if status == online {
+			sf.Debug("station %d online", s.ca)
+		} else {
+			sf.Warn("station %d offline", s.ca)
+		}
+		sf.onLinkStatus(sf, s.ca, status == online)
+	}
+}
+
+func (sf *Client) handleASDU(rawAsdu []byte) {
+	asduPack := asdu.NewEmptyASDU(&sf.option.params)
+	if err := asduPack.UnmarshalBinary(rawAsdu); err != nil {
+		sf.Warn("asdu UnmarshalBinary failed,%+v", err)
+		return
+	}
+	if err := sf.clientHandler(asduPack); err != nil {
+		sf.Warn("Falied handling user data, error: %v", err)
+	}
+}
+
+// clientHandler hand response handler
+func (sf *Client) clientHandler(asduPack *asdu.ASDU) error {
+	defer func() {
+		if err := recover(); err != nil {
+			sf.Critical("client handler %+v", err)
+		}
+	}()
+
+	sf.Debug("ASDU %+v", asduPack)
+
+	switch asduPack.Identifier.Type {
+	case asdu.C_IC_NA_1: // InterrogationCmd
+		return sf.handler.InterrogationHandler(sf, asduPack)
+
+	case asdu.C_CI_NA_1: // CounterInterrogationCmd
+		return sf.handler.CounterInterrogationHandler(sf, asduPack)
+
+	case asdu.C_RD_NA_1: // ReadCmd
+		return sf.handler.ReadHandler(sf, asduPack)
+
+	case asdu.C_CS_NA_1: // ClockSynchronizationCmd
+		return sf.handler.ClockSyncHandler(sf, asduPack)
+
+	case asdu.C_TS_NA_1: // TestCommand
+		return sf.handler.TestCommandHandler(sf, asduPack)
+
+	case asdu.C_RP_NA_1: // ResetProcessCmd
+		return sf.handler.ResetProcessHandler(sf, asduPack)
+
+	case asdu.C_CD_NA_1: // DelayAcquireCommand
+		return sf.handler.DelayAcquisitionHandler(sf, asduPack)
+	}
+
+	return sf.handler.ASDUHandler(sf, asduPack)
+}
+
+// IsOnline get the link state of the station with common address ca
+func (sf *Client) IsOnline(ca asdu.CommonAddr) bool {
+	for _, s := range sf.stations {
+		if s.ca == ca {
+			return atomic.LoadUint32(&s.status) == online
+		}
+	}
+	return false
+}
+
+// Params returns params of client
+func (sf *Client) Params() *asdu.Params {
+	return &sf.option.params
+}
+
+// Send send asdu to the station with the same common address,
+// the global common address will send to every online station.
+func (sf *Client) Send(a *asdu.ASDU) error {
+	return sf.send(nil, a)
+}
+
+// SendContext like Send, but wait for the send buffer if it is full
+// until the client closed or ctx is done. imp interface asdu.ContextConnect
+func (sf *Client) SendContext(ctx context.Context, a *asdu.ASDU) error {
+	return sf.send(ctx, a)
+}
+
+// send ctx为nil时发送缓冲满立即返回ErrBufferFulled, 否则等待
+func (sf *Client) send(ctx context.Context, a *asdu.ASDU) error {
+	data, err := a.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	if a.CommonAddr == asdu.GlobalCommonAddr {
+		for _, s := range sf.stations {
+			if atomic.LoadUint32(&s.status) == online {
+				if err = sf.push(ctx, s.sendASDU, append([]byte(nil), data...)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, s := range sf.stations {
+		if s.ca != a.CommonAddr {
+			continue
+		}
+		if atomic.LoadUint32(&s.status) != online {
+			return ErrNotActive
+		}
+		// data引用a的缓冲, 复制后放入发送缓冲
+		return sf.push(ctx, s.sendASDU, append([]byte(nil), data...))
+	}
+	return ErrUnknownStation
+}
+
+// push 放入站的发送缓冲, ctx为nil时不等待
+func (sf *Client) push(ctx context.Context, ch chan []byte, data []byte) error {
+	if ctx == nil {
+		select {
+		case ch <- data:
+			return nil
+		default:
+			return ErrBufferFulled
+		}
+	}
+	sf.rwMux.Lock()
+	done := sf.ctx.Done()
+	sf.rwMux.Unlock()
+	select {
+	case ch <- data:
+		return nil
+	case <-done:
+		return ErrUseClosedConnection
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// UnderlyingConn returns underlying conn of client if it is a net.Conn
+func (sf *Client) UnderlyingConn() net.Conn {
+	if c, ok := sf.rw.(net.Conn); ok {
+		return c
+	}
+	return nil
+}
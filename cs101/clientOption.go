@@ -0,0 +1,108 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs101
+
+import (
+	"errors"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// default value defined
+const (
+	DefaultResponseTimeout = 1 * time.Second
+	DefaultRetries         = 3
+	DefaultPollInterval    = 100 * time.Millisecond
+)
+
+// stationOption 从动站配置
+type stationOption struct {
+	linkAddr uint16
+	ca       asdu.CommonAddr
+}
+
+// ClientOption 客户端(非平衡方式启动站)配置
+type ClientOption struct {
+	params       asdu.Params
+	linkAddrSize int             // 链路地址字节数
+	respTimeout  time.Duration   // 等待从动站响应的超时时间
+	retries      int             // 超时后的重发次数
+	pollInterval time.Duration   // 所有从动站都无数据时的轮询间隔
+	stations     []stationOption // 轮询的从动站
+}
+
+// NewOption with default config and default asdu.ParamsWide params
+func NewOption() *ClientOption {
+	return &ClientOption{
+		*asdu.ParamsWide,
+		1,
+		DefaultResponseTimeout,
+		DefaultRetries,
+		DefaultPollInterval,
+		nil,
+	}
+}
+
+// SetParams set asdu params if params is valid it will use asdu.ParamsWide
+func (sf *ClientOption) SetParams(p *asdu.Params) *ClientOption {
+	if err := p.Valid(); err != nil {
+		sf.params = *asdu.ParamsWide
+	} else {
+		sf.params = *p
+	}
+	return sf
+}
+
+// SetLinkAddrSize set link address size, if size not in [0, 2] it will use 1
+func (sf *ClientOption) SetLinkAddrSize(size int) *ClientOption {
+	if size < 0 || size > LinkAddrSizeMax {
+		sf.linkAddrSize = 1
+	} else {
+		sf.linkAddrSize = size
+	}
+	return sf
+}
+
+// SetResponseTimeout set the timeout waiting for station response
+func (sf *ClientOption) SetResponseTimeout(t time.Duration) *ClientOption {
+	if t > 0 {
+		sf.respTimeout = t
+	}
+	return sf
+}
+
+// SetRetries set the retransmission times after response timeout
+func (sf *ClientOption) SetRetries(n int) *ClientOption {
+	if n >= 0 {
+		sf.retries = n
+	}
+	return sf
+}
+
+// SetPollInterval set the poll interval when no station has data
+func (sf *ClientOption) SetPollInterval(t time.Duration) *ClientOption {
+	if t > 0 {
+		sf.pollInterval = t
+	}
+	return sf
+}
+
+// AddStation adds a station to poll with its link address and common address.
+func (sf *ClientOption) AddStation(linkAddr uint16, ca asdu.CommonAddr) error {
+	if ca == asdu.InvalidCommonAddr || ca == asdu.GlobalCommonAddr {
+		return errors.New("invalid station common address")
+	}
+	if sf.linkAddrSize < LinkAddrSizeMax && linkAddr>>(8*uint(sf.linkAddrSize)) != 0 {
+		return errors.New("link address exceeds link address size")
+	}
+	for _, v := range sf.stations {
+		if v.ca == ca {
+			return errors.New("duplicate station common address")
+		}
+	}
+	sf.stations = append(sf.stations, stationOption{linkAddr, ca})
+	return nil
+}
@@ -0,0 +1,50 @@
+package cs101
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+func TestClient_Send(t *testing.T) {
+	params := &asdu.Params{CauseSize: 1, CommonAddrSize: 1, InfoObjAddrSize: 2, InfoObjTimeZone: time.UTC}
+	o := NewOption().SetParams(params)
+	if err := o.AddStation(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		ca   asdu.CommonAddr
+	}{
+		{"unicast", 1},
+		{"global", asdu.GlobalCommonAddr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(nil, nil, o)
+			c.stations[0].status = online
+
+			a := asdu.NewASDU(params, asdu.Identifier{
+				Type:       asdu.C_SC_NA_1,
+				Variable:   asdu.VariableStruct{Number: 1},
+				Coa:        asdu.CauseOfTransmission{Cause: asdu.Activation},
+				CommonAddr: tt.ca,
+			})
+			_ = a.AppendInfoObjAddr(1)
+			a.AppendBytes(0x01)
+			want, _ := a.MarshalBinary()
+			want = append([]byte(nil), want...)
+			if err := c.Send(a); err != nil {
+				t.Fatal(err)
+			}
+			// 调用者发送后重用asdu不影响已入队的报文
+			a.Coa.Cause = asdu.Deactivation
+			_, _ = a.MarshalBinary()
+			if got := <-c.stations[0].sendASDU; !bytes.Equal(got, want) {
+				t.Errorf("queued [% x], want [% x]", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs101
+
+import (
+	"errors"
+)
+
+// error defined
+var (
+	ErrUseClosedConnection = errors.New("use of closed connection")
+	ErrBufferFulled        = errors.New("buffer is full")
+	ErrNotActive           = errors.New("station link is not active")
+	ErrUnknownStation      = errors.New("unknown station common address")
+	ErrResponseTimeout     = errors.New("station response timeout")
+)
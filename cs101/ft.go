@@ -4,6 +4,12 @@
 
 package cs101
 
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
 // 采用FT1.2帧格式
 const (
 	startVarFrame byte = 0x68 // 长度可变帧启动字符
@@ -25,8 +31,10 @@ const (
 	// PRM = 1, 由启动站向从动站传输报文
 	RPM     = 1 << 6
 	RES_DIR = 1 << 7 // 非平衡保留,平衡为方向
+)
 
-	// 由启动站向从动站传输的报文中控制域的功能码(PRM = 1)
+// 由启动站向从动站传输的报文中控制域的功能码(PRM = 1)
+const (
 	FccResetRemoteLink                 = iota // 复位远方链路
 	FccResetUserProcess                       // 复位用户进程
 	FccBalanceTestLink                        // 链路测试功能
@@ -41,8 +49,10 @@ const (
 	FccUnbalanceLevel2UserData                // 请求 2 级用户数据
 	// 12-13: 备用
 	// 14-15: 制造厂和用户协商定义
+)
 
-	// 从动站向启动站传输的报文中控制域的功能码(PRM = 0)
+// 从动站向启动站传输的报文中控制域的功能码(PRM = 0)
+const (
 	FcsConfirmed                 = iota // 认可: 肯定认可
 	FcsNConfirmed                       // 否定认可: 未收到报文,链路忙
 	_                                   // 保留
@@ -61,12 +71,147 @@ const (
 	// 15: 链路服务未完成
 )
 
-// Ft12 ...
+// 单字符确认
+const singleCharAck byte = 0xe5
+
+// LinkAddrSizeMax 链路地址最大字节数, 链路地址字节数范围[0, 2]
+const LinkAddrSizeMax = 2
+
+// error defined
+var (
+	ErrFrameChecksum = errors.New("cs101: frame checksum mismatch")
+	ErrFrameEnd      = errors.New("cs101: frame end character mismatch")
+	ErrFrameLength   = errors.New("cs101: frame length mismatch")
+	ErrLinkAddrSize  = errors.New("cs101: link address size not in [0, 2]")
+)
+
+// Ft12 FT1.2帧, 包括固定长度帧,可变长度帧和单字符确认
+// 固定长度帧: | 0x10 | C | A | CS | 0x16 |
+// 可变长度帧: | 0x68 | L | L | 0x68 | C | A | ASDU | CS | 0x16 |
+// 单字符确认: | 0xe5 |
+// See IEC 60870-5-1 and IEC 60870-5-2.
 type Ft12 struct {
-	start        byte
-	apduFiledLen byte
-	ctrl         byte
-	address      uint16
-	checksum     byte
-	end          byte
+	// Ctrl 控制域
+	Ctrl byte
+	// LinkAddr 链路地址, 宽度由链路地址字节数决定
+	LinkAddr uint16
+	// ASDU 不为空时为可变长度帧
+	ASDU []byte
+	// IsSingleChar 是否是单字符确认, 此时其它字段无效
+	IsSingleChar bool
+}
+
+// Function 控制域的功能码
+func (sf Ft12) Function() byte { return sf.Ctrl & 0x0f }
+
+// IsPrimary 是否是启动站发出的报文
+func (sf Ft12) IsPrimary() bool { return sf.Ctrl&RPM == RPM }
+
+// HasACD 从动站是否有1级用户数据要求访问
+func (sf Ft12) HasACD() bool { return !sf.IsPrimary() && sf.Ctrl&ACD_RES == ACD_RES }
+
+// String 返回帧的描述
+func (sf Ft12) String() string {
+	if sf.IsSingleChar {
+		return "FT1.2[E5]"
+	}
+	return fmt.Sprintf("FT1.2[ctrl: 0x%02x, addr: %d, asdu: %d]", sf.Ctrl, sf.LinkAddr, len(sf.ASDU))
+}
+
+// Encode 编码帧, linkAddrSize 链路地址字节数
+func (sf Ft12) Encode(linkAddrSize int) ([]byte, error) {
+	if linkAddrSize < 0 || linkAddrSize > LinkAddrSizeMax {
+		return nil, ErrLinkAddrSize
+	}
+	if sf.IsSingleChar {
+		return []byte{singleCharAck}, nil
+	}
+	var b []byte
+	if len(sf.ASDU) == 0 {
+		b = make([]byte, 0, 4+linkAddrSize)
+		b = append(b, startFixFrame)
+	} else {
+		l := 1 + linkAddrSize + len(sf.ASDU)
+		if l > 255 {
+			return nil, ErrFrameLength
+		}
+		b = make([]byte, 0, 6+l)
+		b = append(b, startVarFrame, byte(l), byte(l), startVarFrame)
+	}
+	start := len(b)
+	b = append(b, sf.Ctrl)
+	for i := 0; i < linkAddrSize; i++ {
+		b = append(b, byte(sf.LinkAddr>>(8*i)))
+	}
+	b = append(b, sf.ASDU...)
+	return append(b, checksum(b[start:]), endFrame), nil
+}
+
+// DecodeFt12 从r读取一个帧, 丢弃帧起始前的无效字节,linkAddrSize 链路地址字节数
+func DecodeFt12(r io.ByteReader, linkAddrSize int) (Ft12, error) {
+	if linkAddrSize < 0 || linkAddrSize > LinkAddrSizeMax {
+		return Ft12{}, ErrLinkAddrSize
+	}
+
+	var head byte
+	var err error
+	for {
+		if head, err = r.ReadByte(); err != nil {
+			return Ft12{}, err
+		}
+		if head == singleCharAck || head == startFixFrame || head == startVarFrame {
+			break
+		}
+	}
+
+	var length int
+	switch head {
+	case singleCharAck:
+		return Ft12{IsSingleChar: true}, nil
+	case startFixFrame:
+		length = 1 + linkAddrSize
+	default: // startVarFrame
+		var hdr [3]byte
+		for i := range hdr {
+			if hdr[i], err = r.ReadByte(); err != nil {
+				return Ft12{}, err
+			}
+		}
+		if hdr[0] != hdr[1] || hdr[2] != startVarFrame || int(hdr[0]) <= linkAddrSize {
+			return Ft12{}, ErrFrameLength
+		}
+		length = int(hdr[0])
+	}
+
+	// 用户数据 + 校验和 + 结束字符
+	data := make([]byte, length+2)
+	for i := range data {
+		if data[i], err = r.ReadByte(); err != nil {
+			return Ft12{}, err
+		}
+	}
+	if data[length+1] != endFrame {
+		return Ft12{}, ErrFrameEnd
+	}
+	if checksum(data[:length]) != data[length] {
+		return Ft12{}, ErrFrameChecksum
+	}
+
+	f := Ft12{Ctrl: data[0]}
+	for i := 0; i < linkAddrSize; i++ {
+		f.LinkAddr |= uint16(data[1+i]) << (8 * i)
+	}
+	if head == startVarFrame {
+		f.ASDU = data[1+linkAddrSize : length]
+	}
+	return f, nil
+}
+
+// checksum 校验和, 所有用户数据字节的算术和(不考虑溢出)
+func checksum(b []byte) byte {
+	var sum byte
+	for _, v := range b {
+		sum += v
+	}
+	return sum
 }
@@ -0,0 +1,124 @@
+package cs101
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFt12_Encode(t *testing.T) {
+	type args struct {
+		linkAddrSize int
+	}
+	tests := []struct {
+		name    string
+		this    Ft12
+		args    args
+		want    []byte
+		wantErr bool
+	}{
+		{
+			"invalid link address size",
+			Ft12{},
+			args{3},
+			nil,
+			true,
+		},
+		{
+			"single char",
+			Ft12{IsSingleChar: true},
+			args{1},
+			[]byte{0xe5},
+			false,
+		},
+		{
+			"fixed frame",
+			Ft12{Ctrl: RPM | FccLinkStatus, LinkAddr: 0x01},
+			args{1},
+			[]byte{startFixFrame, 0x49, 0x01, 0x4a, endFrame},
+			false,
+		},
+		{
+			"variable frame",
+			Ft12{Ctrl: RPM | FCV | FccUserDataWithConfirmed, LinkAddr: 0x0201, ASDU: []byte{0x64, 0x01}},
+			args{2},
+			[]byte{startVarFrame, 0x05, 0x05, startVarFrame, 0x53, 0x01, 0x02, 0x64, 0x01, 0xbb, endFrame},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.this.Encode(tt.args.linkAddrSize)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Ft12.Encode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Ft12.Encode() = % x, want % x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeFt12(t *testing.T) {
+	type args struct {
+		b            []byte
+		linkAddrSize int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    Ft12
+		wantErr error
+	}{
+		{
+			"single char after garbage",
+			args{[]byte{0x00, 0x11, 0xe5}, 1},
+			Ft12{IsSingleChar: true},
+			nil,
+		},
+		{
+			"fixed frame",
+			args{[]byte{startFixFrame, 0x0b, 0x01, 0x0c, endFrame}, 1},
+			Ft12{Ctrl: FcsStatus, LinkAddr: 0x01},
+			nil,
+		},
+		{
+			"variable frame",
+			args{[]byte{startVarFrame, 0x05, 0x05, startVarFrame, 0x53, 0x01, 0x02, 0x64, 0x01, 0xbb, endFrame}, 2},
+			Ft12{Ctrl: RPM | FCV | FccUserDataWithConfirmed, LinkAddr: 0x0201, ASDU: []byte{0x64, 0x01}},
+			nil,
+		},
+		{
+			"checksum mismatch",
+			args{[]byte{startFixFrame, 0x0b, 0x01, 0x0d, endFrame}, 1},
+			Ft12{},
+			ErrFrameChecksum,
+		},
+		{
+			"end mismatch",
+			args{[]byte{startFixFrame, 0x0b, 0x01, 0x0c, 0x00}, 1},
+			Ft12{},
+			ErrFrameEnd,
+		},
+		{
+			"length mismatch",
+			args{[]byte{startVarFrame, 0x05, 0x06, startVarFrame}, 1},
+			Ft12{},
+			ErrFrameLength,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeFt12(bufio.NewReader(bytes.NewReader(tt.args.b)), tt.args.linkAddrSize)
+			if err != tt.wantErr {
+				t.Errorf("DecodeFt12() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeFt12() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs101
+
+import (
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// ClientHandlerInterface  is the interface of client handler
+type ClientHandlerInterface interface {
+	InterrogationHandler(asdu.Connect, *asdu.ASDU) error
+	CounterInterrogationHandler(asdu.Connect, *asdu.ASDU) error
+	ReadHandler(asdu.Connect, *asdu.ASDU) error
+	TestCommandHandler(asdu.Connect, *asdu.ASDU) error
+	ClockSyncHandler(asdu.Connect, *asdu.ASDU) error
+	ResetProcessHandler(asdu.Connect, *asdu.ASDU) error
+	DelayAcquisitionHandler(asdu.Connect, *asdu.ASDU) error
+	ASDUHandler(asdu.Connect, *asdu.ASDU) error
+}
@@ -84,17 +84,10 @@ func newIFrame(sendSN, RcvSN uint16, asdus []byte) ([]byte, error) {
 		return nil, fmt.Errorf("ASDU filed large than max %d", asdu.ASDUSizeMax)
 	}
 
-	b := make([]byte, len(asdus)+6)
-
-	b[0] = startFrame
-	b[1] = byte(len(asdus) + 4)
-	b[2] = byte(sendSN << 1)
-	b[3] = byte(sendSN >> 7)
-	b[4] = byte(RcvSN << 1)
-	b[5] = byte(RcvSN >> 7)
-	copy(b[6:], asdus)
-
-	return b, nil
+	b := new(apduBuf)
+	b.n = APCICtlFiledSize + 2 + copy(b.data[APCICtlFiledSize+2:], asdus)
+	b.setIFrame(sendSN, RcvSN)
+	return b.bytes(), nil
 }
 
 // newSFrame 创建S帧,返回apdu
@@ -114,8 +107,11 @@ type APCI struct {
 	ctr1, ctr2, ctr3, ctr4 byte
 }
 
-// return frame type , APCI, remain data
+// return frame type , APCI, remain data, apdu shorter than the APCI returns nil
 func parse(apdu []byte) (interface{}, []byte) {
+	if len(apdu) < APCICtlFiledSize+2 {
+		return nil, nil
+	}
 	apci := APCI{apdu[0], apdu[1], apdu[2], apdu[3], apdu[4], apdu[5]}
 	if apci.ctr1&0x01 == 0 {
 		return iAPCI{
@@ -161,6 +161,12 @@ func Test_parse(t *testing.T) {
 			uAPCI{uStartDtActive},
 			[]byte{},
 		},
+		{
+			"short",
+			args{[]byte{startFrame, 0x04, 0x07}},
+			nil,
+			nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,97 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs104
+
+import (
+	"sync"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// apduBuf 池化的APDU缓冲, 收发的APDU在各个goroutine间传递该缓冲,
+// 写出或解码完成后归还, 避免每帧分配
+type apduBuf struct {
+	data [APDUSizeMax]byte
+	n    int
+	ack  *Delivery // SendWithAck 的发送结果, 随I帧进入pending
+}
+
+var bufPool = sync.Pool{New: func() interface{} { return new(apduBuf) }}
+
+// getBuf 从池中取一个空缓冲
+func getBuf() *apduBuf {
+	b := bufPool.Get().(*apduBuf)
+	b.n = 0
+	b.ack = nil
+	return b
+}
+
+// putBuf 归还缓冲, 归还后不可再使用
+func putBuf(b *apduBuf) {
+	bufPool.Put(b)
+}
+
+// bytes 返回缓冲中的APDU
+func (sf *apduBuf) bytes() []byte {
+	return sf.data[:sf.n]
+}
+
+// asdu 返回I帧的ASDU
+func (sf *apduBuf) asdu() []byte {
+	return sf.data[APCICtlFiledSize+2 : sf.n]
+}
+
+// typeCause 返回I帧ASDU的类型标识和传送原因
+func (sf *apduBuf) typeCause() (asdu.TypeID, asdu.Cause) {
+	a := sf.asdu()
+	return asdu.TypeID(a[0]), asdu.Cause(a[2] & 0x3f)
+}
+
+// setIFrame 以缓冲中的ASDU写入I帧的APCI
+func (sf *apduBuf) setIFrame(sendSN, rcvSN uint16) {
+	sf.data[0] = startFrame
+	sf.data[1] = byte(sf.n - 2)
+	sf.data[2] = byte(sendSN << 1)
+	sf.data[3] = byte(sendSN >> 7)
+	sf.data[4] = byte(rcvSN << 1)
+	sf.data[5] = byte(rcvSN >> 7)
+}
+
+// newBuf 以apdu创建缓冲, 用于S帧和U帧
+func newBuf(apdu []byte) *apduBuf {
+	b := getBuf()
+	b.n = copy(b.data[:], apdu)
+	return b
+}
+
+// drainBuf 清空缓冲通道并归还缓冲, 未发送的SendWithAck失败
+func drainBuf(ch chan *apduBuf) {
+	for {
+		select {
+		case b := <-ch:
+			if b.ack != nil {
+				b.ack.resolve(ErrConnectionLost)
+			}
+			putBuf(b)
+		default:
+			return
+		}
+	}
+}
+
+// encodeASDU 将asdu编码到缓冲的ASDU位置, APCI在发送时写入
+func encodeASDU(a *asdu.ASDU) (*apduBuf, error) {
+	b := getBuf()
+	data, err := a.AppendBinary(b.data[APCICtlFiledSize+2 : APCICtlFiledSize+2])
+	if err == nil && len(data) > asdu.ASDUSizeMax {
+		err = asdu.ErrLengthOutOfRange
+	}
+	if err != nil {
+		putBuf(b)
+		return nil, err
+	}
+	b.n = APCICtlFiledSize + 2 + len(data)
+	return b, nil
+}
@@ -8,7 +8,6 @@ import (
 	"context"
 	"errors"
 	"io"
-	"math/rand"
 	"net"
 	"strings"
 	"sync"
@@ -31,10 +30,10 @@ type Client struct {
 	handler ClientHandlerInterface
 
 	// channel
-	rcvASDU  chan []byte // for received asdu
-	sendASDU chan []byte // for send asdu
-	rcvRaw   chan []byte // for recvLoop raw cs104 frame
-	sendRaw  chan []byte // for sendLoop raw cs104 frame
+	rcvASDU  chan *apduBuf // for received asdu
+	sendASDU *sendQueue    // for send asdu
+	rcvRaw   chan *apduBuf // for recvLoop raw cs104 frame
+	sendRaw  chan *apduBuf // for sendLoop raw cs104 frame
 
 	// I帧的发送与接收序号
 	seqNoSend uint16 // sequence number of next outbound I-frame
@@ -52,16 +51,35 @@ type Client struct {
 	status   uint32
 	rwMux    sync.RWMutex
 	isActive uint32
+	// SendStopDt 的请求, 由run先确认已接收的I帧再发送STOPDT激活
+	stopDtRequest uint32
 
 	// 其他
 	clog.Clog
+	metrics  Metrics
+	observer Observer
 
 	wg          sync.WaitGroup
 	ctx         context.Context
 	cancel      context.CancelFunc
 	closeCancel context.CancelFunc
 
+	// 连接状态事件
+	lostErr      error // recvLoop/sendLoop 的错误
+	stateHandler func(c *Client, e StateEvent)
+	stateMux     sync.Mutex
+	stateWaiters []chan StateEvent
+
+	// 启动流程及周期任务
+	jobs          []Job
+	cmdBusy       chan struct{}   // 召唤等命令不重叠执行, 有值时有命令在执行
+	cmdReply      chan *asdu.ASDU // 命令的应答
+	endOfInit     chan struct{}   // 收到初始化结束
+	startupActive chan struct{}   // STARTDT确认
+
+	firstASDU        func(a *asdu.ASDU) // 每个连接收到的第一个ASDU, 用于Listener识别子站
 	onConnect        func(c *Client)
+	onActive         func(c *Client)
 	onConnectionLost func(c *Client)
 }
 
@@ -70,16 +88,46 @@ func NewClient(handler ClientHandlerInterface, o *ClientOption) *Client {
 	return &Client{
 		option:           *o,
 		handler:          handler,
-		rcvASDU:          make(chan []byte, o.config.RecvUnAckLimitW<<4),
-		sendASDU:         make(chan []byte, o.config.SendUnAckLimitK<<4),
-		rcvRaw:           make(chan []byte, o.config.RecvUnAckLimitW<<5),
-		sendRaw:          make(chan []byte, o.config.SendUnAckLimitK<<5), // may not block!
+		rcvASDU:          make(chan *apduBuf, o.config.RecvUnAckLimitW<<4),
+		sendASDU:         newSendQueue(o.priority, int(o.config.SendUnAckLimitK<<4)),
+		rcvRaw:           make(chan *apduBuf, o.config.RecvUnAckLimitW<<5),
+		sendRaw:          make(chan *apduBuf, o.config.SendUnAckLimitK<<5), // may not block!
 		Clog:             clog.NewLogger("cs104 client => "),
+		metrics:          noopMetrics{},
+		cmdReply:         make(chan *asdu.ASDU, 16),
+		cmdBusy:          make(chan struct{}, 1),
+		endOfInit:        make(chan struct{}, 1),
+		startupActive:    make(chan struct{}, 1),
 		onConnect:        func(*Client) {},
+		onActive:         func(*Client) {},
 		onConnectionLost: func(*Client) {},
 	}
 }
 
+// SetMetrics set the collector of operational metrics, nil will disable it.
+// It should be set before Start.
+func (sf *Client) SetMetrics(m Metrics) *Client {
+	if m == nil {
+		m = noopMetrics{}
+	}
+	sf.metrics = m
+	return sf
+}
+
+// SetObserver set the observer of every APDU received and sent, nil will disable it.
+// It should be set before Start.
+func (sf *Client) SetObserver(o Observer) *Client {
+	sf.observer = o
+	return sf
+}
+
+// SetJobs set the periodic jobs run while data transfer is active, see Job.
+// It should be set before Start.
+func (sf *Client) SetJobs(jobs ...Job) *Client {
+	sf.jobs = append([]Job(nil), jobs...)
+	return sf
+}
+
 // SetOnConnectHandler set on connect handler
 func (sf *Client) SetOnConnectHandler(f func(c *Client)) *Client {
 	if f != nil {
@@ -88,6 +136,15 @@ func (sf *Client) SetOnConnectHandler(f func(c *Client)) *Client {
 	return sf
 }
 
+// SetOnActiveHandler set the handler called when data transfer is activated(STARTDT confirmed),
+// it is called in the state machine, so it should return quickly.
+func (sf *Client) SetOnActiveHandler(f func(c *Client)) *Client {
+	if f != nil {
+		sf.onActive = f
+	}
+	return sf
+}
+
 // SetConnectionLostHandler set connection lost handler
 func (sf *Client) SetConnectionLostHandler(f func(c *Client)) *Client {
 	if f != nil {
@@ -119,7 +176,8 @@ func (sf *Client) running() {
 	sf.rwMux.Unlock()
 	defer sf.setConnectStatus(initial)
 
-	for {
+	isReconnect := false
+	for attempt := 0; ; {
 		select {
 		case <-ctx.Done():
 			return
@@ -127,26 +185,47 @@ func (sf *Client) running() {
 		}
 
 		sf.Debug("connecting server %+v", sf.option.server)
-		conn, err := openConnection(sf.option.server, sf.option.TLSConfig, sf.option.config.ConnectTimeout0)
+		sf.setState(StateConnecting, nil)
+		conn, err := openConnection(ctx, sf.option.server, sf.option.TLSConfig, sf.option.config.ConnectTimeout0)
+		if err == nil {
+			conn, _, err = sf.option.preamble.start(conn, sf.option.config.ConnectTimeout0)
+		}
 		if err != nil {
+			if ctx.Err() != nil {
+				// 连接中被Close中断
+				sf.setState(StateDisconnected, ErrUseClosedConnection)
+				return
+			}
 			sf.Error("connect failed, %v", err)
-			if !sf.option.autoReconnect {
+			sf.setState(StateDisconnected, &DialError{err})
+			attempt++
+			wait, ok := sf.option.reconnectWait(attempt, err, false)
+			if !ok || !sleepContext(ctx, wait) {
 				return
 			}
-			time.Sleep(sf.option.reconnectInterval)
 			continue
 		}
+		attempt = 0
 		sf.Debug("connect success")
+		if sf.option.connWrapper != nil {
+			conn = sf.option.connWrapper(conn)
+		}
+		if isReconnect {
+			sf.metrics.Reconnect()
+		}
 		sf.conn = conn
-		sf.run(ctx)
+		err = sf.run(ctx)
+		isReconnect = true
 
 		sf.Debug("disconnected server %+v", sf.option.server)
-		select {
-		case <-ctx.Done():
+		if ctx.Err() != nil {
+			return
+		}
+		// 连接断开作为新一轮重连的第一次失败
+		attempt++
+		wait, ok := sf.option.reconnectWait(attempt, err, true)
+		if !ok || !sleepContext(ctx, wait) {
 			return
-		default:
-			// 随机500ms-1s的重试，避免快速重试造成服务器许多无效连接
-			time.Sleep(time.Millisecond * time.Duration(500+rand.Intn(500)))
 		}
 	}
 }
@@ -160,7 +239,8 @@ func (sf *Client) recvLoop() {
 	}()
 
 	for {
-		rawData := make([]byte, APDUSizeMax)
+		buf := getBuf()
+		rawData := buf.data[:]
 		for rdCnt, length := 0, 2; rdCnt < length; {
 			byteCount, err := io.ReadFull(sf.conn, rawData[rdCnt:length])
 			if err != nil {
@@ -168,14 +248,17 @@ func (sf *Client) recvLoop() {
 				if err != io.EOF && err != io.ErrClosedPipe ||
 					strings.Contains(err.Error(), "use of closed network connection") {
 					sf.Error("receive failed, %v", err)
+					sf.setLostErr(err)
 					return
 				}
 				if e, ok := err.(net.Error); ok && !e.Temporary() {
 					sf.Error("receive failed, %v", err)
+					sf.setLostErr(err)
 					return
 				}
 				if rdCnt == 0 && err == io.EOF {
 					sf.Error("remote connect closed, %v", err)
+					sf.setLostErr(err)
 					return
 				}
 			}
@@ -199,9 +282,13 @@ func (sf *Client) recvLoop() {
 					continue
 				}
 				if rdCnt == length {
-					apdu := rawData[:length]
+					buf.n = length
+					apdu := buf.bytes()
 					sf.Debug("RX Raw[% x]", apdu)
-					sf.rcvRaw <- apdu
+					if sf.observer != nil {
+						observe(sf.observer, &sf.option.params, sf.conn, DirReceived, apdu)
+					}
+					sf.rcvRaw <- buf
 				}
 			}
 		}
@@ -219,8 +306,12 @@ func (sf *Client) sendLoop() {
 		select {
 		case <-sf.ctx.Done():
 			return
-		case apdu := <-sf.sendRaw:
+		case buf := <-sf.sendRaw:
+			apdu := buf.bytes()
 			sf.Debug("TX Raw[% x]", apdu)
+			if sf.observer != nil {
+				observe(sf.observer, &sf.option.params, sf.conn, DirSent, apdu)
+			}
 			for wrCnt := 0; len(apdu) > wrCnt; {
 				byteCount, err := sf.conn.Write(apdu[wrCnt:])
 				if err != nil {
@@ -228,27 +319,33 @@ func (sf *Client) sendLoop() {
 					if err != io.EOF && err != io.ErrClosedPipe ||
 						strings.Contains(err.Error(), "use of closed network connection") {
 						sf.Error("sendRaw failed, %v", err)
+						sf.setLostErr(err)
 						return
 					}
 					if e, ok := err.(net.Error); !ok || !e.Temporary() {
 						sf.Error("sendRaw failed, %v", err)
+						sf.setLostErr(err)
 						return
 					}
 					// temporary error may be recoverable
 				}
 				wrCnt += byteCount
 			}
+			putBuf(buf)
 		}
 	}
 }
 
 // run is the big fat state machine.
-func (sf *Client) run(ctx context.Context) {
+func (sf *Client) run(ctx context.Context) (lostErr error) {
 	sf.Debug("run started!")
 	// before any thing make sure init
 	sf.cleanUp()
 
+	sf.rwMux.Lock()
 	sf.ctx, sf.cancel = context.WithCancel(ctx)
+	sf.lostErr = nil
+	sf.rwMux.Unlock()
 	sf.setConnectStatus(connected)
 	sf.wg.Add(3)
 	go sf.recvLoop()
@@ -269,24 +366,30 @@ func (sf *Client) run(ctx context.Context) {
 
 	sendSFrame := func(rcvSN uint16) {
 		sf.Debug("TX sFrame %v", sAPCI{rcvSN})
-		sf.sendRaw <- newSFrame(rcvSN)
+		sf.sendRaw <- newBuf(newSFrame(rcvSN))
+		sf.metrics.FrameSent(FrameS)
+		sf.metrics.RecvWindow(0, sf.option.config.RecvUnAckLimitW)
 	}
 
-	sendIFrame := func(asdu1 []byte) {
+	sendIFrame := func(buf *apduBuf) {
 		seqNo := sf.seqNoSend
 
-		iframe, err := newIFrame(seqNo, sf.seqNoRcv, asdu1)
-		if err != nil {
-			return
-		}
+		buf.setIFrame(seqNo, sf.seqNoRcv)
 		sf.ackNoRcv = sf.seqNoRcv
 		sf.seqNoSend = (seqNo + 1) & 32767
-		sf.pending = append(sf.pending, seqPending{seqNo & 32767, time.Now()})
+		sf.pending = append(sf.pending, seqPending{seqNo & 32767, time.Now(), buf.ack})
 
 		sf.Debug("TX iFrame %v", iAPCI{seqNo, sf.seqNoRcv})
-		sf.sendRaw <- iframe
+		typeID, cause := buf.typeCause()
+		sf.sendRaw <- buf
+		sf.metrics.FrameSent(FrameI)
+		sf.metrics.ASDUSent(typeID, cause)
+		sf.metrics.SendWindow(seqNoCount(sf.ackNoSend, sf.seqNoSend), sf.option.config.SendUnAckLimitK)
+		sf.metrics.RecvWindow(0, sf.option.config.RecvUnAckLimitW)
+		sf.metrics.SendBuffer(sf.sendASDU.len(), sf.sendASDU.cap())
 	}
 
+	// lostErr 连接断开的原因
 	defer func() {
 		// default: STOPDT, when connected establish and not enable "data transfer" yet
 		atomic.StoreUint32(&sf.isActive, inactive)
@@ -294,21 +397,52 @@ func (sf *Client) run(ctx context.Context) {
 		checkTicker.Stop()
 		_ = sf.conn.Close() // 连锁引发cancel
 		sf.wg.Wait()
+		sf.cleanUp()
 		sf.onConnectionLost(sf)
+		switch {
+		case ctx.Err() != nil:
+			lostErr = ErrUseClosedConnection
+		case lostErr == nil:
+			sf.rwMux.RLock()
+			lostErr = sf.lostErr
+			sf.rwMux.RUnlock()
+			if lostErr == nil || lostErr == io.EOF {
+				lostErr = ErrRemoteClosed
+			}
+		}
+		sf.setState(StateDisconnected, lostErr)
 		sf.Debug("run stopped!")
 	}()
 
+	// 已发送STOPDT激活, 等待确认, 不再发送新的I帧
+	var stopping = false
+
 	sf.onConnect(sf)
+	sf.setState(StateConnected, nil)
+	if sf.option.startup != nil {
+		sf.wg.Add(1)
+		go sf.startupLoop()
+	}
+	if len(sf.jobs) > 0 {
+		sf.wg.Add(1)
+		go sf.scheduleLoop()
+	}
 	for {
-		if atomic.LoadUint32(&sf.isActive) == active && seqNoCount(sf.ackNoSend, sf.seqNoSend) <= sf.option.config.SendUnAckLimitK {
-			select {
-			case o := <-sf.sendASDU:
+		if atomic.CompareAndSwapUint32(&sf.stopDtRequest, 1, 0) {
+			// 先确认已接收的I帧
+			if sf.ackNoRcv != sf.seqNoRcv {
+				sendSFrame(sf.seqNoRcv)
+				sf.ackNoRcv = sf.seqNoRcv
+			}
+			stopping = true
+			sf.stopDtActiveSendSince.Store(time.Now())
+			sf.sendUFrame(uStopDtActive)
+		}
+		if !stopping && atomic.LoadUint32(&sf.isActive) == active && seqNoCount(sf.ackNoSend, sf.seqNoSend) < sf.option.config.SendUnAckLimitK {
+			if o := sf.sendASDU.pop(); o != nil {
 				sendIFrame(o)
 				idleTimeout3Sine = time.Now()
 				continue
-			case <-sf.ctx.Done():
-				return
-			default: // make no block
 			}
 		}
 		select {
@@ -316,10 +450,17 @@ func (sf *Client) run(ctx context.Context) {
 			return
 		case now := <-checkTicker.C:
 			// check all timeouts
-			if now.Sub(testFrAliveSendSince) >= sf.option.config.SendUnAckTimeout1 ||
-				now.Sub(sf.startDtActiveSendSince.Load().(time.Time)) >= sf.option.config.SendUnAckTimeout1 ||
-				now.Sub(sf.stopDtActiveSendSince.Load().(time.Time)) >= sf.option.config.SendUnAckTimeout1 {
+			if now.Sub(testFrAliveSendSince) >= sf.option.config.SendUnAckTimeout1 {
 				sf.Error("test frame alive confirm timeout t₁")
+				sf.metrics.Timeout(TimeoutT1)
+				lostErr = ErrTestFrTimeout
+				return
+			}
+			if now.Sub(sf.startDtActiveSendSince.Load().(time.Time)) >= sf.option.config.SendUnAckTimeout1 ||
+				now.Sub(sf.stopDtActiveSendSince.Load().(time.Time)) >= sf.option.config.SendUnAckTimeout1 {
+				sf.Error("STARTDT/STOPDT confirm timeout t₁")
+				sf.metrics.Timeout(TimeoutT1)
+				lostErr = ErrT1Timeout
 				return
 			}
 			// check oldest unacknowledged outbound
@@ -328,6 +469,8 @@ func (sf *Client) run(ctx context.Context) {
 				now.Sub(sf.pending[0].sendTime) >= sf.option.config.SendUnAckTimeout1 {
 				sf.ackNoSend++
 				sf.Error("fatal transmission timeout t₁")
+				sf.metrics.Timeout(TimeoutT1)
+				lostErr = ErrT1Timeout
 				return
 			}
 
@@ -335,45 +478,58 @@ func (sf *Client) run(ctx context.Context) {
 			if sf.ackNoRcv != sf.seqNoRcv &&
 				(now.Sub(unAckRcvSince) >= sf.option.config.RecvUnAckTimeout2 ||
 					now.Sub(idleTimeout3Sine) >= timeoutResolution) {
+				if now.Sub(unAckRcvSince) >= sf.option.config.RecvUnAckTimeout2 {
+					sf.metrics.Timeout(TimeoutT2)
+				}
 				sendSFrame(sf.seqNoRcv)
 				sf.ackNoRcv = sf.seqNoRcv
 			}
 
 			// 空闲时间到，发送TestFrActive帧,保活
 			if now.Sub(idleTimeout3Sine) >= sf.option.config.IdleTimeout3 {
+				sf.metrics.Timeout(TimeoutT3)
 				sf.sendUFrame(uTestFrActive)
 				testFrAliveSendSince = time.Now()
 				idleTimeout3Sine = testFrAliveSendSince
 			}
 
-		case apdu := <-sf.rcvRaw:
+		case buf := <-sf.rcvRaw:
 			idleTimeout3Sine = time.Now() // 每收到一个i帧,S帧,U帧, 重置空闲定时器, t3
-			apci, asduVal := parse(apdu)
+			apci, _ := parse(buf.bytes())
 			switch head := apci.(type) {
 			case sAPCI:
 				sf.Debug("RX sFrame %v", head)
+				sf.metrics.FrameReceived(FrameS)
 				if !sf.updateAckNoOut(head.rcvSN) {
 					sf.Error("fatal incoming acknowledge either earlier than previous or later than sendTime")
+					lostErr = ErrSequence
 					return
 				}
+				sf.metrics.SendWindow(seqNoCount(sf.ackNoSend, sf.seqNoSend), sf.option.config.SendUnAckLimitK)
 
 			case iAPCI:
 				sf.Debug("RX iFrame %v", head)
+				sf.metrics.FrameReceived(FrameI)
 				if atomic.LoadUint32(&sf.isActive) == inactive {
 					sf.Warn("station not active")
 					break // not active, discard apdu
 				}
 				if !sf.updateAckNoOut(head.rcvSN) || head.sendSN != sf.seqNoRcv {
 					sf.Error("fatal incoming acknowledge either earlier than previous or later than sendTime")
+					lostErr = ErrSequence
 					return
 				}
 
-				sf.rcvASDU <- asduVal
+				// 缓冲由handlerLoop归还
+				sf.rcvASDU <- buf
+				buf = nil
 				if sf.ackNoRcv == sf.seqNoRcv { // first unacked
 					unAckRcvSince = time.Now()
 				}
 
 				sf.seqNoRcv = (sf.seqNoRcv + 1) & 32767
+				sf.metrics.SendWindow(seqNoCount(sf.ackNoSend, sf.seqNoSend), sf.option.config.SendUnAckLimitK)
+				sf.metrics.RecvWindow(seqNoCount(sf.ackNoRcv, sf.seqNoRcv), sf.option.config.RecvUnAckLimitW)
 				if seqNoCount(sf.ackNoRcv, sf.seqNoRcv) >= sf.option.config.RecvUnAckLimitW {
 					sendSFrame(sf.seqNoRcv)
 					sf.ackNoRcv = sf.seqNoRcv
@@ -381,27 +537,43 @@ func (sf *Client) run(ctx context.Context) {
 
 			case uAPCI:
 				sf.Debug("RX uFrame %v", head)
+				sf.metrics.FrameReceived(FrameU)
 				switch head.function {
 				//case uStartDtActive:
 				//	sf.sendUFrame(uStartDtConfirm)
 				//	atomic.StoreUint32(&sf.isActive, active)
 				case uStartDtConfirm:
 					atomic.StoreUint32(&sf.isActive, active)
+					stopping = false
 					sf.startDtActiveSendSince.Store(willNotTimeout)
+					select {
+					case sf.startupActive <- struct{}{}:
+					default:
+					}
+					sf.onActive(sf)
+					sf.setState(StateActive, nil)
 				//case uStopDtActive:
 				//	sf.sendUFrame(uStopDtConfirm)
 				//	atomic.StoreUint32(&sf.isActive, inactive)
 				case uStopDtConfirm:
 					atomic.StoreUint32(&sf.isActive, inactive)
+					stopping = false
 					sf.stopDtActiveSendSince.Store(willNotTimeout)
+					sf.setState(StateStopped, nil)
 				case uTestFrActive:
 					sf.sendUFrame(uTestFrConfirm)
 				case uTestFrConfirm:
+					if testFrAliveSendSince != willNotTimeout {
+						sf.metrics.TestFrameRTT(time.Since(testFrAliveSendSince))
+					}
 					testFrAliveSendSince = willNotTimeout
 				default:
 					sf.Error("illegal U-Frame functions[0x%02x] ignored", head.function)
 				}
 			}
+			if buf != nil {
+				putBuf(buf)
+			}
 		}
 	}
 }
@@ -413,16 +585,25 @@ func (sf *Client) handlerLoop() {
 		sf.Debug("handlerLoop stopped")
 	}()
 
+	first := true
 	for {
 		select {
 		case <-sf.ctx.Done():
 			return
-		case rawAsdu := <-sf.rcvASDU:
+		case buf := <-sf.rcvASDU:
 			asduPack := asdu.NewEmptyASDU(&sf.option.params)
-			if err := asduPack.UnmarshalBinary(rawAsdu); err != nil {
+			err := asduPack.UnmarshalBinary(buf.asdu())
+			putBuf(buf)
+			if err != nil {
 				sf.Warn("asdu UnmarshalBinary failed,%+v", err)
 				continue
 			}
+			sf.metrics.ASDUReceived(asduPack.Type, asduPack.Coa.Cause)
+			if first && sf.firstASDU != nil {
+				sf.firstASDU(asduPack)
+			}
+			first = false
+			sf.cmdNotify(asduPack)
 			if err := sf.clientHandler(asduPack); err != nil {
 				sf.Warn("Falied handling I frame, error: %v", err)
 			}
@@ -448,24 +629,20 @@ func (sf *Client) cleanUp() {
 	sf.ackNoSend = 0
 	sf.seqNoRcv = 0
 	sf.seqNoSend = 0
+	atomic.StoreUint32(&sf.stopDtRequest, 0)
+	failPending(sf.pending)
 	sf.pending = nil
 	// clear sending chan buffer
-loop:
-	for {
-		select {
-		case <-sf.sendRaw:
-		case <-sf.rcvRaw:
-		case <-sf.rcvASDU:
-		case <-sf.sendASDU:
-		default:
-			break loop
-		}
-	}
+	drainBuf(sf.sendRaw)
+	drainBuf(sf.rcvRaw)
+	drainBuf(sf.rcvASDU)
+	sf.sendASDU.drain()
 }
 
 func (sf *Client) sendUFrame(which byte) {
 	sf.Debug("TX uFrame %v", uAPCI{which})
-	sf.sendRaw <- newUFrame(which)
+	sf.sendRaw <- newBuf(newUFrame(which))
+	sf.metrics.FrameSent(FrameU)
 }
 
 func (sf *Client) updateAckNoOut(ackNo uint16) (ok bool) {
@@ -479,7 +656,8 @@ func (sf *Client) updateAckNoOut(ackNo uint16) (ok bool) {
 
 	// confirm reception
 	for i, v := range sf.pending {
-		if v.seq == (ackNo - 1) {
+		if v.seq == (ackNo-1)&32767 {
+			ackPending(sf.pending[:i+1])
 			sf.pending = sf.pending[i+1:]
 			break
 		}
@@ -530,6 +708,11 @@ func (sf *Client) clientHandler(asduPack *asdu.ASDU) error {
 	return sf.handler.ASDUHandler(sf, asduPack)
 }
 
+// IsActive get data transfer status, active or inactive
+func (sf *Client) IsActive() bool {
+	return sf.IsConnected() && atomic.LoadUint32(&sf.isActive) == active
+}
+
 // Params returns params of client
 func (sf *Client) Params() *asdu.Params {
 	return &sf.option.params
@@ -537,21 +720,63 @@ func (sf *Client) Params() *asdu.Params {
 
 // Send send asdu
 func (sf *Client) Send(a *asdu.ASDU) error {
+	return sf.send(nil, a, nil)
+}
+
+// SendContext send asdu frame, wait for the send buffer if it is full
+// until the connection drops or ctx is done. imp interface asdu.ContextConnect
+func (sf *Client) SendContext(ctx context.Context, a *asdu.ASDU) error {
+	return sf.send(ctx, a, nil)
+}
+
+// connDone 当前连接的结束信号
+func (sf *Client) connDone() <-chan struct{} {
+	sf.rwMux.RLock()
+	defer sf.rwMux.RUnlock()
+	if sf.ctx == nil {
+		return nil
+	}
+	return sf.ctx.Done()
+}
+
+// SendWithAck send asdu, the returned Delivery completes when the server
+// acknowledges the I-frame, or fails if the connection drops or ctx is done first.
+func (sf *Client) SendWithAck(ctx context.Context, a *asdu.ASDU) *Delivery {
+	d := newDelivery(ctx)
+	if err := ctx.Err(); err != nil {
+		d.resolve(err)
+	} else if err = sf.send(nil, a, d); err != nil {
+		d.resolve(err)
+	}
+	return d
+}
+
+// send ctx为nil时发送缓冲满立即返回ErrBufferFulled, 否则等待
+func (sf *Client) send(ctx context.Context, a *asdu.ASDU, ack *Delivery) error {
 	if !sf.IsConnected() {
 		return ErrUseClosedConnection
 	}
 	if atomic.LoadUint32(&sf.isActive) == inactive {
 		return ErrNotActive
 	}
-	data, err := a.MarshalBinary()
+	buf, err := encodeASDU(a)
 	if err != nil {
 		return err
 	}
-	select {
-	case sf.sendASDU <- data:
-	default:
-		return ErrBufferFulled
+	buf.ack = ack
+	if ctx == nil {
+		if !sf.sendASDU.push(a, buf) {
+			err = ErrBufferFulled
+		}
+	} else {
+		err = sf.sendASDU.pushContext(ctx, sf.connDone(), a, buf)
+	}
+	if err != nil {
+		putBuf(buf)
+		sf.metrics.SendBuffer(sf.sendASDU.len(), sf.sendASDU.cap())
+		return err
 	}
+	sf.metrics.SendBuffer(sf.sendASDU.len(), sf.sendASDU.cap())
 	return nil
 }
 
@@ -576,20 +801,27 @@ func (sf *Client) SendStartDt() {
 	sf.sendUFrame(uStartDtActive)
 }
 
-// SendStopDt stop data transmission on this connection
+// SendStopDt stop data transmission on this connection,
+// the received I-frames are acknowledged and no new I-frame is sent before STOPDT act.
 func (sf *Client) SendStopDt() {
-	sf.stopDtActiveSendSince.Store(time.Now())
-	sf.sendUFrame(uStopDtActive)
+	atomic.StoreUint32(&sf.stopDtRequest, 1)
 }
 
-//InterrogationCmd wrap asdu.InterrogationCmd
+// InterrogationCmd wrap asdu.InterrogationCmd,
+// serialized with the startup and the jobs if configured, see Startup and Job,
+// it returns ErrCommandInProgress without blocking while another command is waiting for its reply.
 func (sf *Client) InterrogationCmd(coa asdu.CauseOfTransmission, ca asdu.CommonAddr, qoi asdu.QualifierOfInterrogation) error {
-	return asdu.InterrogationCmd(sf, coa, ca, qoi)
+	return sf.command(replyKey{asdu.C_IC_NA_1, ca, byte(qoi)}, coa, func() error {
+		return asdu.InterrogationCmd(sf, coa, ca, qoi)
+	})
 }
 
-// CounterInterrogationCmd wrap asdu.CounterInterrogationCmd
+// CounterInterrogationCmd wrap asdu.CounterInterrogationCmd,
+// serialized with the startup and the jobs if configured as InterrogationCmd.
 func (sf *Client) CounterInterrogationCmd(coa asdu.CauseOfTransmission, ca asdu.CommonAddr, qcc asdu.QualifierCountCall) error {
-	return asdu.CounterInterrogationCmd(sf, coa, ca, qcc)
+	return sf.command(replyKey{asdu.C_CI_NA_1, ca, qcc.Value()}, coa, func() error {
+		return asdu.CounterInterrogationCmd(sf, coa, ca, qcc)
+	})
 }
 
 // ReadCmd wrap asdu.ReadCmd
@@ -597,9 +829,12 @@ func (sf *Client) ReadCmd(coa asdu.CauseOfTransmission, ca asdu.CommonAddr, ioa
 	return asdu.ReadCmd(sf, coa, ca, ioa)
 }
 
-// ClockSynchronizationCmd wrap asdu.ClockSynchronizationCmd
+// ClockSynchronizationCmd wrap asdu.ClockSynchronizationCmd,
+// serialized with the startup and the jobs if configured as InterrogationCmd.
 func (sf *Client) ClockSynchronizationCmd(coa asdu.CauseOfTransmission, ca asdu.CommonAddr, t time.Time) error {
-	return asdu.ClockSynchronizationCmd(sf, coa, ca, t)
+	return sf.command(replyKey{typeID: asdu.C_CS_NA_1, ca: ca}, coa, func() error {
+		return asdu.ClockSynchronizationCmd(sf, coa, ca, t)
+	})
 }
 
 // ResetProcessCmd wrap asdu.ResetProcessCmd
@@ -6,6 +6,8 @@ package cs104
 
 import (
 	"crypto/tls"
+	"math/rand"
+	"net"
 	"net/url"
 	"strings"
 	"time"
@@ -21,6 +23,12 @@ type ClientOption struct {
 	autoReconnect     bool          // 是否启动重连
 	reconnectInterval time.Duration // 重连间隔时间
 	TLSConfig         *tls.Config   // tls配置
+	connWrapper       func(net.Conn) net.Conn
+	priority          SendPriority // 发送优先级
+	startup           *Startup     // 启动流程, nil为不启用
+	reconnectPolicy   ReconnectPolicy
+	onReconnect       func(attempt int, wait time.Duration, err error)
+	preamble          *Preamble
 }
 
 // NewOption with default config and default asdu.ParamsWide params
@@ -32,6 +40,12 @@ func NewOption() *ClientOption {
 		true,
 		DefaultReconnectInterval,
 		nil,
+		nil,
+		DefaultSendPriority(),
+		nil,
+		nil,
+		nil,
+		nil,
 	}
 }
 
@@ -63,18 +77,95 @@ func (sf *ClientOption) SetReconnectInterval(t time.Duration) *ClientOption {
 	return sf
 }
 
-// SetAutoReconnect enable auto reconnect
+// SetAutoReconnect enable auto reconnect when connect failed, a lost connection is always reconnected
 func (sf *ClientOption) SetAutoReconnect(b bool) *ClientOption {
 	sf.autoReconnect = b
 	return sf
 }
 
+// SetReconnectPolicy set the policy of reconnect when connect failed or the connection lost,
+// the attempt starts from 1 again after a connection established.
+// nil will wait the reconnect interval after connect failed, and random 500ms-1s after the connection lost.
+func (sf *ClientOption) SetReconnectPolicy(p ReconnectPolicy) *ClientOption {
+	sf.reconnectPolicy = p
+	return sf
+}
+
+// SetReconnectHandler set the handler called when connect failed or the connection lost,
+// with the number of consecutive failed attempts, the wait before next attempt and the error.
+// wait is negative when it will not reconnect.
+func (sf *ClientOption) SetReconnectHandler(f func(attempt int, wait time.Duration, err error)) *ClientOption {
+	sf.onReconnect = f
+	return sf
+}
+
+// reconnectWait 第attempt次连续失败后到下一次连接的等待时间, ok为false时停止重连.
+// lost为true时为已建立的连接断开, 不受autoReconnect限制, 未设置重连策略时随机等待500ms-1s,
+// 避免快速重试造成服务器许多无效连接
+func (sf *ClientOption) reconnectWait(attempt int, err error, lost bool) (wait time.Duration, ok bool) {
+	switch {
+	case !sf.autoReconnect && !lost:
+	case sf.reconnectPolicy != nil:
+		wait, ok = sf.reconnectPolicy.Next(attempt)
+	case lost:
+		wait, ok = time.Millisecond*time.Duration(500+rand.Intn(500)), true
+	default:
+		wait, ok = sf.reconnectInterval, true
+	}
+	if !ok {
+		wait = -1
+	} else if wait < 0 {
+		wait = 0
+	}
+	if sf.onReconnect != nil {
+		sf.onReconnect(attempt, wait, err)
+	}
+	return wait, ok
+}
+
 // SetTLSConfig set tls config
 func (sf *ClientOption) SetTLSConfig(t *tls.Config) *ClientOption {
 	sf.TLSConfig = t
 	return sf
 }
 
+// SetConnWrapper set the function wraps every connection established, used for fault injection in tests
+func (sf *ClientOption) SetConnWrapper(f func(net.Conn) net.Conn) *ClientOption {
+	sf.connWrapper = f
+	return sf
+}
+
+// SetSendPriority set the send priority of asdu
+func (sf *ClientOption) SetSendPriority(p SendPriority) *ClientOption {
+	sf.priority = p
+	return sf
+}
+
+// SetStartup set the startup sequence run after every connection established,
+// it sends STARTDT itself, nil will disable it. zero timeouts use those of DefaultStartup.
+func (sf *ClientOption) SetStartup(s *Startup) *ClientOption {
+	if s != nil {
+		c := *s
+		c.Steps = append([]StartupStep(nil), s.Steps...)
+		def := DefaultStartup(s.CommonAddr)
+		if c.Timeout <= 0 {
+			c.Timeout = def.Timeout
+		}
+		if c.EndOfInitTimeout <= 0 {
+			c.EndOfInitTimeout = def.EndOfInitTimeout
+		}
+		s = &c
+	}
+	sf.startup = s
+	return sf
+}
+
+// SetPreamble set the registration frame and heartbeats of DTU style connections, nil will disable it.
+func (sf *ClientOption) SetPreamble(p *Preamble) *ClientOption {
+	sf.preamble = p
+	return sf
+}
+
 // AddRemoteServer adds a broker URI to the list of brokers to be used.
 // The format should be scheme://host:port
 // Default values for hostname is "127.0.0.1", for schema is "tcp://".
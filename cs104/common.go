@@ -5,6 +5,7 @@
 package cs104
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"net"
@@ -18,18 +19,21 @@ const DefaultReconnectInterval = 1 * time.Minute
 type seqPending struct {
 	seq      uint16
 	sendTime time.Time
+	ack      *Delivery // SendWithAck 的发送结果
 }
 
-func openConnection(uri *url.URL, tlsc *tls.Config, timeout time.Duration) (net.Conn, error) {
+func openConnection(ctx context.Context, uri *url.URL, tlsc *tls.Config, timeout time.Duration) (net.Conn, error) {
 	switch uri.Scheme {
 	case "tcp":
-		return net.DialTimeout("tcp", uri.Host, timeout)
+		d := &net.Dialer{Timeout: timeout}
+		return d.DialContext(ctx, "tcp", uri.Host)
 	case "ssl":
 		fallthrough
 	case "tls":
 		fallthrough
 	case "tcps":
-		return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", uri.Host, tlsc)
+		d := &tls.Dialer{NetDialer: &net.Dialer{Timeout: timeout}, Config: tlsc}
+		return d.DialContext(ctx, "tcp", uri.Host)
 	}
 	return nil, errors.New("Unknown protocol")
 }
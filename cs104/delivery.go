@@ -0,0 +1,83 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs104
+
+import (
+	"context"
+	"sync"
+)
+
+// Delivery SendWithAck 的发送结果, 对端以S帧或I帧的接收序号确认该I帧后完成,
+// 确认前连接断开(t₁超时,关闭等)或ctx结束则失败.
+type Delivery struct {
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+func newDelivery(ctx context.Context) *Delivery {
+	d := &Delivery{done: make(chan struct{})}
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				d.resolve(ctx.Err())
+			case <-d.done:
+			}
+		}()
+	}
+	return d
+}
+
+// resolve 完成, err为nil表示已确认, 只有第一次有效
+func (sf *Delivery) resolve(err error) {
+	sf.once.Do(func() {
+		sf.err = err
+		close(sf.done)
+	})
+}
+
+// Done 完成时关闭
+func (sf *Delivery) Done() <-chan struct{} {
+	return sf.done
+}
+
+// Err 完成后返回结果, nil表示对端已确认, 未完成时返回nil
+func (sf *Delivery) Err() error {
+	select {
+	case <-sf.done:
+		return sf.err
+	default:
+		return nil
+	}
+}
+
+// Wait 等待完成并返回结果, ctx结束时返回ctx.Err(), 不影响Delivery本身
+func (sf *Delivery) Wait(ctx context.Context) error {
+	select {
+	case <-sf.done:
+		return sf.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ackPending 完成已被确认的发送
+func ackPending(pending []seqPending) {
+	for _, v := range pending {
+		if v.ack != nil {
+			v.ack.resolve(nil)
+		}
+	}
+}
+
+// failPending 连接断开时使未确认的发送失败
+func failPending(pending []seqPending) {
+	for _, v := range pending {
+		if v.ack != nil {
+			v.ack.resolve(ErrConnectionLost)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package cs104
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+func TestSrvSession_SendWithAck(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		status  uint32
+		wantErr error
+	}{
+		{"closed", context.Background(), disconnected, ErrUseClosedConnection},
+		{"ctx canceled", canceled, connected, context.Canceled},
+		{"connection lost", context.Background(), connected, ErrConnectionLost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := newSendSession(1)
+			sf.status = tt.status
+			d := sf.SendWithAck(tt.ctx, newSendASDU())
+			sf.cleanUp() // 未发送的被丢弃
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := d.Wait(ctx); err != tt.wantErr {
+				t.Errorf("Delivery.Wait() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSrvSession_updateAckNoOut_delivery(t *testing.T) {
+	sf := &SrvSession{seqNoSend: 3}
+	var ds []*Delivery
+	for i := uint16(0); i < 3; i++ {
+		d := newDelivery(context.Background())
+		ds = append(ds, d)
+		sf.pending = append(sf.pending, seqPending{i, time.Now(), d})
+	}
+	if !sf.updateAckNoOut(2) {
+		t.Fatal("updateAckNoOut() = false")
+	}
+	for i, d := range ds {
+		select {
+		case <-d.Done():
+			if i == 2 || d.Err() != nil {
+				t.Errorf("Delivery %d done, error %v", i, d.Err())
+			}
+		default:
+			if i != 2 {
+				t.Errorf("Delivery %d not done", i)
+			}
+		}
+	}
+	sf.sendASDU = newSendQueue(DefaultSendPriority(), 1)
+	sf.cleanUp()
+	if err := ds[2].Wait(context.Background()); err != ErrConnectionLost {
+		t.Errorf("Delivery.Wait() after connection lost = %v", err)
+	}
+}
+
+func TestClient_SendWithAck(t *testing.T) {
+	c := NewClient(&mockClientHandler{}, NewOption())
+	d := c.SendWithAck(context.Background(), asdu.NewASDU(asdu.ParamsWide, asdu.Identifier{}))
+	if err := d.Wait(context.Background()); err != ErrUseClosedConnection {
+		t.Errorf("Delivery.Wait() = %v, want %v", err, ErrUseClosedConnection)
+	}
+}
+
+func TestSrvSession_updateAckNoOut_wraparound(t *testing.T) {
+	tests := []struct {
+		name  string
+		ackNo uint16
+		want  []bool // 各个发送是否已确认
+	}{
+		{"before wrap", 32767, []bool{true, false, false}},
+		{"at wrap", 0, []bool{true, true, false}},
+		{"after wrap", 1, []bool{true, true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := &SrvSession{ackNoSend: 32766, seqNoSend: 1}
+			var ds []*Delivery
+			for _, seq := range []uint16{32766, 32767, 0} {
+				d := newDelivery(context.Background())
+				ds = append(ds, d)
+				sf.pending = append(sf.pending, seqPending{seq, time.Now(), d})
+			}
+			if !sf.updateAckNoOut(tt.ackNo) {
+				t.Fatal("updateAckNoOut() = false")
+			}
+			for i, d := range ds {
+				select {
+				case <-d.Done():
+					if !tt.want[i] || d.Err() != nil {
+						t.Errorf("Delivery %d done, error %v", i, d.Err())
+					}
+				default:
+					if tt.want[i] {
+						t.Errorf("Delivery %d not done", i)
+					}
+				}
+			}
+			acked := 0
+			for _, v := range tt.want {
+				if v {
+					acked++
+				}
+			}
+			if len(sf.pending) != len(ds)-acked {
+				t.Errorf("pending %d after ack %d, want %d", len(sf.pending), tt.ackNo, len(ds)-acked)
+			}
+		})
+	}
+}
@@ -13,4 +13,24 @@ var (
 	ErrUseClosedConnection = errors.New("use of closed connection")
 	ErrBufferFulled        = errors.New("buffer is full")
 	ErrNotActive           = errors.New("server is not active")
+	ErrConnectionLost      = errors.New("connection lost before acknowledged")
+	ErrCommandInProgress   = errors.New("command in progress")
 )
+
+// 连接断开的原因, 见StateEvent
+var (
+	ErrT1Timeout     = errors.New("acknowledge timeout t₁")
+	ErrTestFrTimeout = errors.New("test frame confirm timeout t₁")
+	ErrSequence      = errors.New("sequence number error")
+	ErrRemoteClosed  = errors.New("remote closed connection")
+)
+
+// DialError 建立连接失败的错误
+type DialError struct {
+	Err error
+}
+
+func (sf *DialError) Error() string { return "dial failed, " + sf.Err.Error() }
+
+// Unwrap returns the underlying error
+func (sf *DialError) Unwrap() error { return sf.Err }
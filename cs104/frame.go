@@ -0,0 +1,165 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs104
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// U帧控制功能, 见 Control.Function
+const (
+	UStartDtActive  = uStartDtActive  // 启动激活 0x04
+	UStartDtConfirm = uStartDtConfirm // 启动确认 0x08
+	UStopDtActive   = uStopDtActive   // 停止激活 0x10
+	UStopDtConfirm  = uStopDtConfirm  // 停止确认 0x20
+	UTestFrActive   = uTestFrActive   // 测试激活 0x40
+	UTestFrConfirm  = uTestFrConfirm  // 测试确认 0x80
+)
+
+// ErrInvalidAPDU APDU的启动字符或长度错误
+var ErrInvalidAPDU = errors.New("cs104: invalid APDU")
+
+// Control 解析后的APCI控制域
+type Control struct {
+	Type     FrameType
+	SendSN   uint16 // I帧发送序号
+	RecvSN   uint16 // I帧, S帧接收序号
+	Function byte   // U帧控制功能
+}
+
+func (sf Control) String() string {
+	switch sf.Type {
+	case FrameI:
+		return iAPCI{sf.SendSN, sf.RecvSN}.String()
+	case FrameS:
+		return sAPCI{sf.RecvSN}.String()
+	}
+	return uAPCI{sf.Function}.String()
+}
+
+// ParseAPDU 解析一个完整的APDU, 返回控制域及ASDU(I帧)
+func ParseAPDU(apdu []byte) (Control, []byte, error) {
+	if len(apdu) < APCICtlFiledSize+2 || apdu[0] != startFrame || int(apdu[1])+2 != len(apdu) {
+		return Control{}, nil, ErrInvalidAPDU
+	}
+	apci, asduVal := parse(apdu)
+	switch head := apci.(type) {
+	case iAPCI:
+		return Control{Type: FrameI, SendSN: head.sendSN, RecvSN: head.rcvSN}, asduVal, nil
+	case sAPCI:
+		return Control{Type: FrameS, RecvSN: head.rcvSN}, nil, nil
+	case uAPCI:
+		return Control{Type: FrameU, Function: head.function}, nil, nil
+	}
+	return Control{}, nil, ErrInvalidAPDU
+}
+
+// ScanAPDU is a bufio.SplitFunc that splits a byte stream into APDUs the same way as
+// the receive loop: bytes before the start character are skipped, and an invalid
+// length drops the start character and the length.
+func ScanAPDU(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	for {
+		i := bytes.IndexByte(data[advance:], startFrame)
+		if i < 0 {
+			return len(data), nil, nil
+		}
+		advance += i
+		if len(data)-advance < 2 {
+			break
+		}
+		length := int(data[advance+1]) + 2
+		if length < APCICtlFiledSize+2 || length > APDUSizeMax {
+			advance += 2
+			continue
+		}
+		if len(data)-advance < length {
+			break
+		}
+		return advance + length, data[advance : advance+length], nil
+	}
+	if atEOF { // 未完成的APDU
+		return len(data), nil, nil
+	}
+	return advance, nil, nil
+}
+
+// Direction 帧的传输方向
+type Direction byte
+
+// 帧的传输方向
+const (
+	DirReceived Direction = iota // 接收
+	DirSent                      // 发送
+)
+
+func (sf Direction) String() string {
+	if sf == DirSent {
+		return "TX"
+	}
+	return "RX"
+}
+
+// Frame 一个收发的APDU
+type Frame struct {
+	Direction Direction
+	Time      time.Time
+	Local     net.Addr
+	Remote    net.Addr
+	APDU      []byte     // 原始报文, 不可修改
+	Control   Control    // 解析后的控制域
+	ASDU      *asdu.ASDU // I帧的ASDU, 解码失败时为nil
+	Err       error      // ASDU的解码错误
+}
+
+// Observer is the interface of frame observer, it is called in the receive and send loop
+// of the connection for every APDU, so it should return quickly.
+type Observer interface {
+	Observe(f *Frame)
+}
+
+// ObserverFunc is an adapter to allow the use of ordinary functions as Observer.
+type ObserverFunc func(f *Frame)
+
+// Observe imp Observer
+func (sf ObserverFunc) Observe(f *Frame) {
+	sf(f)
+}
+
+// NewFrame 解析APDU, 并以参数p解码I帧的ASDU
+func NewFrame(p *asdu.Params, dir Direction, t time.Time, apdu []byte) *Frame {
+	f := &Frame{
+		Direction: dir,
+		Time:      t,
+		APDU:      apdu,
+	}
+	ctl, asduVal, err := ParseAPDU(apdu)
+	f.Control = ctl
+	if err != nil {
+		f.Err = err
+	} else if ctl.Type == FrameI {
+		a := asdu.NewEmptyASDU(p)
+		if err = a.UnmarshalBinary(asduVal); err != nil {
+			f.Err = err
+		} else {
+			f.ASDU = a
+		}
+	}
+	return f
+}
+
+// observe 解析APDU并通知观察者
+func observe(o Observer, p *asdu.Params, conn net.Conn, dir Direction, apdu []byte) {
+	// 收发缓冲会被复用, 需拷贝
+	f := NewFrame(p, dir, time.Now(), append([]byte(nil), apdu...))
+	if conn != nil {
+		f.Local, f.Remote = conn.LocalAddr(), conn.RemoteAddr()
+	}
+	o.Observe(f)
+}
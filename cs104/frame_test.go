@@ -0,0 +1,119 @@
+package cs104
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+func TestParseAPDU(t *testing.T) {
+	tests := []struct {
+		name     string
+		apdu     []byte
+		want     Control
+		wantASDU []byte
+		wantErr  bool
+	}{
+		{"I frame", []byte{startFrame, 0x06, 0x02, 0x00, 0x04, 0x00, 0x64, 0x01}, Control{Type: FrameI, SendSN: 1, RecvSN: 2}, []byte{0x64, 0x01}, false},
+		{"S frame", []byte{startFrame, 0x04, 0x01, 0x00, 0x06, 0x00}, Control{Type: FrameS, RecvSN: 3}, nil, false},
+		{"U frame", []byte{startFrame, 0x04, 0x43, 0x00, 0x00, 0x00}, Control{Type: FrameU, Function: UTestFrActive}, nil, false},
+		{"short", []byte{startFrame, 0x04, 0x43}, Control{}, nil, true},
+		{"start", []byte{0x00, 0x04, 0x43, 0x00, 0x00, 0x00}, Control{}, nil, true},
+		{"length", []byte{startFrame, 0x05, 0x43, 0x00, 0x00, 0x00}, Control{}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotASDU, err := ParseAPDU(tt.apdu)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseAPDU() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want || !reflect.DeepEqual(gotASDU, tt.wantASDU) {
+				t.Errorf("ParseAPDU() = %v % x, want %v % x", got, gotASDU, tt.want, tt.wantASDU)
+			}
+		})
+	}
+}
+
+func TestClient_SetObserver(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	srv := NewServer(&mockStation{})
+	go srv.ListenAndServer(addr)
+	defer srv.Close()
+
+	frames := make(chan *Frame, 64)
+	o := NewOption().SetReconnectInterval(50 * time.Millisecond)
+	if err = o.AddRemoteServer(addr); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(&mockClientHandler{}, o).SetObserver(ObserverFunc(func(f *Frame) { frames <- f }))
+	client.SetOnConnectHandler(func(c *Client) { c.SendStartDt() })
+	client.SetOnActiveHandler(func(c *Client) {
+		_ = c.InterrogationCmd(asdu.CauseOfTransmission{Cause: asdu.Activation}, 1, asdu.QOIStation)
+	})
+	if err = client.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	next := func() *Frame {
+		select {
+		case f := <-frames:
+			return f
+		case <-time.After(3 * time.Second):
+			t.Fatal("timeout waiting frame")
+		}
+		return nil
+	}
+	f := next()
+	if f.Direction != DirSent || f.Control != (Control{Type: FrameU, Function: UStartDtActive}) || f.Remote.String() != addr {
+		t.Fatalf("unexpected frame %v %v %v", f.Direction, f.Control, f.Remote)
+	}
+	f = next()
+	if f.Direction != DirReceived || f.Control != (Control{Type: FrameU, Function: UStartDtConfirm}) {
+		t.Fatalf("unexpected frame %v %v", f.Direction, f.Control)
+	}
+	f = next()
+	if f.Direction != DirSent || f.Control != (Control{Type: FrameI}) || f.ASDU == nil ||
+		f.ASDU.Type != asdu.C_IC_NA_1 || f.ASDU.Coa.Cause != asdu.Activation {
+		t.Fatalf("unexpected frame %v %v %v", f.Direction, f.Control, f.ASDU)
+	}
+	f = next()
+	if f.Direction != DirReceived || f.Control.Type != FrameI || f.ASDU == nil || f.ASDU.Coa.Cause != asdu.ActivationCon {
+		t.Fatalf("unexpected frame %v %v %v", f.Direction, f.Control, f.ASDU)
+	}
+}
+
+func TestScanAPDU(t *testing.T) {
+	stream := []byte{
+		0x00, 0x01, // 启动字符前的数据
+		startFrame, 0x04, 0x43, 0x00, 0x00, 0x00,
+		startFrame, 0x02, // 长度错误
+		startFrame, 0x04, 0x01, 0x00, 0x02, 0x00,
+		startFrame, 0x04, 0x83, // 未完成
+	}
+	sc := bufio.NewScanner(bytes.NewReader(stream))
+	sc.Split(ScanAPDU)
+	var got [][]byte
+	for sc.Scan() {
+		got = append(got, append([]byte(nil), sc.Bytes()...))
+	}
+	want := [][]byte{
+		{startFrame, 0x04, 0x43, 0x00, 0x00, 0x00},
+		{startFrame, 0x04, 0x01, 0x00, 0x02, 0x00},
+	}
+	if sc.Err() != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanAPDU() = % x, %v, want % x", got, sc.Err(), want)
+	}
+}
@@ -0,0 +1,77 @@
+//go:build go1.18
+// +build go1.18
+
+package cs104
+
+import (
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+func FuzzParseAPDU(f *testing.F) {
+	seeds := [][]byte{
+		{startFrame, 0x04, 0x02, 0x00, 0x03, 0x00},
+		{startFrame, 0x04, 0x01, 0x00, 0x02, 0x00},
+		{startFrame, 0x04, 0x07, 0x00, 0x00, 0x00},
+		{startFrame, 0x06, 0x02, 0x00, 0x04, 0x00, 0x64, 0x01},
+		{startFrame, 0x04, 0x43, 0x00, 0x00, 0x00},
+		{startFrame, 0x04, 0x43},
+		{0x00, 0x04, 0x43, 0x00, 0x00, 0x00},
+		{startFrame, 0x05, 0x43, 0x00, 0x00, 0x00},
+		{startFrame, 0x0e, 0x00, 0x00, 0x00, 0x00, 0x64, 0x01, 0x06, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14},
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, apdu []byte) {
+		parse(apdu)
+		ctl, asduVal, err := ParseAPDU(apdu)
+		if err != nil {
+			return
+		}
+		var raw []byte
+		switch ctl.Type {
+		case FrameI:
+			if raw, err = newIFrame(ctl.SendSN, ctl.RecvSN, asduVal); err != nil {
+				t.Fatalf("newIFrame() error = %v", err)
+			}
+		case FrameS:
+			raw = newSFrame(ctl.RecvSN)
+		case FrameU:
+			raw = newUFrame(ctl.Function)
+		}
+		if got, _, err := ParseAPDU(raw); err != nil || got != ctl {
+			t.Errorf("ParseAPDU(% x) = %v, %v, want %v", raw, got, err, ctl)
+		}
+		NewFrame(asdu.ParamsWide, DirReceived, time.Time{}, apdu)
+	})
+}
+
+func FuzzScanAPDU(f *testing.F) {
+	f.Add([]byte{
+		0x00, 0x01,
+		startFrame, 0x04, 0x43, 0x00, 0x00, 0x00,
+		startFrame, 0x02,
+		startFrame, 0x04, 0x01, 0x00, 0x02, 0x00,
+		startFrame, 0x04, 0x83,
+	})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for len(data) > 0 {
+			advance, token, err := ScanAPDU(data, true)
+			if err != nil {
+				return
+			}
+			if advance <= 0 || advance > len(data) {
+				t.Fatalf("ScanAPDU() advance = %d, len %d", advance, len(data))
+			}
+			if token != nil {
+				if _, _, err = ParseAPDU(token); err != nil {
+					t.Errorf("ParseAPDU(% x) error = %v", token, err)
+				}
+			}
+			data = data[advance:]
+		}
+	})
+}
@@ -0,0 +1,237 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs104
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/clog"
+)
+
+// Listener 主站侧的监听, 接受子站(如动态IP的GPRS/4G终端)主动拨入的连接,
+// 每个连接以完整的Client语义(STARTDT,召唤,命令,启动流程及周期任务)运行.
+// 子站由注册帧(见ClientOption.SetPreamble的Preamble.Identify)或第一个ASDU的公共地址标识.
+type Listener struct {
+	option  ClientOption
+	handler ClientHandlerInterface
+
+	mux      sync.Mutex
+	listen   net.Listener
+	closed   bool
+	clients  map[string]*Client   // 已识别的子站
+	sessions map[*Client]net.Conn // 所有连接, 包括未识别的
+	wg       sync.WaitGroup
+
+	onAccept         func(c *Client)
+	onConnect        func(id string, c *Client)
+	onConnectionLost func(id string, c *Client)
+	clog.Clog
+}
+
+// NewListener new a master listener, the option is used for every accepted connection,
+// its remote server, reconnect and tls config are ignored. The preamble of the option is run on every
+// accepted connection, the outstation is identified by Preamble.Identify if set.
+func NewListener(handler ClientHandlerInterface, o *ClientOption) *Listener {
+	return &Listener{
+		option:           *o,
+		handler:          handler,
+		clients:          make(map[string]*Client),
+		sessions:         make(map[*Client]net.Conn),
+		onAccept:         func(*Client) {},
+		onConnect:        func(string, *Client) {},
+		onConnectionLost: func(string, *Client) {},
+		Clog:             clog.NewLogger("cs104 listener => "),
+	}
+}
+
+// SetOnAcceptHandler set the handler called with the client of every accepted connection before it runs,
+// used to set metrics, observer, jobs and so on. It should not replace the connect handler of the client.
+func (sf *Listener) SetOnAcceptHandler(f func(c *Client)) *Listener {
+	if f != nil {
+		sf.onAccept = f
+	}
+	return sf
+}
+
+// SetOnConnectHandler set the handler called when the outstation is identified
+func (sf *Listener) SetOnConnectHandler(f func(id string, c *Client)) *Listener {
+	if f != nil {
+		sf.onConnect = f
+	}
+	return sf
+}
+
+// SetConnectionLostHandler set the handler called when the connection of an identified outstation is lost
+func (sf *Listener) SetConnectionLostHandler(f func(id string, c *Client)) *Listener {
+	if f != nil {
+		sf.onConnectionLost = f
+	}
+	return sf
+}
+
+// Client returns the client of the outstation identified by id, nil if not connected
+func (sf *Listener) Client(id string) *Client {
+	sf.mux.Lock()
+	defer sf.mux.Unlock()
+	return sf.clients[id]
+}
+
+// Clients returns the identification of all connected outstations
+func (sf *Listener) Clients() []string {
+	sf.mux.Lock()
+	defer sf.mux.Unlock()
+	ids := make([]string, 0, len(sf.clients))
+	for id := range sf.clients {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// ListenAndServer run the listener
+func (sf *Listener) ListenAndServer(addr string) {
+	listen, err := net.Listen("tcp", addr)
+	if err != nil {
+		sf.Error("listener run failed, %v", err)
+		return
+	}
+	sf.Serve(listen)
+}
+
+// Serve accept connections on the listener l, and close it when returns
+func (sf *Listener) Serve(l net.Listener) {
+	sf.mux.Lock()
+	sf.listen = l
+	sf.closed = false
+	sf.mux.Unlock()
+
+	defer func() {
+		_ = sf.Close()
+		sf.Debug("listener stop")
+	}()
+	sf.Debug("listener run")
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			sf.Error("listener run failed, %v", err)
+			return
+		}
+		sf.wg.Add(1)
+		go func() {
+			defer sf.wg.Done()
+			sf.serve(conn)
+		}()
+	}
+}
+
+// Close close the listener and all connections accepted
+func (sf *Listener) Close() error {
+	var err error
+
+	sf.mux.Lock()
+	if sf.listen != nil {
+		err = sf.listen.Close()
+		sf.listen = nil
+	}
+	sf.closed = true
+	for c, conn := range sf.sessions {
+		_ = c.Close()
+		_ = conn.Close()
+	}
+	sf.mux.Unlock()
+	sf.wg.Wait()
+	return err
+}
+
+// serve 识别子站并在连接上运行Client
+func (sf *Listener) serve(conn net.Conn) {
+	var id atomic.Value
+
+	pc, frameID, err := sf.option.preamble.start(conn, sf.option.config.ConnectTimeout0)
+	if err != nil {
+		sf.Error("preamble %v failed, %v", conn.RemoteAddr(), err)
+		return
+	}
+	conn = pc
+
+	c := NewClient(sf.handler, &sf.option)
+	c.Clog = sf.Clog
+	identified := func(v string) {
+		id.Store(v)
+		sf.mux.Lock()
+		old := sf.clients[v]
+		sf.clients[v] = c
+		sf.mux.Unlock()
+		if old != nil {
+			// 子站重新拨入, 旧连接可能还未超时
+			sf.Warn("outstation %s connected again from %v", v, conn.RemoteAddr())
+			_ = old.Close()
+		}
+		sf.onConnect(v, c)
+	}
+
+	if frameID == "" {
+		c.firstASDU = func(a *asdu.ASDU) {
+			identified(strconv.Itoa(int(a.CommonAddr)))
+		}
+	}
+
+	c.SetOnConnectHandler(func(c *Client) {
+		if frameID != "" {
+			identified(frameID)
+		}
+		if c.option.startup == nil {
+			c.SendStartDt()
+		}
+	})
+
+	sf.mux.Lock()
+	if sf.closed {
+		sf.mux.Unlock()
+		_ = conn.Close()
+		return
+	}
+	sf.sessions[c] = conn
+	sf.mux.Unlock()
+
+	sf.onAccept(c)
+	c.serve(conn)
+
+	sf.mux.Lock()
+	delete(sf.sessions, c)
+	v, ok := id.Load().(string)
+	if ok && sf.clients[v] == c {
+		delete(sf.clients, v)
+	}
+	sf.mux.Unlock()
+	if ok {
+		sf.onConnectionLost(v, c)
+	}
+}
+
+// serve 在已接受的连接上运行, 连接断开或Close后返回, 不重连
+func (sf *Client) serve(conn net.Conn) {
+	var ctx context.Context
+
+	sf.rwMux.Lock()
+	if !atomic.CompareAndSwapUint32(&sf.status, initial, disconnected) {
+		sf.rwMux.Unlock()
+		_ = conn.Close()
+		return
+	}
+	ctx, sf.closeCancel = context.WithCancel(context.Background())
+	sf.rwMux.Unlock()
+	defer sf.setConnectStatus(initial)
+
+	if sf.option.connWrapper != nil {
+		conn = sf.option.connWrapper(conn)
+	}
+	sf.conn = conn
+	sf.run(ctx)
+}
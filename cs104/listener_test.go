@@ -0,0 +1,129 @@
+package cs104
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/clog"
+)
+
+// heartbeatConn send the heartbeat before every APDU
+type heartbeatConn struct {
+	net.Conn
+	heartbeat []byte
+}
+
+func (sf *heartbeatConn) Write(b []byte) (int, error) {
+	if _, err := sf.Conn.Write(sf.heartbeat); err != nil {
+		return 0, err
+	}
+	return sf.Conn.Write(b)
+}
+
+// dialOutstation dial addr, send the identification frame and run an outstation session on the connection,
+// it sends the heartbeat before every APDU if not nil
+func dialOutstation(t *testing.T, addr string, frame, heartbeat []byte) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	if heartbeat != nil {
+		conn = &heartbeatConn{conn, heartbeat}
+	}
+	cfg := DefaultConfig()
+	sess := &SrvSession{
+		config:   &cfg,
+		params:   asdu.ParamsWide,
+		handler:  &mockStation{},
+		conn:     conn,
+		rcvASDU:  make(chan *apduBuf, 64),
+		sendASDU: newSendQueue(DefaultSendPriority(), 64),
+		rcvRaw:   make(chan *apduBuf, 64),
+		sendRaw:  make(chan *apduBuf, 64),
+		onConnection: func(c asdu.Connect) {
+			_ = asdu.EndOfInitialization(c, asdu.CauseOfTransmission{Cause: asdu.Initialized}, 7,
+				asdu.InfoObjAddrIrrelevant, asdu.CauseOfInitial{})
+		},
+		Clog:    clog.NewLogger("cs104 outstation => "),
+		metrics: noopMetrics{},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go sess.run(ctx)
+}
+
+func TestListener(t *testing.T) {
+	// 心跳以0x68开头, 未过滤时破坏帧同步
+	heartbeat := []byte("hb")
+	tests := []struct {
+		name      string
+		preamble  *Preamble
+		frame     []byte
+		heartbeat []byte
+		want      string
+	}{
+		{"common addr", nil, nil, nil, "7"},
+		{"registration frame", &Preamble{Identify: IdentifyByPacket(10)}, []byte("RTU-0001\x00\x00"), nil, "RTU-0001"},
+		{"registration frame and heartbeats", &Preamble{Identify: IdentifyByPacket(10), Heartbeats: [][]byte{heartbeat}},
+			append([]byte("RTU-0002\x00\x00"), heartbeat...), heartbeat, "RTU-0002"},
+		{"common addr and heartbeats", &Preamble{Heartbeats: [][]byte{heartbeat}}, heartbeat, heartbeat, "7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			connected := make(chan string, 1)
+			lost := make(chan string, 1)
+			ln := NewListener(&mockClientHandler{}, NewOption().SetPreamble(tt.preamble)).
+				SetOnConnectHandler(func(id string, c *Client) { connected <- id }).
+				SetConnectionLostHandler(func(id string, c *Client) { lost <- id })
+			go ln.Serve(l)
+
+			dialOutstation(t, l.Addr().String(), tt.frame, tt.heartbeat)
+			select {
+			case id := <-connected:
+				if id != tt.want {
+					t.Fatalf("outstation identified as %q, want %q", id, tt.want)
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatal("timeout waiting outstation identified")
+			}
+			c := ln.Client(tt.want)
+			if c == nil {
+				t.Fatalf("Listener.Client(%q) = nil, clients %v", tt.want, ln.Clients())
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			for !c.IsActive() {
+				if ctx.Err() != nil {
+					t.Fatal("timeout waiting STARTDT con")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if err = c.InterrogationCmd(asdu.CauseOfTransmission{Cause: asdu.Activation}, 7, asdu.QOIStation); err != nil {
+				t.Fatal(err)
+			}
+
+			_ = ln.Close()
+			select {
+			case id := <-lost:
+				if id != tt.want {
+					t.Errorf("lost outstation %q, want %q", id, tt.want)
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatal("timeout waiting connection lost")
+			}
+			if ids := ln.Clients(); len(ids) != 0 {
+				t.Errorf("clients %v after close", ids)
+			}
+		})
+	}
+}
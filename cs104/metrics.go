@@ -0,0 +1,254 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs104
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// FrameType APCI帧的类型
+type FrameType byte
+
+// APCI帧的类型
+const (
+	FrameI FrameType = iota // I帧, 编号的信息传输
+	FrameS                  // S帧, 编号的监视功能
+	FrameU                  // U帧, 未编号的控制功能
+)
+
+func (sf FrameType) String() string {
+	switch sf {
+	case FrameI:
+		return "I"
+	case FrameS:
+		return "S"
+	case FrameU:
+		return "U"
+	}
+	return "unknown"
+}
+
+// Timeout 链路超时的类型
+type Timeout byte
+
+// 链路超时的类型
+const (
+	TimeoutT1 Timeout = iota // 发送或测试APDU的超时, 超时关闭连接
+	TimeoutT2                // 无数据报文时确认的超时, 超时发送S帧
+	TimeoutT3                // 长期空闲状态下发送测试帧的超时, 超时发送TESTFR
+)
+
+func (sf Timeout) String() string {
+	switch sf {
+	case TimeoutT1:
+		return "t1"
+	case TimeoutT2:
+		return "t2"
+	case TimeoutT3:
+		return "t3"
+	}
+	return "unknown"
+}
+
+// Metrics is the interface of the operational metrics of a connection,
+// it is called in the state machine, so it should be safe for concurrent use and return quickly.
+type Metrics interface {
+	// FrameSent I/S/U帧发送
+	FrameSent(FrameType)
+	// FrameReceived I/S/U帧接收
+	FrameReceived(FrameType)
+	// SendWindow 已发送未被确认的I帧数及最大值k
+	SendWindow(unacked, k uint16)
+	// RecvWindow 已接收未确认的I帧数及最大值w
+	RecvWindow(unacked, w uint16)
+	// Timeout t1/t2/t3 超时
+	Timeout(Timeout)
+	// TestFrameRTT TESTFR 激活到确认的往返时间
+	TestFrameRTT(time.Duration)
+	// Reconnect 客户端重连
+	Reconnect()
+	// SendBuffer 发送ASDU缓存的占用及容量, 缓存满时Send返回 ErrBufferFulled, SendContext等待
+	SendBuffer(used, capacity int)
+	// ASDUSent ASDU发送
+	ASDUSent(asdu.TypeID, asdu.Cause)
+	// ASDUReceived ASDU接收
+	ASDUReceived(asdu.TypeID, asdu.Cause)
+}
+
+// noopMetrics 不收集任何指标
+type noopMetrics struct{}
+
+func (noopMetrics) FrameSent(FrameType)                  {}
+func (noopMetrics) FrameReceived(FrameType)              {}
+func (noopMetrics) SendWindow(uint16, uint16)            {}
+func (noopMetrics) RecvWindow(uint16, uint16)            {}
+func (noopMetrics) Timeout(Timeout)                      {}
+func (noopMetrics) TestFrameRTT(time.Duration)           {}
+func (noopMetrics) Reconnect()                           {}
+func (noopMetrics) SendBuffer(int, int)                  {}
+func (noopMetrics) ASDUSent(asdu.TypeID, asdu.Cause)     {}
+func (noopMetrics) ASDUReceived(asdu.TypeID, asdu.Cause) {}
+
+// ASDUKey ASDU计数的标识
+type ASDUKey struct {
+	Type  asdu.TypeID
+	Cause asdu.Cause
+}
+
+// LinkMetrics 一个连接的运行指标, 计数器和仪表值
+type LinkMetrics struct {
+	framesSent     [3]uint64
+	framesReceived [3]uint64
+	sendUnacked    uint32
+	sendK          uint32
+	recvUnacked    uint32
+	recvW          uint32
+	timeouts       [3]uint64
+	testFrRTTSum   int64 // nanosecond
+	testFrRTTCount uint64
+	testFrRTTLast  int64 // nanosecond
+	reconnects     uint64
+	sendBufUsed    int64
+	sendBufCap     int64
+
+	mu           sync.Mutex
+	asduSent     map[ASDUKey]uint64
+	asduReceived map[ASDUKey]uint64
+}
+
+var _ Metrics = (*LinkMetrics)(nil)
+
+// NewLinkMetrics new a link metrics with zero value
+func NewLinkMetrics() *LinkMetrics {
+	return &LinkMetrics{
+		asduSent:     make(map[ASDUKey]uint64),
+		asduReceived: make(map[ASDUKey]uint64),
+	}
+}
+
+// FrameSent imp Metrics
+func (sf *LinkMetrics) FrameSent(t FrameType) {
+	if t <= FrameU {
+		atomic.AddUint64(&sf.framesSent[t], 1)
+	}
+}
+
+// FrameReceived imp Metrics
+func (sf *LinkMetrics) FrameReceived(t FrameType) {
+	if t <= FrameU {
+		atomic.AddUint64(&sf.framesReceived[t], 1)
+	}
+}
+
+// SendWindow imp Metrics
+func (sf *LinkMetrics) SendWindow(unacked, k uint16) {
+	atomic.StoreUint32(&sf.sendUnacked, uint32(unacked))
+	atomic.StoreUint32(&sf.sendK, uint32(k))
+}
+
+// RecvWindow imp Metrics
+func (sf *LinkMetrics) RecvWindow(unacked, w uint16) {
+	atomic.StoreUint32(&sf.recvUnacked, uint32(unacked))
+	atomic.StoreUint32(&sf.recvW, uint32(w))
+}
+
+// Timeout imp Metrics
+func (sf *LinkMetrics) Timeout(t Timeout) {
+	if t <= TimeoutT3 {
+		atomic.AddUint64(&sf.timeouts[t], 1)
+	}
+}
+
+// TestFrameRTT imp Metrics
+func (sf *LinkMetrics) TestFrameRTT(d time.Duration) {
+	atomic.AddInt64(&sf.testFrRTTSum, int64(d))
+	atomic.AddUint64(&sf.testFrRTTCount, 1)
+	atomic.StoreInt64(&sf.testFrRTTLast, int64(d))
+}
+
+// Reconnect imp Metrics
+func (sf *LinkMetrics) Reconnect() {
+	atomic.AddUint64(&sf.reconnects, 1)
+}
+
+// SendBuffer imp Metrics
+func (sf *LinkMetrics) SendBuffer(used, capacity int) {
+	atomic.StoreInt64(&sf.sendBufUsed, int64(used))
+	atomic.StoreInt64(&sf.sendBufCap, int64(capacity))
+}
+
+// ASDUSent imp Metrics
+func (sf *LinkMetrics) ASDUSent(t asdu.TypeID, c asdu.Cause) {
+	sf.mu.Lock()
+	sf.asduSent[ASDUKey{t, c}]++
+	sf.mu.Unlock()
+}
+
+// ASDUReceived imp Metrics
+func (sf *LinkMetrics) ASDUReceived(t asdu.TypeID, c asdu.Cause) {
+	sf.mu.Lock()
+	sf.asduReceived[ASDUKey{t, c}]++
+	sf.mu.Unlock()
+}
+
+// LinkSnapshot 连接运行指标的快照
+type LinkSnapshot struct {
+	FramesSent     map[FrameType]uint64
+	FramesReceived map[FrameType]uint64
+	SendUnacked    uint16
+	SendK          uint16
+	RecvUnacked    uint16
+	RecvW          uint16
+	Timeouts       map[Timeout]uint64
+	TestFrRTTSum   time.Duration
+	TestFrRTTCount uint64
+	TestFrRTTLast  time.Duration
+	Reconnects     uint64
+	SendBufUsed    int
+	SendBufCap     int
+	ASDUSent       map[ASDUKey]uint64
+	ASDUReceived   map[ASDUKey]uint64
+}
+
+// Snapshot returns a copy of the current metrics
+func (sf *LinkMetrics) Snapshot() LinkSnapshot {
+	s := LinkSnapshot{
+		FramesSent:     make(map[FrameType]uint64, 3),
+		FramesReceived: make(map[FrameType]uint64, 3),
+		SendUnacked:    uint16(atomic.LoadUint32(&sf.sendUnacked)),
+		SendK:          uint16(atomic.LoadUint32(&sf.sendK)),
+		RecvUnacked:    uint16(atomic.LoadUint32(&sf.recvUnacked)),
+		RecvW:          uint16(atomic.LoadUint32(&sf.recvW)),
+		Timeouts:       make(map[Timeout]uint64, 3),
+		TestFrRTTSum:   time.Duration(atomic.LoadInt64(&sf.testFrRTTSum)),
+		TestFrRTTCount: atomic.LoadUint64(&sf.testFrRTTCount),
+		TestFrRTTLast:  time.Duration(atomic.LoadInt64(&sf.testFrRTTLast)),
+		Reconnects:     atomic.LoadUint64(&sf.reconnects),
+		SendBufUsed:    int(atomic.LoadInt64(&sf.sendBufUsed)),
+		SendBufCap:     int(atomic.LoadInt64(&sf.sendBufCap)),
+		ASDUSent:       make(map[ASDUKey]uint64),
+		ASDUReceived:   make(map[ASDUKey]uint64),
+	}
+	for t := FrameI; t <= FrameU; t++ {
+		s.FramesSent[t] = atomic.LoadUint64(&sf.framesSent[t])
+		s.FramesReceived[t] = atomic.LoadUint64(&sf.framesReceived[t])
+	}
+	for t := TimeoutT1; t <= TimeoutT3; t++ {
+		s.Timeouts[t] = atomic.LoadUint64(&sf.timeouts[t])
+	}
+	sf.mu.Lock()
+	for k, v := range sf.asduSent {
+		s.ASDUSent[k] = v
+	}
+	for k, v := range sf.asduReceived {
+		s.ASDUReceived[k] = v
+	}
+	sf.mu.Unlock()
+	return s
+}
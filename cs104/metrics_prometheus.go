@@ -0,0 +1,156 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs104
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MetricsRegistry 按连接名称管理运行指标, 并以Prometheus文本格式输出.
+// 例如服务端以远端地址区分会话:
+//
+//	reg := cs104.NewMetricsRegistry()
+//	srv.SetMetrics(func(conn net.Conn) cs104.Metrics {
+//		return reg.Link(conn.RemoteAddr().String())
+//	})
+//	http.Handle("/metrics", reg)
+type MetricsRegistry struct {
+	mu    sync.Mutex
+	links map[string]*LinkMetrics
+}
+
+// NewMetricsRegistry new a registry without any link
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{links: make(map[string]*LinkMetrics)}
+}
+
+// Link returns the metrics of the link with the name, create it if not exist
+func (sf *MetricsRegistry) Link(name string) *LinkMetrics {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	m, ok := sf.links[name]
+	if !ok {
+		m = NewLinkMetrics()
+		sf.links[name] = m
+	}
+	return m
+}
+
+// Remove remove the metrics of the link with the name
+func (sf *MetricsRegistry) Remove(name string) {
+	sf.mu.Lock()
+	delete(sf.links, name)
+	sf.mu.Unlock()
+}
+
+// ServeHTTP imp http.Handler, write metrics in Prometheus text format
+func (sf *MetricsRegistry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = sf.WritePrometheus(w)
+}
+
+type namedSnapshot struct {
+	name string
+	LinkSnapshot
+}
+
+// WritePrometheus write metrics of all links in Prometheus text exposition format
+func (sf *MetricsRegistry) WritePrometheus(w io.Writer) error {
+	sf.mu.Lock()
+	snaps := make([]namedSnapshot, 0, len(sf.links))
+	for name, m := range sf.links {
+		snaps = append(snaps, namedSnapshot{name, m.Snapshot()})
+	}
+	sf.mu.Unlock()
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].name < snaps[j].name })
+
+	bw := bufio.NewWriter(w)
+	family := func(name, typ, help string, sample func(s namedSnapshot)) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		for _, s := range snaps {
+			sample(s)
+		}
+	}
+	link := func(s namedSnapshot) string {
+		return `link="` + escapeLabel(s.name) + `"`
+	}
+
+	family("iec104_frames_sent_total", "counter", "Number of APDU sent by frame type.", func(s namedSnapshot) {
+		for t := FrameI; t <= FrameU; t++ {
+			fmt.Fprintf(bw, "iec104_frames_sent_total{%s,type=\"%s\"} %d\n", link(s), t, s.FramesSent[t])
+		}
+	})
+	family("iec104_frames_received_total", "counter", "Number of APDU received by frame type.", func(s namedSnapshot) {
+		for t := FrameI; t <= FrameU; t++ {
+			fmt.Fprintf(bw, "iec104_frames_received_total{%s,type=\"%s\"} %d\n", link(s), t, s.FramesReceived[t])
+		}
+	})
+	family("iec104_send_unacked", "gauge", "Number of I frames sent but not acknowledged.", func(s namedSnapshot) {
+		fmt.Fprintf(bw, "iec104_send_unacked{%s} %d\n", link(s), s.SendUnacked)
+	})
+	family("iec104_send_window_k", "gauge", "Maximum number of unacknowledged I frames sent(k).", func(s namedSnapshot) {
+		fmt.Fprintf(bw, "iec104_send_window_k{%s} %d\n", link(s), s.SendK)
+	})
+	family("iec104_recv_unacked", "gauge", "Number of I frames received but not acknowledged.", func(s namedSnapshot) {
+		fmt.Fprintf(bw, "iec104_recv_unacked{%s} %d\n", link(s), s.RecvUnacked)
+	})
+	family("iec104_recv_window_w", "gauge", "Latest acknowledge after receiving w I frames.", func(s namedSnapshot) {
+		fmt.Fprintf(bw, "iec104_recv_window_w{%s} %d\n", link(s), s.RecvW)
+	})
+	family("iec104_timeouts_total", "counter", "Number of t1/t2/t3 timeout events.", func(s namedSnapshot) {
+		for t := TimeoutT1; t <= TimeoutT3; t++ {
+			fmt.Fprintf(bw, "iec104_timeouts_total{%s,timer=\"%s\"} %d\n", link(s), t, s.Timeouts[t])
+		}
+	})
+	family("iec104_testfr_rtt_seconds", "summary", "Round-trip time of TESTFR act to con.", func(s namedSnapshot) {
+		fmt.Fprintf(bw, "iec104_testfr_rtt_seconds_sum{%s} %g\n", link(s), s.TestFrRTTSum.Seconds())
+		fmt.Fprintf(bw, "iec104_testfr_rtt_seconds_count{%s} %d\n", link(s), s.TestFrRTTCount)
+	})
+	family("iec104_reconnects_total", "counter", "Number of client reconnections.", func(s namedSnapshot) {
+		fmt.Fprintf(bw, "iec104_reconnects_total{%s} %d\n", link(s), s.Reconnects)
+	})
+	family("iec104_send_buffer_used", "gauge", "Number of ASDU waiting in the send buffer.", func(s namedSnapshot) {
+		fmt.Fprintf(bw, "iec104_send_buffer_used{%s} %d\n", link(s), s.SendBufUsed)
+	})
+	family("iec104_send_buffer_capacity", "gauge", "Capacity of the send buffer.", func(s namedSnapshot) {
+		fmt.Fprintf(bw, "iec104_send_buffer_capacity{%s} %d\n", link(s), s.SendBufCap)
+	})
+	family("iec104_asdu_sent_total", "counter", "Number of ASDU sent by type and cause.", func(s namedSnapshot) {
+		writeASDU(bw, "iec104_asdu_sent_total", link(s), s.ASDUSent)
+	})
+	family("iec104_asdu_received_total", "counter", "Number of ASDU received by type and cause.", func(s namedSnapshot) {
+		writeASDU(bw, "iec104_asdu_received_total", link(s), s.ASDUReceived)
+	})
+	return bw.Flush()
+}
+
+func writeASDU(w io.Writer, name, link string, m map[ASDUKey]uint64) {
+	keys := make([]ASDUKey, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Type != keys[j].Type {
+			return keys[i].Type < keys[j].Type
+		}
+		return keys[i].Cause < keys[j].Cause
+	})
+	for _, k := range keys {
+		typ := strings.TrimSuffix(strings.TrimPrefix(k.Type.String(), "TID<"), ">")
+		fmt.Fprintf(w, "%s{%s,type=\"%s\",cause=\"%s\"} %d\n", name, link, typ, k.Cause, m[k])
+	}
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelReplacer.Replace(s)
+}
@@ -0,0 +1,130 @@
+package cs104
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+func TestMetricsRegistry_WritePrometheus(t *testing.T) {
+	reg := NewMetricsRegistry()
+	m := reg.Link(`10.0.0.1:2404"`)
+	m.FrameSent(FrameI)
+	m.FrameSent(FrameI)
+	m.FrameReceived(FrameS)
+	m.SendWindow(3, 12)
+	m.RecvWindow(1, 8)
+	m.Timeout(TimeoutT3)
+	m.TestFrameRTT(500 * time.Millisecond)
+	m.Reconnect()
+	m.SendBuffer(2, 192)
+	m.ASDUSent(asdu.M_SP_NA_1, asdu.Spontaneous)
+	m.ASDUReceived(asdu.C_IC_NA_1, asdu.Activation)
+
+	var buf bytes.Buffer
+	if err := reg.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"# TYPE iec104_frames_sent_total counter\n",
+		`iec104_frames_sent_total{link="10.0.0.1:2404\"",type="I"} 2` + "\n",
+		`iec104_frames_received_total{link="10.0.0.1:2404\"",type="S"} 1` + "\n",
+		`iec104_send_unacked{link="10.0.0.1:2404\""} 3` + "\n",
+		`iec104_send_window_k{link="10.0.0.1:2404\""} 12` + "\n",
+		`iec104_recv_unacked{link="10.0.0.1:2404\""} 1` + "\n",
+		`iec104_timeouts_total{link="10.0.0.1:2404\"",timer="t3"} 1` + "\n",
+		`iec104_testfr_rtt_seconds_sum{link="10.0.0.1:2404\""} 0.5` + "\n",
+		`iec104_testfr_rtt_seconds_count{link="10.0.0.1:2404\""} 1` + "\n",
+		`iec104_reconnects_total{link="10.0.0.1:2404\""} 1` + "\n",
+		`iec104_send_buffer_used{link="10.0.0.1:2404\""} 2` + "\n",
+		`iec104_asdu_sent_total{link="10.0.0.1:2404\"",type="M_SP_NA_1",cause="Spontaneous"} 1` + "\n",
+		`iec104_asdu_received_total{link="10.0.0.1:2404\"",type="C_IC_NA_1",cause="Activation"} 1` + "\n",
+	}
+	for _, w := range want {
+		if !strings.Contains(buf.String(), w) {
+			t.Errorf("WritePrometheus() missing %q", w)
+		}
+	}
+}
+
+func TestMetrics_Link(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	reg := NewMetricsRegistry()
+	srv := NewServer(&mockStation{})
+	srv.SetMetrics(func(net.Conn) Metrics { return reg.Link("server") })
+	go srv.ListenAndServer(addr)
+	defer srv.Close()
+
+	active := make(chan struct{})
+	o := NewOption().SetReconnectInterval(50 * time.Millisecond)
+	if err = o.AddRemoteServer(addr); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(&mockClientHandler{}, o).SetMetrics(reg.Link("client"))
+	client.SetOnConnectHandler(func(c *Client) { c.SendStartDt() })
+	client.SetOnActiveHandler(func(*Client) { close(active) })
+	if err = client.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	select {
+	case <-active:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting STARTDT confirm")
+	}
+	if err = client.InterrogationCmd(asdu.CauseOfTransmission{Cause: asdu.Activation}, 1, asdu.QOIStation); err != nil {
+		t.Fatal(err)
+	}
+
+	key := ASDUKey{asdu.C_IC_NA_1, asdu.ActivationTerm}
+	deadline := time.Now().Add(3 * time.Second)
+	for reg.Link("client").Snapshot().ASDUReceived[key] == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting ActTerm")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cs := reg.Link("client").Snapshot()
+	if cs.FramesSent[FrameU] != 1 || cs.FramesReceived[FrameU] != 1 || cs.FramesSent[FrameI] != 1 {
+		t.Errorf("client frames sent %v received %v", cs.FramesSent, cs.FramesReceived)
+	}
+	if cs.ASDUSent[ASDUKey{asdu.C_IC_NA_1, asdu.Activation}] != 1 || cs.SendBufCap == 0 {
+		t.Errorf("client asdu sent %v, send buffer capacity %d", cs.ASDUSent, cs.SendBufCap)
+	}
+	ss := reg.Link("server").Snapshot()
+	if ss.ASDUReceived[ASDUKey{asdu.C_IC_NA_1, asdu.Activation}] != 1 || ss.FramesSent[FrameI] != 2 {
+		t.Errorf("server asdu received %v, frames sent %v", ss.ASDUReceived, ss.FramesSent)
+	}
+	// ASDU计数在I帧发送时, 与发送的I帧数一致
+	var sent uint64
+	for _, v := range ss.ASDUSent {
+		sent += v
+	}
+	if sent != ss.FramesSent[FrameI] {
+		t.Errorf("server asdu sent %v, I frames sent %d", ss.ASDUSent, ss.FramesSent[FrameI])
+	}
+}
+
+// mockClientHandler ignore all asdu
+type mockClientHandler struct{}
+
+func (mockClientHandler) InterrogationHandler(asdu.Connect, *asdu.ASDU) error        { return nil }
+func (mockClientHandler) CounterInterrogationHandler(asdu.Connect, *asdu.ASDU) error { return nil }
+func (mockClientHandler) ReadHandler(asdu.Connect, *asdu.ASDU) error                 { return nil }
+func (mockClientHandler) TestCommandHandler(asdu.Connect, *asdu.ASDU) error          { return nil }
+func (mockClientHandler) ClockSyncHandler(asdu.Connect, *asdu.ASDU) error            { return nil }
+func (mockClientHandler) ResetProcessHandler(asdu.Connect, *asdu.ASDU) error         { return nil }
+func (mockClientHandler) DelayAcquisitionHandler(asdu.Connect, *asdu.ASDU) error     { return nil }
+func (mockClientHandler) ASDUHandler(asdu.Connect, *asdu.ASDU) error                 { return nil }
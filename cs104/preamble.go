@@ -0,0 +1,139 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs104
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"time"
+)
+
+// IdentifyFunc 从连接建立后对端发送的注册帧(104报文之前)中读取对端的标识
+type IdentifyFunc func(r io.Reader) (id string, err error)
+
+// IdentifyByPacket 定长size字节的注册帧, 去掉末尾的0和空白后作为标识
+func IdentifyByPacket(size int) IdentifyFunc {
+	return func(r io.Reader) (string, error) {
+		b := make([]byte, size)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", err
+		}
+		return string(bytes.TrimRight(b, "\x00\r\n\t ")), nil
+	}
+}
+
+// Preamble DTU(如GPRS/4G透传模块)风格连接的注册帧及心跳处理
+type Preamble struct {
+	// Register 连接建立后在104报文之前发送的注册帧, 如设备编号, nil为不发送
+	Register []byte
+	// Identify 连接建立后在104报文之前读取对端的注册帧, nil为不读取
+	Identify IdentifyFunc
+	// Heartbeats 对端在104报文之间发送的心跳报文, 接收时在帧之前过滤掉
+	Heartbeats [][]byte
+}
+
+// start 连接建立后发送及读取注册帧, 返回过滤心跳的连接及对端的标识, nil时返回原连接
+func (sf *Preamble) start(conn net.Conn, timeout time.Duration) (net.Conn, string, error) {
+	if sf == nil {
+		return conn, "", nil
+	}
+	var id string
+
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	if len(sf.Register) > 0 {
+		if _, err := conn.Write(sf.Register); err != nil {
+			_ = conn.Close()
+			return nil, "", err
+		}
+	}
+	r := bufio.NewReaderSize(conn, APDUSizeMax*4)
+	if sf.Identify != nil {
+		var err error
+		if id, err = sf.Identify(r); err == nil && id == "" {
+			err = errors.New("empty identification")
+		}
+		if err != nil {
+			_ = conn.Close()
+			return nil, "", err
+		}
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return newPreambleConn(conn, r, sf.Heartbeats), id, nil
+}
+
+// preambleConn 从注册帧后的缓冲读取, 并过滤在帧之间出现的心跳报文
+type preambleConn struct {
+	net.Conn
+	r          *bufio.Reader
+	heartbeats [][]byte
+	remain     int // 当前帧剩余未读的字节数
+}
+
+func newPreambleConn(conn net.Conn, r *bufio.Reader, heartbeats [][]byte) *preambleConn {
+	sf := &preambleConn{Conn: conn, r: r}
+	for _, hb := range heartbeats {
+		if len(hb) > 0 {
+			sf.heartbeats = append(sf.heartbeats, hb)
+		}
+	}
+	// 较长的优先匹配
+	sort.SliceStable(sf.heartbeats, func(i, j int) bool { return len(sf.heartbeats[i]) > len(sf.heartbeats[j]) })
+	return sf
+}
+
+// Read imp net.Conn, 只返回当前帧内的数据, 帧之前的心跳报文被丢弃
+func (sf *preambleConn) Read(b []byte) (int, error) {
+	if len(sf.heartbeats) == 0 {
+		return sf.r.Read(b)
+	}
+	for sf.remain == 0 {
+		head, err := sf.r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if hb := sf.heartbeat(); hb > 0 {
+			_, _ = sf.r.Discard(hb)
+			continue
+		}
+		if head[0] != startFrame {
+			sf.remain = 1 // 非帧数据交给recvLoop重新同步
+			break
+		}
+		head, err = sf.r.Peek(2)
+		if err != nil {
+			return 0, err
+		}
+		sf.remain = int(head[1]) + 2
+	}
+	if len(b) > sf.remain {
+		b = b[:sf.remain]
+	}
+	n, err := sf.r.Read(b)
+	sf.remain -= n
+	return n, err
+}
+
+// heartbeat 缓冲开头的心跳报文长度, 不是心跳时返回0
+func (sf *preambleConn) heartbeat() int {
+	for _, hb := range sf.heartbeats {
+		n := sf.r.Buffered()
+		if n > len(hb) {
+			n = len(hb)
+		}
+		buf, _ := sf.r.Peek(n)
+		if !bytes.Equal(buf, hb[:n]) {
+			continue
+		}
+		// 已缓冲的部分匹配, 等待剩余部分
+		if buf, err := sf.r.Peek(len(hb)); err == nil && bytes.Equal(buf, hb) {
+			return len(hb)
+		}
+	}
+	return 0
+}
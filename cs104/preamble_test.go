@@ -0,0 +1,95 @@
+package cs104
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+func TestPreambleConn_Read(t *testing.T) {
+	hb := []byte{0xfe, 0x68}
+	startDt := newUFrame(uStartDtActive)
+	sFrame := newSFrame(1)
+	iFrame, _ := newIFrame(0, 0, []byte{0x0d, 0x01, 0x03, 0x00, 0x01, 0x00, 0xfe, 0x68, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+	join := func(bs ...[]byte) []byte { return bytes.Join(bs, nil) }
+	tests := []struct {
+		name       string
+		heartbeats [][]byte
+		stream     []byte
+		want       []byte
+	}{
+		{"no heartbeats", nil, join(hb, startDt), join(hb, startDt)},
+		{"between frames", [][]byte{hb}, join(hb, startDt, hb, hb, sFrame, hb), join(startDt, sFrame)},
+		{"inside frame", [][]byte{hb}, join(iFrame, hb), iFrame},
+		{"longest first", [][]byte{{0xfe}, {0xfe, 0x68, 0x00}}, join([]byte{0xfe, 0x68, 0x00}, startDt, []byte{0xfe}, sFrame),
+			join(startDt, sFrame)},
+		{"junk", [][]byte{hb}, join([]byte{0x01}, startDt), join([]byte{0x01}, startDt)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newPreambleConn(nil, bufio.NewReader(bytes.NewReader(tt.stream)), tt.heartbeats)
+			got, err := ioutil.ReadAll(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("preambleConn.Read() = [% x], want [% x]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_SetPreamble(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	identity := make(chan string, 1)
+	srv := NewServer(&mockStation{})
+	srv.SetPreamble(&Preamble{Identify: IdentifyByPacket(8), Heartbeats: [][]byte{[]byte("HB")}})
+	srv.SetOnConnectionHandler(func(c asdu.Connect) { identity <- c.(*SrvSession).Identity() })
+	go srv.ListenAndServer(addr)
+	defer srv.Close()
+
+	var conn net.Conn
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if conn, err = net.Dial("tcp", addr); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+	}
+	defer conn.Close()
+	stream := bytes.Join([][]byte{[]byte("DTU00001"), []byte("HB"), newUFrame(uStartDtActive),
+		[]byte("HB"), newUFrame(uTestFrActive), []byte("HB")}, nil)
+	if _, err = conn.Write(stream); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case id := <-identity:
+		if id != "DTU00001" {
+			t.Errorf("SrvSession.Identity() = %q", id)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting connection")
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	got := make([]byte, 12)
+	if _, err = io.ReadFull(conn, got); err != nil {
+		t.Fatal(err)
+	}
+	if want := append(newUFrame(uStartDtConfirm), newUFrame(uTestFrConfirm)...); !reflect.DeepEqual(got, want) {
+		t.Errorf("server replied [% x], want [% x]", got, want)
+	}
+}
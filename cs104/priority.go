@@ -0,0 +1,174 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs104
+
+import (
+	"context"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// Priority 发送优先级, 值越小越优先
+type Priority int
+
+// 发送优先级
+const (
+	PriorityHigh   Priority = iota // 命令及其确认, 继电保护事件
+	PriorityNormal                 // 突发事件
+	PriorityLow                    // 周期,背景扫描,召唤应答及文件传输
+	priorityCount
+)
+
+// PriorityRule 发送优先级规则, Type或Cause为0时匹配任意值
+type PriorityRule struct {
+	Type     asdu.TypeID
+	Cause    asdu.Cause
+	Priority Priority
+}
+
+// SendPriority 发送队列的优先级配置.
+// k窗口打开时先发送高优先级的ASDU, 同一优先级内先进先出.
+type SendPriority struct {
+	// 按顺序匹配, 使用第一条匹配的规则, 都不匹配时为PriorityNormal
+	Rules []PriorityRule
+	// 有较低优先级等待时, 该优先级最多连续发送的帧数, 之后让出一帧, 0为严格优先
+	Weights [priorityCount]int
+}
+
+// DefaultSendPriority 默认的发送优先级.
+// 召唤的激活终止与召唤应答同为低优先级, 保证激活终止在召唤应答之后发送.
+func DefaultSendPriority() SendPriority {
+	rules := []PriorityRule{
+		{Type: asdu.M_EP_TA_1, Priority: PriorityHigh},
+		{Type: asdu.M_EP_TB_1, Priority: PriorityHigh},
+		{Type: asdu.M_EP_TC_1, Priority: PriorityHigh},
+		{Type: asdu.M_EP_TD_1, Priority: PriorityHigh},
+		{Type: asdu.M_EP_TE_1, Priority: PriorityHigh},
+		{Type: asdu.M_EP_TF_1, Priority: PriorityHigh},
+		{Cause: asdu.Activation, Priority: PriorityHigh},
+		{Cause: asdu.ActivationCon, Priority: PriorityHigh},
+		{Cause: asdu.Deactivation, Priority: PriorityHigh},
+		{Cause: asdu.DeactivationCon, Priority: PriorityHigh},
+		{Cause: asdu.UnknownTypeID, Priority: PriorityHigh},
+		{Cause: asdu.UnknownCOT, Priority: PriorityHigh},
+		{Cause: asdu.UnknownCA, Priority: PriorityHigh},
+		{Cause: asdu.UnknownIOA, Priority: PriorityHigh},
+		{Cause: asdu.Periodic, Priority: PriorityLow},
+		{Cause: asdu.Background, Priority: PriorityLow},
+		{Cause: asdu.ActivationTerm, Priority: PriorityLow},
+		{Cause: asdu.FileTransfer, Priority: PriorityLow},
+	}
+	for c := asdu.InterrogatedByStation; c <= asdu.RequestByGroup4Counter; c++ {
+		rules = append(rules, PriorityRule{Cause: c, Priority: PriorityLow})
+	}
+	return SendPriority{Rules: rules, Weights: [priorityCount]int{0, 8, 0}}
+}
+
+// classify 获取asdu的发送优先级
+func (sf *SendPriority) classify(a *asdu.ASDU) Priority {
+	for _, r := range sf.Rules {
+		if (r.Type == 0 || r.Type == a.Type) && (r.Cause == 0 || r.Cause == a.Coa.Cause) {
+			if r.Priority < PriorityHigh || r.Priority >= priorityCount {
+				return PriorityNormal
+			}
+			return r.Priority
+		}
+	}
+	return PriorityNormal
+}
+
+// sendQueue 按优先级分类的发送队列, push可并发调用, pop只在run中调用
+type sendQueue struct {
+	priority SendPriority
+	classes  [priorityCount]chan *apduBuf
+	sent     [priorityCount]int // 有较低优先级等待时连续发送的帧数
+}
+
+func newSendQueue(p SendPriority, size int) *sendQueue {
+	sf := &sendQueue{priority: p}
+	for i := range sf.classes {
+		sf.classes[i] = make(chan *apduBuf, size)
+	}
+	return sf
+}
+
+// push 按asdu的优先级入队, 队列满时返回false
+func (sf *sendQueue) push(a *asdu.ASDU, b *apduBuf) bool {
+	select {
+	case sf.classes[sf.priority.classify(a)] <- b:
+		return true
+	default:
+		return false
+	}
+}
+
+// pushContext 按asdu的优先级入队, 队列满时等待直到有空间, closed关闭或ctx结束
+func (sf *sendQueue) pushContext(ctx context.Context, closed <-chan struct{}, a *asdu.ASDU, b *apduBuf) error {
+	select {
+	case sf.classes[sf.priority.classify(a)] <- b:
+		return nil
+	case <-closed:
+		return ErrUseClosedConnection
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pop 取出下一个要发送的缓冲, 无数据时返回nil
+func (sf *sendQueue) pop() *apduBuf {
+	for p := range sf.classes {
+		if len(sf.classes[p]) == 0 {
+			sf.sent[p] = 0
+			continue
+		}
+		if w := sf.priority.Weights[p]; w > 0 && sf.sent[p] >= w && sf.lowerWaiting(p) {
+			sf.sent[p] = 0
+			continue
+		}
+		select {
+		case b := <-sf.classes[p]:
+			if sf.lowerWaiting(p) {
+				sf.sent[p]++
+			}
+			return b
+		default:
+		}
+	}
+	return nil
+}
+
+func (sf *sendQueue) lowerWaiting(p int) bool {
+	for i := p + 1; i < len(sf.classes); i++ {
+		if len(sf.classes[i]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// len 队列中的帧数
+func (sf *sendQueue) len() int {
+	n := 0
+	for _, c := range sf.classes {
+		n += len(c)
+	}
+	return n
+}
+
+// cap 队列的容量
+func (sf *sendQueue) cap() int {
+	n := 0
+	for _, c := range sf.classes {
+		n += cap(c)
+	}
+	return n
+}
+
+// drain 清空队列并归还缓冲
+func (sf *sendQueue) drain() {
+	for _, c := range sf.classes {
+		drainBuf(c)
+	}
+}
@@ -0,0 +1,88 @@
+package cs104
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+func newPriorityASDU(typeID asdu.TypeID, cause asdu.Cause) *asdu.ASDU {
+	return asdu.NewASDU(asdu.ParamsWide, asdu.Identifier{Type: typeID, Coa: asdu.CauseOfTransmission{Cause: cause}})
+}
+
+func TestSendPriority_classify(t *testing.T) {
+	def := DefaultSendPriority()
+	custom := SendPriority{Rules: []PriorityRule{
+		{Type: asdu.M_ME_NC_1, Cause: asdu.Spontaneous, Priority: PriorityLow},
+		{Type: asdu.M_ME_NC_1, Priority: PriorityHigh},
+		{Cause: asdu.Request, Priority: 10},
+	}}
+	tests := []struct {
+		name     string
+		priority SendPriority
+		asdu     *asdu.ASDU
+		want     Priority
+	}{
+		{"protection event", def, newPriorityASDU(asdu.M_EP_TD_1, asdu.Spontaneous), PriorityHigh},
+		{"command confirmation", def, newPriorityASDU(asdu.C_SC_NA_1, asdu.ActivationCon), PriorityHigh},
+		{"unknown ioa", def, newPriorityASDU(asdu.C_SC_NA_1, asdu.UnknownIOA), PriorityHigh},
+		{"spontaneous", def, newPriorityASDU(asdu.M_SP_NA_1, asdu.Spontaneous), PriorityNormal},
+		{"periodic", def, newPriorityASDU(asdu.M_ME_NC_1, asdu.Periodic), PriorityLow},
+		{"interrogated", def, newPriorityASDU(asdu.M_SP_NA_1, asdu.InterrogatedByGroup3), PriorityLow},
+		{"interrogation termination", def, newPriorityASDU(asdu.C_IC_NA_1, asdu.ActivationTerm), PriorityLow},
+		{"type and cause", custom, newPriorityASDU(asdu.M_ME_NC_1, asdu.Spontaneous), PriorityLow},
+		{"type", custom, newPriorityASDU(asdu.M_ME_NC_1, asdu.Periodic), PriorityHigh},
+		{"invalid priority", custom, newPriorityASDU(asdu.M_SP_NA_1, asdu.Request), PriorityNormal},
+		{"no rules", SendPriority{}, newPriorityASDU(asdu.M_EP_TD_1, asdu.Spontaneous), PriorityNormal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.priority.classify(tt.asdu); got != tt.want {
+				t.Errorf("SendPriority.classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendQueue_pop(t *testing.T) {
+	high := newPriorityASDU(asdu.M_EP_TD_1, asdu.Spontaneous)
+	normal := newPriorityASDU(asdu.M_SP_NA_1, asdu.Spontaneous)
+	low := newPriorityASDU(asdu.M_ME_NC_1, asdu.Periodic)
+	tests := []struct {
+		name    string
+		weights [priorityCount]int
+		push    []*asdu.ASDU
+		want    []string
+	}{
+		{"fifo", [priorityCount]int{}, []*asdu.ASDU{normal, normal, normal}, []string{"N0", "N1", "N2"}},
+		{"strict", [priorityCount]int{}, []*asdu.ASDU{low, low, normal, high, normal},
+			[]string{"H3", "N2", "N4", "L0", "L1"}},
+		{"weighted", [priorityCount]int{0, 2, 0}, []*asdu.ASDU{low, low, normal, normal, normal, normal, normal, high},
+			[]string{"H7", "N2", "N3", "L0", "N4", "N5", "L1", "N6"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := DefaultSendPriority()
+			p.Weights = tt.weights
+			q := newSendQueue(p, 8)
+			for i, a := range tt.push {
+				b := getBuf()
+				b.n = i
+				if !q.push(a, b) {
+					t.Fatal("push failed")
+				}
+			}
+			if q.len() != len(tt.push) || q.cap() != 8*int(priorityCount) {
+				t.Errorf("sendQueue len = %d, cap = %d", q.len(), q.cap())
+			}
+			var got []string
+			for b := q.pop(); b != nil; b = q.pop() {
+				got = append(got, string("HNL"[p.classify(tt.push[b.n])])+string(rune('0'+b.n)))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sendQueue.pop() order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs104
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// ReconnectPolicy 连接失败后的重连策略
+type ReconnectPolicy interface {
+	// Next 返回第attempt(从1开始)次连续连接失败后到下一次连接的等待时间, ok为false时停止重连
+	Next(attempt int) (wait time.Duration, ok bool)
+}
+
+// ReconnectPolicyFunc 函数实现的ReconnectPolicy
+type ReconnectPolicyFunc func(attempt int) (time.Duration, bool)
+
+// Next imp interface ReconnectPolicy
+func (sf ReconnectPolicyFunc) Next(attempt int) (time.Duration, bool) {
+	return sf(attempt)
+}
+
+// ConstantReconnect 固定间隔重连
+func ConstantReconnect(interval time.Duration) ReconnectPolicy {
+	return ReconnectPolicyFunc(func(int) (time.Duration, bool) {
+		return interval, true
+	})
+}
+
+// ExponentialReconnect 指数退避重连, 从initial开始每次加倍, 最大为max
+func ExponentialReconnect(initial, max time.Duration) ReconnectPolicy {
+	return ReconnectPolicyFunc(func(attempt int) (time.Duration, bool) {
+		wait := initial
+		for i := 1; i < attempt && wait < max; i++ {
+			wait *= 2
+		}
+		if wait > max {
+			wait = max
+		}
+		return wait, true
+	})
+}
+
+// JitterReconnect 在p的等待时间上随机增减最多factor(0~1)的比例, 避免多个客户端同时重连
+func JitterReconnect(p ReconnectPolicy, factor float64) ReconnectPolicy {
+	return ReconnectPolicyFunc(func(attempt int) (time.Duration, bool) {
+		wait, ok := p.Next(attempt)
+		if ok && factor > 0 {
+			wait += time.Duration((rand.Float64()*2 - 1) * factor * float64(wait))
+		}
+		return wait, ok
+	})
+}
+
+// LimitReconnect 最多连续失败attempts次, 之后停止重连
+func LimitReconnect(p ReconnectPolicy, attempts int) ReconnectPolicy {
+	return ReconnectPolicyFunc(func(attempt int) (time.Duration, bool) {
+		if attempt >= attempts {
+			return 0, false
+		}
+		return p.Next(attempt)
+	})
+}
+
+// sleepContext 等待d, ctx结束时提前返回false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
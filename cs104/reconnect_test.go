@@ -0,0 +1,156 @@
+package cs104
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReconnectPolicy(t *testing.T) {
+	exp := ExponentialReconnect(time.Second, 10*time.Second)
+	tests := []struct {
+		name     string
+		policy   ReconnectPolicy
+		attempt  int
+		wantWait time.Duration
+		wantOk   bool
+	}{
+		{"constant", ConstantReconnect(time.Second), 5, time.Second, true},
+		{"exponential first", exp, 1, time.Second, true},
+		{"exponential third", exp, 3, 4 * time.Second, true},
+		{"exponential cap", exp, 10, 10 * time.Second, true},
+		{"limit", LimitReconnect(exp, 3), 2, 2 * time.Second, true},
+		{"limit reached", LimitReconnect(exp, 3), 3, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, ok := tt.policy.Next(tt.attempt)
+			if wait != tt.wantWait || ok != tt.wantOk {
+				t.Errorf("Next() = %v, %v, want %v, %v", wait, ok, tt.wantWait, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestJitterReconnect(t *testing.T) {
+	p := JitterReconnect(ConstantReconnect(time.Second), 0.5)
+	for i := 1; i < 100; i++ {
+		if wait, ok := p.Next(i); !ok || wait < 500*time.Millisecond || wait > 1500*time.Millisecond {
+			t.Fatalf("Next() = %v, %v", wait, ok)
+		}
+	}
+}
+
+func TestClient_CloseWhileReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	var attempts int32
+	o := NewOption().SetReconnectPolicy(ExponentialReconnect(time.Hour, time.Hour)).
+		SetReconnectHandler(func(attempt int, wait time.Duration, err error) {
+			if err == nil || wait != time.Hour {
+				t.Errorf("reconnect handler attempt %d, wait %v, error %v", attempt, wait, err)
+			}
+			atomic.StoreInt32(&attempts, int32(attempt))
+		})
+	if err = o.AddRemoteServer(addr); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(&mockClientHandler{}, o)
+	if err = client.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(3 * time.Second); atomic.LoadInt32(&attempts) != 1; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting connect failed")
+		}
+	}
+	_ = client.Close()
+	for deadline := time.Now().Add(time.Second); client.connectStatus() != initial; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("running not stopped after Close")
+		}
+	}
+}
+
+func TestClient_ReconnectAfterConnectionLost(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	// 接受连接后立即断开
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	type event struct {
+		attempt int
+		wait    time.Duration
+		err     error
+	}
+	events := make(chan event, 16)
+	o := NewOption().SetReconnectPolicy(ConstantReconnect(20 * time.Millisecond)).
+		SetReconnectHandler(func(attempt int, wait time.Duration, err error) {
+			select {
+			case events <- event{attempt, wait, err}:
+			default:
+			}
+		})
+	if err = o.AddRemoteServer(l.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(&mockClientHandler{}, o)
+	if err = client.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// 每次连接建立后重新计数, 断开使用重连策略的等待时间
+	for i := 0; i < 3; i++ {
+		select {
+		case e := <-events:
+			if e.attempt != 1 || e.wait != 20*time.Millisecond || e.err != ErrRemoteClosed {
+				t.Fatalf("reconnect handler attempt %d, wait %v, error %v", e.attempt, e.wait, e.err)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("timeout waiting connection lost")
+		}
+	}
+}
+
+func TestClientOption_reconnectWait(t *testing.T) {
+	tests := []struct {
+		name   string
+		o      *ClientOption
+		lost   bool
+		min    time.Duration
+		max    time.Duration
+		wantOk bool
+	}{
+		{"dial failed", NewOption().SetReconnectInterval(time.Second), false, time.Second, time.Second, true},
+		{"dial failed without auto reconnect", NewOption().SetAutoReconnect(false), false, -1, -1, false},
+		{"lost", NewOption(), true, 500 * time.Millisecond, time.Second, true},
+		{"lost without auto reconnect", NewOption().SetAutoReconnect(false), true, 500 * time.Millisecond, time.Second, true},
+		{"lost with policy", NewOption().SetReconnectPolicy(ConstantReconnect(time.Minute)), true, time.Minute, time.Minute, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, ok := tt.o.reconnectWait(1, ErrRemoteClosed, tt.lost)
+			if ok != tt.wantOk || wait < tt.min || wait > tt.max {
+				t.Errorf("reconnectWait() = %v, %v, want [%v, %v], %v", wait, ok, tt.min, tt.max, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs104
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// Router 按公共地址(CommonAddr)分发ASDU到各个站的处理者,用于一个服务端承载多个逻辑站.
+// 未注册的公共地址回复 <46> 未知的应用服务数据单元公共地址.
+// 全局地址(GlobalCommonAddr)的总召唤,计数量召唤,时钟同步和复位进程命令
+// 依次分发到所有的站,每个站以自己的公共地址回复激活确认和激活终止,
+// 站处理失败时回复该站的否定激活确认.
+// See companion standard 101, subclass 7.2.4.
+type Router struct {
+	rwMux    sync.RWMutex
+	stations map[asdu.CommonAddr]ServerHandlerInterface
+}
+
+var _ ServerHandlerInterface = (*Router)(nil)
+
+// NewRouter new a router without any station
+func NewRouter() *Router {
+	return &Router{
+		stations: make(map[asdu.CommonAddr]ServerHandlerInterface),
+	}
+}
+
+// Handle register the handler of the station with common address ca, it will replace the previous one.
+func (sf *Router) Handle(ca asdu.CommonAddr, handler ServerHandlerInterface) error {
+	if ca == asdu.InvalidCommonAddr || ca == asdu.GlobalCommonAddr {
+		return errors.New("invalid station common address")
+	}
+	if handler == nil {
+		return errors.New("nil station handler")
+	}
+	sf.rwMux.Lock()
+	sf.stations[ca] = handler
+	sf.rwMux.Unlock()
+	return nil
+}
+
+// Remove unregister the station with common address ca
+func (sf *Router) Remove(ca asdu.CommonAddr) {
+	sf.rwMux.Lock()
+	delete(sf.stations, ca)
+	sf.rwMux.Unlock()
+}
+
+// CommonAddrs returns the common address of all registered stations in ascending order
+func (sf *Router) CommonAddrs() []asdu.CommonAddr {
+	sf.rwMux.RLock()
+	cas := make([]asdu.CommonAddr, 0, len(sf.stations))
+	for ca := range sf.stations {
+		cas = append(cas, ca)
+	}
+	sf.rwMux.RUnlock()
+	sort.Slice(cas, func(i, j int) bool { return cas[i] < cas[j] })
+	return cas
+}
+
+// station returns the handler of the station with common address ca
+func (sf *Router) station(ca asdu.CommonAddr) (ServerHandlerInterface, bool) {
+	sf.rwMux.RLock()
+	h, ok := sf.stations[ca]
+	sf.rwMux.RUnlock()
+	return h, ok
+}
+
+// broadcast 将全局地址的命令依次分发给每个站.
+// build 构造该站公共地址的命令,站处理失败时回复该站的否定激活确认.
+func (sf *Router) broadcast(c asdu.Connect, id asdu.Identifier,
+	build func(a *asdu.ASDU) error,
+	handle func(h ServerHandlerInterface, a *asdu.ASDU) error) error {
+	var firstErr error
+
+	for _, ca := range sf.CommonAddrs() {
+		h, ok := sf.station(ca)
+		if !ok { // removed meanwhile
+			continue
+		}
+		sid := id
+		sid.CommonAddr = ca
+		a := asdu.NewASDU(c.Params(), sid)
+		if err := build(a); err != nil {
+			return err
+		}
+		neg := a.Clone()
+		if err := handle(h, a); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			neg.Coa.Cause = asdu.ActivationCon
+			neg.Coa.IsNegative = true
+			if err = c.Send(neg); err != nil {
+				return err
+			}
+		}
+	}
+	return firstErr
+}
+
+// InterrogationHandler imp ServerHandlerInterface
+func (sf *Router) InterrogationHandler(c asdu.Connect, pack *asdu.ASDU, qoi asdu.QualifierOfInterrogation) error {
+	if pack.CommonAddr == asdu.GlobalCommonAddr {
+		return sf.broadcast(c, pack.Identifier,
+			func(a *asdu.ASDU) error {
+				if err := a.AppendInfoObjAddr(asdu.InfoObjAddrIrrelevant); err != nil {
+					return err
+				}
+				a.AppendBytes(byte(qoi))
+				return nil
+			},
+			func(h ServerHandlerInterface, a *asdu.ASDU) error {
+				return h.InterrogationHandler(c, a, qoi)
+			})
+	}
+	h, ok := sf.station(pack.CommonAddr)
+	if !ok {
+		return pack.SendReplyMirror(c, asdu.UnknownCA)
+	}
+	return h.InterrogationHandler(c, pack, qoi)
+}
+
+// CounterInterrogationHandler imp ServerHandlerInterface
+func (sf *Router) CounterInterrogationHandler(c asdu.Connect, pack *asdu.ASDU, qcc asdu.QualifierCountCall) error {
+	if pack.CommonAddr == asdu.GlobalCommonAddr {
+		return sf.broadcast(c, pack.Identifier,
+			func(a *asdu.ASDU) error {
+				if err := a.AppendInfoObjAddr(asdu.InfoObjAddrIrrelevant); err != nil {
+					return err
+				}
+				a.AppendBytes(qcc.Value())
+				return nil
+			},
+			func(h ServerHandlerInterface, a *asdu.ASDU) error {
+				return h.CounterInterrogationHandler(c, a, qcc)
+			})
+	}
+	h, ok := sf.station(pack.CommonAddr)
+	if !ok {
+		return pack.SendReplyMirror(c, asdu.UnknownCA)
+	}
+	return h.CounterInterrogationHandler(c, pack, qcc)
+}
+
+// ReadHandler imp ServerHandlerInterface
+func (sf *Router) ReadHandler(c asdu.Connect, pack *asdu.ASDU, ioa asdu.InfoObjAddr) error {
+	h, ok := sf.station(pack.CommonAddr)
+	if !ok {
+		return pack.SendReplyMirror(c, asdu.UnknownCA)
+	}
+	return h.ReadHandler(c, pack, ioa)
+}
+
+// ClockSyncHandler imp ServerHandlerInterface
+func (sf *Router) ClockSyncHandler(c asdu.Connect, pack *asdu.ASDU, tm time.Time) error {
+	if pack.CommonAddr == asdu.GlobalCommonAddr {
+		return sf.broadcast(c, pack.Identifier,
+			func(a *asdu.ASDU) error {
+				if err := a.AppendInfoObjAddr(asdu.InfoObjAddrIrrelevant); err != nil {
+					return err
+				}
+				a.AppendCP56Time2a(tm, a.InfoObjTimeZone)
+				return nil
+			},
+			func(h ServerHandlerInterface, a *asdu.ASDU) error {
+				return h.ClockSyncHandler(c, a, tm)
+			})
+	}
+	h, ok := sf.station(pack.CommonAddr)
+	if !ok {
+		return pack.SendReplyMirror(c, asdu.UnknownCA)
+	}
+	return h.ClockSyncHandler(c, pack, tm)
+}
+
+// ResetProcessHandler imp ServerHandlerInterface
+func (sf *Router) ResetProcessHandler(c asdu.Connect, pack *asdu.ASDU, qrp asdu.QualifierOfResetProcessCmd) error {
+	if pack.CommonAddr == asdu.GlobalCommonAddr {
+		return sf.broadcast(c, pack.Identifier,
+			func(a *asdu.ASDU) error {
+				if err := a.AppendInfoObjAddr(asdu.InfoObjAddrIrrelevant); err != nil {
+					return err
+				}
+				a.AppendBytes(byte(qrp))
+				return nil
+			},
+			func(h ServerHandlerInterface, a *asdu.ASDU) error {
+				return h.ResetProcessHandler(c, a, qrp)
+			})
+	}
+	h, ok := sf.station(pack.CommonAddr)
+	if !ok {
+		return pack.SendReplyMirror(c, asdu.UnknownCA)
+	}
+	return h.ResetProcessHandler(c, pack, qrp)
+}
+
+// DelayAcquisitionHandler imp ServerHandlerInterface
+func (sf *Router) DelayAcquisitionHandler(c asdu.Connect, pack *asdu.ASDU, msec uint16) error {
+	h, ok := sf.station(pack.CommonAddr)
+	if !ok {
+		return pack.SendReplyMirror(c, asdu.UnknownCA)
+	}
+	return h.DelayAcquisitionHandler(c, pack, msec)
+}
+
+// ASDUHandler imp ServerHandlerInterface
+func (sf *Router) ASDUHandler(c asdu.Connect, pack *asdu.ASDU) error {
+	h, ok := sf.station(pack.CommonAddr)
+	if !ok {
+		return pack.SendReplyMirror(c, asdu.UnknownCA)
+	}
+	return h.ASDUHandler(c, pack)
+}
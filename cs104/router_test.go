@@ -0,0 +1,241 @@
+package cs104
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// mockConn record all send asdu
+type mockConn struct {
+	p    *asdu.Params
+	sent []*asdu.ASDU
+}
+
+func (sf *mockConn) Params() *asdu.Params     { return sf.p }
+func (sf *mockConn) UnderlyingConn() net.Conn { return nil }
+func (sf *mockConn) Send(a *asdu.ASDU) error {
+	sf.sent = append(sf.sent, a.Clone())
+	return nil
+}
+
+// mockStation reply ActCon and ActTerm for the interrogation, or fail with err
+type mockStation struct {
+	err   error
+	calls int
+}
+
+func (sf *mockStation) InterrogationHandler(c asdu.Connect, a *asdu.ASDU, _ asdu.QualifierOfInterrogation) error {
+	sf.calls++
+	if sf.err != nil {
+		return sf.err
+	}
+	if err := a.SendReplyMirror(c, asdu.ActivationCon); err != nil {
+		return err
+	}
+	return a.SendReplyMirror(c, asdu.ActivationTerm)
+}
+func (sf *mockStation) CounterInterrogationHandler(asdu.Connect, *asdu.ASDU, asdu.QualifierCountCall) error {
+	sf.calls++
+	return sf.err
+}
+func (sf *mockStation) ReadHandler(asdu.Connect, *asdu.ASDU, asdu.InfoObjAddr) error {
+	sf.calls++
+	return sf.err
+}
+func (sf *mockStation) ClockSyncHandler(c asdu.Connect, a *asdu.ASDU, _ time.Time) error {
+	sf.calls++
+	if sf.err != nil {
+		return sf.err
+	}
+	return a.SendReplyMirror(c, asdu.ActivationCon)
+}
+func (sf *mockStation) ResetProcessHandler(asdu.Connect, *asdu.ASDU, asdu.QualifierOfResetProcessCmd) error {
+	sf.calls++
+	return sf.err
+}
+func (sf *mockStation) DelayAcquisitionHandler(asdu.Connect, *asdu.ASDU, uint16) error {
+	sf.calls++
+	return sf.err
+}
+func (sf *mockStation) ASDUHandler(asdu.Connect, *asdu.ASDU) error {
+	sf.calls++
+	return sf.err
+}
+
+type sentIdentifier struct {
+	ca       asdu.CommonAddr
+	cause    asdu.Cause
+	negative bool
+}
+
+func sentIdentifiers(c *mockConn) []sentIdentifier {
+	r := make([]sentIdentifier, 0, len(c.sent))
+	for _, a := range c.sent {
+		r = append(r, sentIdentifier{a.CommonAddr, a.Coa.Cause, a.Coa.IsNegative})
+	}
+	return r
+}
+
+func newInterrogation(p *asdu.Params, ca asdu.CommonAddr) *asdu.ASDU {
+	a := asdu.NewASDU(p, asdu.Identifier{
+		Type:       asdu.C_IC_NA_1,
+		Variable:   asdu.VariableStruct{Number: 1},
+		Coa:        asdu.CauseOfTransmission{Cause: asdu.Activation},
+		CommonAddr: ca,
+	})
+	_ = a.AppendInfoObjAddr(asdu.InfoObjAddrIrrelevant)
+	a.AppendBytes(byte(asdu.QOIStation))
+	return a
+}
+
+func TestRouter_Handle(t *testing.T) {
+	r := NewRouter()
+	if err := r.Handle(asdu.InvalidCommonAddr, &mockStation{}); err == nil {
+		t.Errorf("Handle() invalid common address want error")
+	}
+	if err := r.Handle(asdu.GlobalCommonAddr, &mockStation{}); err == nil {
+		t.Errorf("Handle() global common address want error")
+	}
+	if err := r.Handle(1, nil); err == nil {
+		t.Errorf("Handle() nil handler want error")
+	}
+	for _, ca := range []asdu.CommonAddr{3, 1, 2} {
+		if err := r.Handle(ca, &mockStation{}); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+	r.Remove(2)
+	if got, want := r.CommonAddrs(), []asdu.CommonAddr{1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CommonAddrs() = %v, want %v", got, want)
+	}
+}
+
+func TestRouter_InterrogationHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		ca       asdu.CommonAddr
+		stations map[asdu.CommonAddr]error
+		want     []sentIdentifier
+		wantErr  bool
+	}{
+		{
+			"unknown common address",
+			5,
+			map[asdu.CommonAddr]error{1: nil},
+			[]sentIdentifier{{5, asdu.UnknownCA, false}},
+			false,
+		},
+		{
+			"dispatch to station",
+			2,
+			map[asdu.CommonAddr]error{1: nil, 2: nil},
+			[]sentIdentifier{{2, asdu.ActivationCon, false}, {2, asdu.ActivationTerm, false}},
+			false,
+		},
+		{
+			"broadcast to all stations",
+			asdu.GlobalCommonAddr,
+			map[asdu.CommonAddr]error{2: nil, 1: nil},
+			[]sentIdentifier{
+				{1, asdu.ActivationCon, false}, {1, asdu.ActivationTerm, false},
+				{2, asdu.ActivationCon, false}, {2, asdu.ActivationTerm, false},
+			},
+			false,
+		},
+		{
+			"broadcast with failed station",
+			asdu.GlobalCommonAddr,
+			map[asdu.CommonAddr]error{1: errors.New("failed"), 2: nil},
+			[]sentIdentifier{
+				{1, asdu.ActivationCon, true},
+				{2, asdu.ActivationCon, false}, {2, asdu.ActivationTerm, false},
+			},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter()
+			for ca, err := range tt.stations {
+				_ = r.Handle(ca, &mockStation{err: err})
+			}
+			c := &mockConn{p: asdu.ParamsWide}
+			err := r.InterrogationHandler(c, newInterrogation(c.p, tt.ca), asdu.QOIStation)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("InterrogationHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := sentIdentifiers(c); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("InterrogationHandler() sent = %v, want %v", got, tt.want)
+			}
+			for _, a := range c.sent {
+				if ioa, qoi := a.GetInterrogationCmd(); ioa != asdu.InfoObjAddrIrrelevant || qoi != asdu.QOIStation {
+					t.Errorf("InterrogationHandler() reply info object = (%v, %v)", ioa, qoi)
+				}
+			}
+		})
+	}
+}
+
+func TestRouter_ClockSyncHandler(t *testing.T) {
+	r := NewRouter()
+	s1, s2 := &mockStation{}, &mockStation{}
+	_ = r.Handle(1, s1)
+	_ = r.Handle(2, s2)
+
+	c := &mockConn{p: asdu.ParamsWide}
+	tm := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	a := asdu.NewASDU(c.p, asdu.Identifier{
+		Type:       asdu.C_CS_NA_1,
+		Variable:   asdu.VariableStruct{Number: 1},
+		Coa:        asdu.CauseOfTransmission{Cause: asdu.Activation},
+		CommonAddr: asdu.GlobalCommonAddr,
+	})
+	if err := r.ClockSyncHandler(c, a, tm); err != nil {
+		t.Fatalf("ClockSyncHandler() error = %v", err)
+	}
+	if s1.calls != 1 || s2.calls != 1 {
+		t.Errorf("ClockSyncHandler() station calls = %d, %d, want 1, 1", s1.calls, s2.calls)
+	}
+	want := []sentIdentifier{{1, asdu.ActivationCon, false}, {2, asdu.ActivationCon, false}}
+	if got := sentIdentifiers(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("ClockSyncHandler() sent = %v, want %v", got, want)
+	}
+	for _, v := range c.sent {
+		if _, got := v.GetClockSynchronizationCmd(); !got.Equal(tm) {
+			t.Errorf("ClockSyncHandler() reply time = %v, want %v", got, tm)
+		}
+	}
+}
+
+func TestRouter_ASDUHandler(t *testing.T) {
+	r := NewRouter()
+	s := &mockStation{}
+	_ = r.Handle(1, s)
+
+	c := &mockConn{p: asdu.ParamsWide}
+	for _, ca := range []asdu.CommonAddr{1, 2} {
+		a := asdu.NewASDU(c.p, asdu.Identifier{
+			Type:       asdu.C_SC_NA_1,
+			Variable:   asdu.VariableStruct{Number: 1},
+			Coa:        asdu.CauseOfTransmission{Cause: asdu.Activation},
+			CommonAddr: ca,
+		})
+		_ = a.AppendInfoObjAddr(100)
+		a.AppendBytes(0x01)
+		if err := r.ASDUHandler(c, a); err != nil {
+			t.Fatalf("ASDUHandler() error = %v", err)
+		}
+	}
+	if s.calls != 1 {
+		t.Errorf("ASDUHandler() station calls = %d, want 1", s.calls)
+	}
+	want := []sentIdentifier{{2, asdu.UnknownCA, false}}
+	if got := sentIdentifiers(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("ASDUHandler() sent = %v, want %v", got, want)
+	}
+}
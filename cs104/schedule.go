@@ -0,0 +1,277 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs104
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// JobKind 周期任务的类型
+type JobKind int
+
+// 周期任务的类型
+const (
+	JobInterrogation        JobKind = iota + 1 // 召唤, 等待激活终止
+	JobCounterInterrogation                    // 电能量召唤, 等待激活终止
+	JobClockSync                               // 时钟同步, 等待激活确认
+	JobRead                                    // 读命令, 不等待应答
+)
+
+// String returns the name of kind
+func (sf JobKind) String() string {
+	switch sf {
+	case JobInterrogation:
+		return "interrogation"
+	case JobCounterInterrogation:
+		return "counter interrogation"
+	case JobClockSync:
+		return "clock sync"
+	case JobRead:
+		return "read"
+	}
+	return fmt.Sprintf("JobKind(%d)", int(sf))
+}
+
+// Job 客户端的周期任务, 只在数据传输激活(STARTDT确认)时执行, STOPDT和断开期间错过的不补发.
+// 召唤,时钟同步等命令与启动流程及Client.InterrogationCmd等用户命令互斥执行, 不会在召唤进行中(激活终止前)发送.
+type Job struct {
+	Kind     JobKind
+	Interval time.Duration // 执行间隔, 小于等于0的任务不执行
+	// 按时钟对齐到Interval的整数倍(UTC), 如每15分钟的0,15,30,45分执行,
+	// 否则在数据传输激活后每隔Interval执行
+	Align      bool
+	CommonAddr asdu.CommonAddr               // 命令的公共地址
+	QOI        asdu.QualifierOfInterrogation // 召唤限定词, 如asdu.QOIStation, asdu.QOIGroup3
+	QCC        asdu.QualifierCountCall       // 电能量召唤限定词, 可带冻结
+	IOAs       []asdu.InfoObjAddr            // 读命令的信息对象地址
+	Timeout    time.Duration                 // 等待应答的超时, 0为30s
+}
+
+// next 下一次执行的时间
+func (sf *Job) next(now time.Time) time.Time {
+	if sf.Align {
+		return now.Truncate(sf.Interval).Add(sf.Interval)
+	}
+	return now.Add(sf.Interval)
+}
+
+// errEndOfInit 命令执行中收到初始化结束, 需重新执行启动流程
+var errEndOfInit = errors.New("end of initialization received")
+
+// cmdNotify 将命令的应答及初始化结束交给等待的任务, 在handlerLoop中调用
+func (sf *Client) cmdNotify(a *asdu.ASDU) {
+	if sf.option.startup == nil && len(sf.jobs) == 0 {
+		return
+	}
+	switch a.Type {
+	case asdu.M_EI_NA_1:
+		select {
+		case sf.endOfInit <- struct{}{}:
+		default:
+		}
+	case asdu.C_IC_NA_1, asdu.C_CS_NA_1, asdu.C_CI_NA_1:
+		select {
+		case sf.cmdReply <- a:
+		default:
+			sf.Debug("command reply dropped, %v", a.Identifier)
+		}
+	}
+}
+
+// scheduleLoop 执行周期任务, 连接断开时退出
+func (sf *Client) scheduleLoop() {
+	sf.Debug("scheduleLoop started")
+	defer func() {
+		sf.wg.Done()
+		sf.Debug("scheduleLoop stopped")
+	}()
+
+	var jobs []*Job
+	var next []time.Time
+	now := time.Now()
+	for i := range sf.jobs {
+		if j := &sf.jobs[i]; j.Interval > 0 {
+			jobs = append(jobs, j)
+			next = append(next, j.next(now))
+		}
+	}
+	ticker := time.NewTicker(timeoutResolution)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sf.ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+		if !sf.IsActive() {
+			// 挂起, 激活后非对齐的任务重新计时
+			for i, j := range jobs {
+				if !j.Align {
+					next[i] = j.next(now)
+				}
+			}
+			continue
+		}
+		for i, j := range jobs {
+			if now.Before(next[i]) {
+				continue
+			}
+			if err := sf.runJob(j, false); err != nil {
+				if sf.ctx.Err() != nil {
+					return
+				}
+				sf.Warn("job: %v failed, %v", j.Kind, err)
+			}
+			now = time.Now()
+			next[i] = j.next(now)
+		}
+	}
+}
+
+// runJob 发送任务的命令并等待应答, 与其他命令互斥.
+// restartable为true时收到初始化结束返回errEndOfInit.
+func (sf *Client) runJob(j *Job, restartable bool) error {
+	select {
+	case sf.cmdBusy <- struct{}{}:
+	case <-sf.ctx.Done():
+		return sf.ctx.Err()
+	}
+	defer func() { <-sf.cmdBusy }()
+	sf.drainReply()
+
+	var err error
+	var k replyKey
+
+	c := asdu.WithContext(sf.ctx, sf)
+	coa := asdu.CauseOfTransmission{Cause: asdu.Activation}
+	switch j.Kind {
+	case JobInterrogation:
+		k = replyKey{asdu.C_IC_NA_1, j.CommonAddr, byte(j.QOI)}
+		err = asdu.InterrogationCmd(c, coa, j.CommonAddr, j.QOI)
+	case JobCounterInterrogation:
+		k = replyKey{asdu.C_CI_NA_1, j.CommonAddr, j.QCC.Value()}
+		err = asdu.CounterInterrogationCmd(c, coa, j.CommonAddr, j.QCC)
+	case JobClockSync:
+		k = replyKey{typeID: asdu.C_CS_NA_1, ca: j.CommonAddr}
+		err = asdu.ClockSynchronizationCmd(c, coa, j.CommonAddr, time.Now())
+	case JobRead:
+		for _, ioa := range j.IOAs {
+			if err = asdu.ReadCmd(c, asdu.CauseOfTransmission{Cause: asdu.Request}, j.CommonAddr, ioa); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown job %v", j.Kind)
+	}
+	if err != nil {
+		return err
+	}
+	return sf.waitReply(sf.ctx, k, j.Timeout, restartable)
+}
+
+// command 发送用户的召唤,电能量召唤及时钟同步命令. 配置了启动流程或周期任务时与其互斥执行:
+// 有命令在执行时不等待, 返回ErrCommandInProgress, 可在处理函数及回调中调用;
+// 激活命令的应答到达或超时前其他命令不执行.
+func (sf *Client) command(k replyKey, coa asdu.CauseOfTransmission, send func() error) error {
+	if sf.option.startup == nil && len(sf.jobs) == 0 {
+		return send()
+	}
+	select {
+	case sf.cmdBusy <- struct{}{}:
+	default:
+		return ErrCommandInProgress
+	}
+	sf.drainReply()
+	if err := send(); err != nil || coa.Cause != asdu.Activation {
+		<-sf.cmdBusy
+		return err
+	}
+	sf.rwMux.RLock()
+	ctx := sf.ctx
+	sf.rwMux.RUnlock()
+	go func() {
+		defer func() { <-sf.cmdBusy }()
+		if err := sf.waitReply(ctx, k, 0, false); err != nil && ctx.Err() == nil {
+			sf.Warn("command: %v", err)
+		}
+	}()
+	return nil
+}
+
+// drainReply 丢弃之前命令的应答
+func (sf *Client) drainReply() {
+	for len(sf.cmdReply) > 0 {
+		<-sf.cmdReply
+	}
+}
+
+// replyKey 命令应答的匹配条件
+type replyKey struct {
+	typeID asdu.TypeID
+	ca     asdu.CommonAddr
+	qual   byte // 召唤限定词QOI或电能量召唤限定词QCC, 时钟同步不比较
+}
+
+// match a是否为命令的应答: 类型标识,公共地址(全局地址的命令匹配所有站)及限定词相同
+func (sf replyKey) match(a *asdu.ASDU) bool {
+	if a.Type != sf.typeID || (sf.ca != asdu.GlobalCommonAddr && a.CommonAddr != sf.ca) {
+		return false
+	}
+	switch sf.typeID {
+	case asdu.C_IC_NA_1:
+		_, qoi, err := a.ParseInterrogationCmd()
+		return err == nil && byte(qoi) == sf.qual
+	case asdu.C_CI_NA_1:
+		_, qcc, err := a.ParseCounterInterrogationCmd()
+		return err == nil && qcc.Value() == sf.qual
+	}
+	return true
+}
+
+// cause 等待的应答: 召唤等待激活终止, 时钟同步等待激活确认
+func (sf replyKey) cause() asdu.Cause {
+	if sf.typeID == asdu.C_CS_NA_1 {
+		return asdu.ActivationCon
+	}
+	return asdu.ActivationTerm
+}
+
+// waitReply 等待命令k的应答, 否定确认或超时返回错误
+func (sf *Client) waitReply(ctx context.Context, k replyKey, timeout time.Duration, restartable bool) error {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	var endOfInit <-chan struct{}
+	if restartable {
+		endOfInit = sf.endOfInit
+	}
+	cause := k.cause()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return fmt.Errorf("no %v %v within %v", k.typeID, cause, timeout)
+		case <-endOfInit:
+			return errEndOfInit
+		case a := <-sf.cmdReply:
+			switch {
+			case !k.match(a):
+			case a.Coa.IsNegative, a.Coa.Cause >= asdu.UnknownTypeID:
+				return fmt.Errorf("negative %v %v", k.typeID, a.Coa.Cause)
+			case a.Coa.Cause == cause:
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package cs104
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+func TestJob_next(t *testing.T) {
+	now := time.Date(2020, 1, 1, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		name string
+		job  Job
+		want time.Time
+	}{
+		{"interval", Job{Interval: 15 * time.Minute}, now.Add(15 * time.Minute)},
+		{"quarter hour", Job{Interval: 15 * time.Minute, Align: true}, time.Date(2020, 1, 1, 10, 15, 0, 0, time.UTC)},
+		{"hour", Job{Interval: time.Hour, Align: true}, time.Date(2020, 1, 1, 11, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.job.next(now); !got.Equal(tt.want) {
+				t.Errorf("Job.next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_SetJobs(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	srv := NewServer(&mockStation{})
+	go srv.ListenAndServer(addr)
+	defer srv.Close()
+
+	sent := make(chan asdu.TypeID, 64)
+	o := NewOption().SetReconnectInterval(50 * time.Millisecond)
+	if err = o.AddRemoteServer(addr); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(&mockClientHandler{}, o).SetObserver(ObserverFunc(func(f *Frame) {
+		if f.Direction == DirSent && f.Control.Type == FrameI {
+			sent <- f.ASDU.Type
+		}
+	})).SetJobs(
+		Job{Kind: JobInterrogation, Interval: 200 * time.Millisecond, CommonAddr: 1, QOI: asdu.QOIGroup3},
+		Job{Kind: JobRead, Interval: 200 * time.Millisecond, CommonAddr: 1, IOAs: []asdu.InfoObjAddr{1, 2}},
+		Job{Kind: JobClockSync},
+	)
+	active := make(chan struct{}, 1)
+	client.SetOnConnectHandler(func(c *Client) { c.SendStartDt() })
+	client.SetOnActiveHandler(func(c *Client) { active <- struct{}{} })
+	if err = client.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	select {
+	case <-active:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting STARTDT con")
+	}
+	count := make(map[asdu.TypeID]int)
+	for timeout := time.After(time.Second); count[asdu.C_IC_NA_1] < 2; {
+		select {
+		case typeID := <-sent:
+			count[typeID]++
+		case <-timeout:
+			t.Fatalf("jobs sent %v", count)
+		}
+	}
+	if count[asdu.C_RD_NA_1] < 2 || count[asdu.C_CS_NA_1] != 0 {
+		t.Errorf("jobs sent %v", count)
+	}
+
+	// STOPDT期间挂起
+	client.SendStopDt()
+	for deadline := time.Now().Add(time.Second); client.IsActive(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting STOPDT con")
+		}
+	}
+	for len(sent) > 0 {
+		<-sent
+	}
+	select {
+	case typeID := <-sent:
+		t.Errorf("job sent %v after STOPDT", typeID)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestReplyKey_match(t *testing.T) {
+	reply := func(typeID asdu.TypeID, ca asdu.CommonAddr, qual byte) *asdu.ASDU {
+		a := asdu.NewASDU(asdu.ParamsWide, asdu.Identifier{
+			Type:       typeID,
+			Variable:   asdu.VariableStruct{Number: 1},
+			Coa:        asdu.CauseOfTransmission{Cause: asdu.ActivationTerm},
+			CommonAddr: ca,
+		})
+		_ = a.AppendInfoObjAddr(asdu.InfoObjAddrIrrelevant)
+		a.AppendBytes(qual)
+		return a
+	}
+	qcc := asdu.QualifierCountCall{Request: asdu.QCCTotal, Freeze: asdu.QCCFrzRead}.Value()
+	tests := []struct {
+		name string
+		k    replyKey
+		a    *asdu.ASDU
+		want bool
+	}{
+		{"interrogation", replyKey{asdu.C_IC_NA_1, 1, byte(asdu.QOIStation)}, reply(asdu.C_IC_NA_1, 1, byte(asdu.QOIStation)), true},
+		{"other common address", replyKey{asdu.C_IC_NA_1, 1, byte(asdu.QOIStation)}, reply(asdu.C_IC_NA_1, 2, byte(asdu.QOIStation)), false},
+		{"other qoi", replyKey{asdu.C_IC_NA_1, 1, byte(asdu.QOIStation)}, reply(asdu.C_IC_NA_1, 1, byte(asdu.QOIGroup3)), false},
+		{"global common address", replyKey{asdu.C_IC_NA_1, asdu.GlobalCommonAddr, byte(asdu.QOIStation)}, reply(asdu.C_IC_NA_1, 2, byte(asdu.QOIStation)), true},
+		{"other type", replyKey{asdu.C_IC_NA_1, 1, byte(asdu.QOIStation)}, reply(asdu.C_CI_NA_1, 1, byte(asdu.QOIStation)), false},
+		{"counter interrogation", replyKey{asdu.C_CI_NA_1, 1, qcc}, reply(asdu.C_CI_NA_1, 1, qcc), true},
+		{"other qcc", replyKey{asdu.C_CI_NA_1, 1, qcc}, reply(asdu.C_CI_NA_1, 1, qcc+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.k.match(tt.a); got != tt.want {
+				t.Errorf("replyKey.match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// slowStation reply ActTerm of the interrogation after a delay
+type slowStation struct {
+	mockStation
+}
+
+func (sf *slowStation) InterrogationHandler(c asdu.Connect, a *asdu.ASDU, _ asdu.QualifierOfInterrogation) error {
+	if err := a.SendReplyMirror(c, asdu.ActivationCon); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+	return a.SendReplyMirror(c, asdu.ActivationTerm)
+}
+
+func TestClient_commandSerializedWithJobs(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	srv := NewServer(&slowStation{})
+	go srv.ListenAndServer(addr)
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var outstanding, overlapped, user int
+	o := NewOption().SetReconnectInterval(50 * time.Millisecond)
+	if err = o.AddRemoteServer(addr); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(&mockClientHandler{}, o).SetObserver(ObserverFunc(func(f *Frame) {
+		if f.ASDU == nil || f.ASDU.Type != asdu.C_IC_NA_1 {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case f.Direction == DirSent:
+			if outstanding++; outstanding > 1 {
+				overlapped++
+			}
+			if _, qoi := f.ASDU.GetInterrogationCmd(); qoi == asdu.QOIStation {
+				user++
+			}
+		case f.ASDU.Coa.Cause == asdu.ActivationTerm:
+			outstanding--
+		}
+	})).SetJobs(Job{Kind: JobInterrogation, Interval: 50 * time.Millisecond, CommonAddr: 1, QOI: asdu.QOIGroup3})
+	active := make(chan struct{}, 1)
+	client.SetOnConnectHandler(func(c *Client) { c.SendStartDt() })
+	client.SetOnActiveHandler(func(c *Client) { active <- struct{}{} })
+	if err = client.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	select {
+	case <-active:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting STARTDT con")
+	}
+	// 任务的召唤进行中时返回ErrCommandInProgress, 重试
+	for i, deadline := 0, time.Now().Add(3*time.Second); i < 3; {
+		err = client.InterrogationCmd(asdu.CauseOfTransmission{Cause: asdu.Activation}, 1, asdu.QOIStation)
+		switch {
+		case err == nil:
+			i++
+		case err != ErrCommandInProgress:
+			t.Fatal(err)
+		case time.Now().After(deadline):
+			t.Fatal("timeout waiting command done")
+		default:
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	time.Sleep(500 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if overlapped != 0 || user != 3 {
+		t.Errorf("interrogation overlapped %d times, user interrogation sent %d", overlapped, user)
+	}
+}
+
+// cmdInHandler issue an interrogation when receiving the interrogation reply of a job
+type cmdInHandler struct {
+	mockClientHandler
+	result chan error
+}
+
+func (sf *cmdInHandler) InterrogationHandler(c asdu.Connect, a *asdu.ASDU) error {
+	if a.Coa.Cause != asdu.ActivationCon {
+		return nil
+	}
+	start := time.Now()
+	err := c.(*Client).InterrogationCmd(asdu.CauseOfTransmission{Cause: asdu.Activation}, 1, asdu.QOIStation)
+	if time.Since(start) > 50*time.Millisecond {
+		err = errors.New("interrogation blocked the handler")
+	}
+	select {
+	case sf.result <- err:
+	default:
+	}
+	return nil
+}
+
+func TestClient_commandInHandler(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	srv := NewServer(&slowStation{})
+	go srv.ListenAndServer(addr)
+	defer srv.Close()
+
+	handler := &cmdInHandler{result: make(chan error, 1)}
+	o := NewOption().SetReconnectInterval(50 * time.Millisecond)
+	if err = o.AddRemoteServer(addr); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(handler, o).SetJobs(Job{Kind: JobInterrogation, Interval: time.Hour, CommonAddr: 1, QOI: asdu.QOIGroup3, Timeout: 10 * time.Second})
+	active := make(chan struct{}, 1)
+	client.SetOnConnectHandler(func(c *Client) { c.SendStartDt() })
+	client.SetOnActiveHandler(func(c *Client) { active <- struct{}{} })
+	if err = client.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	select {
+	case <-active:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting STARTDT con")
+	}
+	c := make(chan error, 1)
+	go func() { c <- client.runJob(&client.jobs[0], false) }()
+
+	select {
+	case err = <-handler.result:
+		if err != ErrCommandInProgress {
+			t.Errorf("InterrogationCmd() in handler = %v, want %v", err, ErrCommandInProgress)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting ActCon")
+	}
+	// 处理函数未阻塞, 任务在超时前收到激活终止
+	select {
+	case err = <-c:
+		if err != nil {
+			t.Errorf("job = %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("job stalled by the handler")
+	}
+}
@@ -31,6 +31,11 @@ type Server struct {
 	listen         net.Listener
 	onConnection   func(asdu.Connect)
 	connectionLost func(asdu.Connect)
+	metrics        func(conn net.Conn) Metrics
+	observer       Observer
+	connWrapper    func(net.Conn) net.Conn
+	priority       SendPriority
+	preamble       *Preamble
 	clog.Clog
 	wg sync.WaitGroup
 }
@@ -42,6 +47,7 @@ func NewServer(handler ServerHandlerInterface) *Server {
 		params:   *asdu.ParamsWide,
 		handler:  handler,
 		sessions: make(map[*SrvSession]struct{}),
+		priority: DefaultSendPriority(),
 		Clog:     clog.NewLogger("cs104 server => "),
 	}
 }
@@ -66,6 +72,12 @@ func (sf *Server) SetParams(p *asdu.Params) *Server {
 	return sf
 }
 
+// SetSendPriority set the send priority of the new sessions
+func (sf *Server) SetSendPriority(p SendPriority) *Server {
+	sf.priority = p
+	return sf
+}
+
 // ListenAndServer run the server
 func (sf *Server) ListenAndServer(addr string) {
 	listen, err := net.Listen("tcp", addr)
@@ -90,22 +102,39 @@ func (sf *Server) ListenAndServer(addr string) {
 			sf.Error("server run failed, %v", err)
 			return
 		}
+		if sf.connWrapper != nil {
+			conn = sf.connWrapper(conn)
+		}
 
 		sf.wg.Add(1)
 		go func() {
+			defer sf.wg.Done()
+			conn, id, err := sf.preamble.start(conn, sf.config.ConnectTimeout0)
+			if err != nil {
+				sf.Error("preamble failed, %v", err)
+				return
+			}
 			sess := &SrvSession{
 				config:   &sf.config,
 				params:   &sf.params,
 				handler:  sf.handler,
 				conn:     conn,
-				rcvASDU:  make(chan []byte, sf.config.RecvUnAckLimitW<<4),
-				sendASDU: make(chan []byte, sf.config.SendUnAckLimitK<<4),
-				rcvRaw:   make(chan []byte, sf.config.RecvUnAckLimitW<<5),
-				sendRaw:  make(chan []byte, sf.config.SendUnAckLimitK<<5), // may not block!
+				rcvASDU:  make(chan *apduBuf, sf.config.RecvUnAckLimitW<<4),
+				sendASDU: newSendQueue(sf.priority, int(sf.config.SendUnAckLimitK<<4)),
+				rcvRaw:   make(chan *apduBuf, sf.config.RecvUnAckLimitW<<5),
+				sendRaw:  make(chan *apduBuf, sf.config.SendUnAckLimitK<<5), // may not block!
 
 				onConnection:   sf.onConnection,
 				connectionLost: sf.connectionLost,
 				Clog:           sf.Clog,
+				metrics:        noopMetrics{},
+				observer:       sf.observer,
+				identity:       id,
+			}
+			if sf.metrics != nil {
+				if m := sf.metrics(conn); m != nil {
+					sess.metrics = m
+				}
 			}
 			sf.mux.Lock()
 			sf.sessions[sess] = struct{}{}
@@ -114,7 +143,6 @@ func (sf *Server) ListenAndServer(addr string) {
 			sf.mux.Lock()
 			delete(sf.sessions, sess)
 			sf.mux.Unlock()
-			sf.wg.Done()
 		}()
 	}
 }
@@ -137,12 +165,29 @@ func (sf *Server) Close() error {
 func (sf *Server) Send(a *asdu.ASDU) error {
 	sf.mux.Lock()
 	for k := range sf.sessions {
-		_ = k.Send(a.Clone())
+		_ = k.Send(a)
 	}
 	sf.mux.Unlock()
 	return nil
 }
 
+// SendContext imp interface asdu.ContextConnect, wait for the send buffer of every session
+func (sf *Server) SendContext(ctx context.Context, a *asdu.ASDU) error {
+	sf.mux.Lock()
+	sessions := make([]*SrvSession, 0, len(sf.sessions))
+	for k := range sf.sessions {
+		sessions = append(sessions, k)
+	}
+	sf.mux.Unlock()
+	for _, k := range sessions {
+		_ = k.SendContext(ctx, a)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Params imp interface Connect
 func (sf *Server) Params() *asdu.Params { return &sf.params }
 
@@ -159,6 +204,27 @@ func (sf *Server) SetOnConnectionHandler(f func(asdu.Connect)) {
 	sf.onConnection = f
 }
 
+// SetMetrics set the function returns the collector of operational metrics for each connection
+func (sf *Server) SetMetrics(f func(conn net.Conn) Metrics) {
+	sf.metrics = f
+}
+
+// SetObserver set the observer of every APDU received and sent on all connections
+func (sf *Server) SetObserver(o Observer) {
+	sf.observer = o
+}
+
+// SetConnWrapper set the function wraps every connection accepted, used for fault injection in tests
+func (sf *Server) SetConnWrapper(f func(net.Conn) net.Conn) {
+	sf.connWrapper = f
+}
+
+// SetPreamble set the registration frame and heartbeats of DTU style connections, nil will disable it.
+// The identification read is returned by SrvSession.Identity.
+func (sf *Server) SetPreamble(p *Preamble) {
+	sf.preamble = p
+}
+
 // SetConnectionLostHandler set connect lost handler
 func (sf *Server) SetConnectionLostHandler(f func(asdu.Connect)) {
 	sf.connectionLost = f
@@ -30,10 +30,10 @@ type SrvSession struct {
 	conn    net.Conn
 	handler ServerHandlerInterface
 
-	rcvASDU  chan []byte // for received asdu
-	sendASDU chan []byte // for send asdu
-	rcvRaw   chan []byte // for recvLoop raw cs104 frame
-	sendRaw  chan []byte // for sendLoop raw cs104 frame
+	rcvASDU  chan *apduBuf // for received asdu
+	sendASDU *sendQueue    // for send asdu
+	rcvRaw   chan *apduBuf // for recvLoop raw cs104 frame
+	sendRaw  chan *apduBuf // for sendLoop raw cs104 frame
 
 	// see subclass 5.1 — Protection against loss and duplication of messages
 	seqNoSend uint16 // sequence number of next outbound I-frame
@@ -48,6 +48,9 @@ type SrvSession struct {
 	rwMux  sync.RWMutex
 
 	clog.Clog
+	metrics  Metrics
+	observer Observer
+	identity string // 注册帧中对端的标识, 见Preamble
 
 	onConnection   func(asdu.Connect)
 	connectionLost func(asdu.Connect)
@@ -67,7 +70,8 @@ func (sf *SrvSession) recvLoop() {
 	}()
 
 	for {
-		rawData := make([]byte, APDUSizeMax)
+		buf := getBuf()
+		rawData := buf.data[:]
 		for rdCnt, length := 0, 2; rdCnt < length; {
 			byteCount, err := io.ReadFull(sf.conn, rawData[rdCnt:length])
 			if err != nil {
@@ -108,9 +112,13 @@ func (sf *SrvSession) recvLoop() {
 					continue
 				}
 				if rdCnt == length {
-					apdu := rawData[:length]
+					buf.n = length
+					apdu := buf.bytes()
 					sf.Debug("RX Raw[% x]", apdu)
-					sf.rcvRaw <- apdu
+					if sf.observer != nil {
+						observe(sf.observer, sf.params, sf.conn, DirReceived, apdu)
+					}
+					sf.rcvRaw <- buf
 				}
 			}
 		}
@@ -130,8 +138,12 @@ func (sf *SrvSession) sendLoop() {
 		select {
 		case <-sf.ctx.Done():
 			return
-		case apdu := <-sf.sendRaw:
+		case buf := <-sf.sendRaw:
+			apdu := buf.bytes()
 			sf.Debug("TX Raw[% x]", apdu)
+			if sf.observer != nil {
+				observe(sf.observer, sf.params, sf.conn, DirSent, apdu)
+			}
 			for wrCnt := 0; len(apdu) > wrCnt; {
 				byteCount, err := sf.conn.Write(apdu[wrCnt:])
 				if err != nil {
@@ -149,6 +161,7 @@ func (sf *SrvSession) sendLoop() {
 				}
 				wrCnt += byteCount
 			}
+			putBuf(buf)
 		}
 	}
 }
@@ -159,7 +172,9 @@ func (sf *SrvSession) run(ctx context.Context) {
 	// before any thing make sure init
 	sf.cleanUp()
 
+	sf.rwMux.Lock()
 	sf.ctx, sf.cancel = context.WithCancel(ctx)
+	sf.rwMux.Unlock()
 	sf.setConnectStatus(connected)
 	sf.wg.Add(3)
 	go sf.recvLoop()
@@ -168,6 +183,8 @@ func (sf *SrvSession) run(ctx context.Context) {
 
 	// default: STOPDT, when connected establish and not enable "data transfer" yet
 	var isActive = false
+	// 收到STOPDT激活时仍有未确认的I帧, 等待全部确认后回复STOPDT确认
+	var stopDtPending = false
 	var checkTicker = time.NewTicker(timeoutResolution)
 
 	// transmission timestamps for timeout calculation
@@ -182,26 +199,32 @@ func (sf *SrvSession) run(ctx context.Context) {
 
 	sendSFrame := func(rcvSN uint16) {
 		sf.Debug("TX sFrame %v", sAPCI{rcvSN})
-		sf.sendRaw <- newSFrame(rcvSN)
+		sf.sendRaw <- newBuf(newSFrame(rcvSN))
+		sf.metrics.FrameSent(FrameS)
+		sf.metrics.RecvWindow(0, sf.config.RecvUnAckLimitW)
 	}
 	sendUFrame := func(which byte) {
 		sf.Debug("TX uFrame %v", uAPCI{which})
-		sf.sendRaw <- newUFrame(which)
+		sf.sendRaw <- newBuf(newUFrame(which))
+		sf.metrics.FrameSent(FrameU)
 	}
 
-	sendIFrame := func(asdu1 []byte) {
+	sendIFrame := func(buf *apduBuf) {
 		seqNo := sf.seqNoSend
 
-		iframe, err := newIFrame(seqNo, sf.seqNoRcv, asdu1)
-		if err != nil {
-			return
-		}
+		buf.setIFrame(seqNo, sf.seqNoRcv)
 		sf.ackNoRcv = sf.seqNoRcv
 		sf.seqNoSend = (seqNo + 1) & 32767
-		sf.pending = append(sf.pending, seqPending{seqNo & 32767, time.Now()})
+		sf.pending = append(sf.pending, seqPending{seqNo & 32767, time.Now(), buf.ack})
 
 		sf.Debug("TX iFrame %v", iAPCI{seqNo, sf.seqNoRcv})
-		sf.sendRaw <- iframe
+		typeID, cause := buf.typeCause()
+		sf.sendRaw <- buf
+		sf.metrics.FrameSent(FrameI)
+		sf.metrics.ASDUSent(typeID, cause)
+		sf.metrics.SendWindow(seqNoCount(sf.ackNoSend, sf.seqNoSend), sf.config.SendUnAckLimitK)
+		sf.metrics.RecvWindow(0, sf.config.RecvUnAckLimitW)
+		sf.metrics.SendBuffer(sf.sendASDU.len(), sf.sendASDU.cap())
 	}
 	if sf.onConnection != nil {
 		sf.onConnection(sf)
@@ -211,6 +234,7 @@ func (sf *SrvSession) run(ctx context.Context) {
 		checkTicker.Stop()
 		_ = sf.conn.Close() // 连锁引发cancel
 		sf.wg.Wait()
+		sf.cleanUp()
 		if sf.connectionLost != nil {
 			sf.connectionLost(sf)
 		}
@@ -218,15 +242,11 @@ func (sf *SrvSession) run(ctx context.Context) {
 	}()
 
 	for {
-		if isActive && seqNoCount(sf.ackNoSend, sf.seqNoSend) <= sf.config.SendUnAckLimitK {
-			select {
-			case o := <-sf.sendASDU:
+		if isActive && seqNoCount(sf.ackNoSend, sf.seqNoSend) < sf.config.SendUnAckLimitK {
+			if o := sf.sendASDU.pop(); o != nil {
 				sendIFrame(o)
 				idleTimeout3Sine = time.Now()
 				continue
-			case <-sf.ctx.Done():
-				return
-			default: // make no block
 			}
 		}
 		select {
@@ -238,6 +258,7 @@ func (sf *SrvSession) run(ctx context.Context) {
 				// now.Sub(startDtActiveSendSince) >= t.SendUnAckTimeout1 ||
 				// now.Sub(stopDtActiveSendSince) >= t.SendUnAckTimeout1 ||
 				sf.Error("test frame alive confirm timeout t₁")
+				sf.metrics.Timeout(TimeoutT1)
 				return
 			}
 			// check oldest unacknowledged outbound
@@ -246,6 +267,7 @@ func (sf *SrvSession) run(ctx context.Context) {
 				now.Sub(sf.pending[0].sendTime) >= sf.config.SendUnAckTimeout1 {
 				sf.ackNoSend++
 				sf.Error("fatal transmission timeout t₁")
+				sf.metrics.Timeout(TimeoutT1)
 				return
 			}
 
@@ -253,30 +275,41 @@ func (sf *SrvSession) run(ctx context.Context) {
 			if sf.ackNoRcv != sf.seqNoRcv &&
 				(now.Sub(unAckRcvSince) >= sf.config.RecvUnAckTimeout2 ||
 					now.Sub(idleTimeout3Sine) >= timeoutResolution) {
+				if now.Sub(unAckRcvSince) >= sf.config.RecvUnAckTimeout2 {
+					sf.metrics.Timeout(TimeoutT2)
+				}
 				sendSFrame(sf.seqNoRcv)
 				sf.ackNoRcv = sf.seqNoRcv
 			}
 
 			// 空闲时间到，发送TestFrActive帧,保活
 			if now.Sub(idleTimeout3Sine) >= sf.config.IdleTimeout3 {
+				sf.metrics.Timeout(TimeoutT3)
 				sendUFrame(uTestFrActive)
 				testFrAliveSendSince = time.Now()
 				idleTimeout3Sine = testFrAliveSendSince
 			}
 
-		case apdu := <-sf.rcvRaw:
+		case buf := <-sf.rcvRaw:
 			idleTimeout3Sine = time.Now() // 每收到一个i帧,S帧,U帧, 重置空闲定时器, t3
-			apci, asduVal := parse(apdu)
+			apci, _ := parse(buf.bytes())
 			switch head := apci.(type) {
 			case sAPCI:
 				sf.Debug("RX sFrame %v", head)
+				sf.metrics.FrameReceived(FrameS)
 				if !sf.updateAckNoOut(head.rcvSN) {
 					sf.Error("fatal incoming acknowledge either earlier than previous or later than sendTime")
 					return
 				}
+				sf.metrics.SendWindow(seqNoCount(sf.ackNoSend, sf.seqNoSend), sf.config.SendUnAckLimitK)
+				if stopDtPending && sf.ackNoSend == sf.seqNoSend {
+					sendUFrame(uStopDtConfirm)
+					stopDtPending = false
+				}
 
 			case iAPCI:
 				sf.Debug("RX iFrame %v", head)
+				sf.metrics.FrameReceived(FrameI)
 				if !isActive {
 					sf.Warn("station not active")
 					break // not active, discard apdu
@@ -286,12 +319,16 @@ func (sf *SrvSession) run(ctx context.Context) {
 					return
 				}
 
-				sf.rcvASDU <- asduVal
+				// 缓冲由handlerLoop归还
+				sf.rcvASDU <- buf
+				buf = nil
 				if sf.ackNoRcv == sf.seqNoRcv { // first unacked
 					unAckRcvSince = time.Now()
 				}
 
 				sf.seqNoRcv = (sf.seqNoRcv + 1) & 32767
+				sf.metrics.SendWindow(seqNoCount(sf.ackNoSend, sf.seqNoSend), sf.config.SendUnAckLimitK)
+				sf.metrics.RecvWindow(seqNoCount(sf.ackNoRcv, sf.seqNoRcv), sf.config.RecvUnAckLimitW)
 				if seqNoCount(sf.ackNoRcv, sf.seqNoRcv) >= sf.config.RecvUnAckLimitW {
 					sendSFrame(sf.seqNoRcv)
 					sf.ackNoRcv = sf.seqNoRcv
@@ -299,27 +336,44 @@ func (sf *SrvSession) run(ctx context.Context) {
 
 			case uAPCI:
 				sf.Debug("RX uFrame %v", head)
+				sf.metrics.FrameReceived(FrameU)
 				switch head.function {
 				case uStartDtActive:
 					sendUFrame(uStartDtConfirm)
 					isActive = true
+					stopDtPending = false
 				// case uStartDtConfirm:
 				// 	isActive = true
 				// 	startDtActiveSendSince = willNotTimeout
 				case uStopDtActive:
-					sendUFrame(uStopDtConfirm)
+					// 停止发送新的I帧, 确认已接收的I帧, 已发送的I帧全部被确认后才回复确认
 					isActive = false
+					if sf.ackNoRcv != sf.seqNoRcv {
+						sendSFrame(sf.seqNoRcv)
+						sf.ackNoRcv = sf.seqNoRcv
+					}
+					if sf.ackNoSend == sf.seqNoSend {
+						sendUFrame(uStopDtConfirm)
+					} else {
+						stopDtPending = true
+					}
 				// case uStopDtConfirm:
 				// 	isActive = false
 				// 	stopDtActiveSendSince = willNotTimeout
 				case uTestFrActive:
 					sendUFrame(uTestFrConfirm)
 				case uTestFrConfirm:
+					if testFrAliveSendSince != willNotTimeout {
+						sf.metrics.TestFrameRTT(time.Since(testFrAliveSendSince))
+					}
 					testFrAliveSendSince = willNotTimeout
 				default:
 					sf.Error("illegal U-Frame functions[0x%02x] ignored", head.function)
 				}
 			}
+			if buf != nil {
+				putBuf(buf)
+			}
 		}
 	}
 }
@@ -336,12 +390,15 @@ func (sf *SrvSession) handlerLoop() {
 		select {
 		case <-sf.ctx.Done():
 			return
-		case rawAsdu := <-sf.rcvASDU:
+		case buf := <-sf.rcvASDU:
 			asduPack := asdu.NewEmptyASDU(sf.params)
-			if err := asduPack.UnmarshalBinary(rawAsdu); err != nil {
+			err := asduPack.UnmarshalBinary(buf.asdu())
+			putBuf(buf)
+			if err != nil {
 				sf.Error("asdu UnmarshalBinary failed,%+v", err)
 				continue
 			}
+			sf.metrics.ASDUReceived(asduPack.Type, asduPack.Coa.Cause)
 			if err := sf.serverHandler(asduPack); err != nil {
 				sf.Error("serverHandler falied,%+v", err)
 			}
@@ -367,19 +424,13 @@ func (sf *SrvSession) cleanUp() {
 	sf.ackNoSend = 0
 	sf.seqNoRcv = 0
 	sf.seqNoSend = 0
+	failPending(sf.pending)
 	sf.pending = nil
 	// clear sending chan buffer
-loop:
-	for {
-		select {
-		case <-sf.sendRaw:
-		case <-sf.rcvRaw:
-		case <-sf.rcvASDU:
-		case <-sf.sendASDU:
-		default:
-			break loop
-		}
-	}
+	drainBuf(sf.sendRaw)
+	drainBuf(sf.rcvRaw)
+	drainBuf(sf.rcvASDU)
+	sf.sendASDU.drain()
 }
 
 // 回绕机制
@@ -401,7 +452,8 @@ func (sf *SrvSession) updateAckNoOut(ackNo uint16) (ok bool) {
 
 	// confirm reception
 	for i, v := range sf.pending {
-		if v.seq == (ackNo - 1) {
+		if v.seq == (ackNo-1)&32767 {
+			ackPending(sf.pending[:i+1])
 			sf.pending = sf.pending[i+1:]
 			break
 		}
@@ -420,6 +472,7 @@ func (sf *SrvSession) serverHandler(asduPack *asdu.ASDU) error {
 
 	sf.Debug("ASDU %+v", asduPack)
 
+	// 解码使用副本, 保证处理者回复镜像时信息体完整, 信息体错误的帧不交给处理者
 	switch asduPack.Identifier.Type {
 	case asdu.C_IC_NA_1: // InterrogationCmd
 		if !(asduPack.Identifier.Coa.Cause == asdu.Activation ||
@@ -429,7 +482,10 @@ func (sf *SrvSession) serverHandler(asduPack *asdu.ASDU) error {
 		if asduPack.CommonAddr == asdu.InvalidCommonAddr {
 			return asduPack.SendReplyMirror(sf, asdu.UnknownCA)
 		}
-		ioa, qoi := asduPack.GetInterrogationCmd()
+		ioa, qoi, err := asduPack.ParseInterrogationCmd()
+		if err != nil {
+			return err
+		}
 		if ioa != asdu.InfoObjAddrIrrelevant {
 			return asduPack.SendReplyMirror(sf, asdu.UnknownIOA)
 		}
@@ -442,7 +498,10 @@ func (sf *SrvSession) serverHandler(asduPack *asdu.ASDU) error {
 		if asduPack.CommonAddr == asdu.InvalidCommonAddr {
 			return asduPack.SendReplyMirror(sf, asdu.UnknownCA)
 		}
-		ioa, qcc := asduPack.GetCounterInterrogationCmd()
+		ioa, qcc, err := asduPack.ParseCounterInterrogationCmd()
+		if err != nil {
+			return err
+		}
 		if ioa != asdu.InfoObjAddrIrrelevant {
 			return asduPack.SendReplyMirror(sf, asdu.UnknownIOA)
 		}
@@ -455,7 +514,11 @@ func (sf *SrvSession) serverHandler(asduPack *asdu.ASDU) error {
 		if asduPack.CommonAddr == asdu.InvalidCommonAddr {
 			return asduPack.SendReplyMirror(sf, asdu.UnknownCA)
 		}
-		return sf.handler.ReadHandler(sf, asduPack, asduPack.GetReadCmd())
+		ioa, err := asduPack.ParseReadCmd()
+		if err != nil {
+			return err
+		}
+		return sf.handler.ReadHandler(sf, asduPack, ioa)
 
 	case asdu.C_CS_NA_1: // ClockSynchronizationCmd
 		if asduPack.Identifier.Coa.Cause != asdu.Activation {
@@ -465,7 +528,10 @@ func (sf *SrvSession) serverHandler(asduPack *asdu.ASDU) error {
 			return asduPack.SendReplyMirror(sf, asdu.UnknownCA)
 		}
 
-		ioa, tm := asduPack.GetClockSynchronizationCmd()
+		ioa, tm, err := asduPack.ParseClockSynchronizationCmd()
+		if err != nil {
+			return err
+		}
 		if ioa != asdu.InfoObjAddrIrrelevant {
 			return asduPack.SendReplyMirror(sf, asdu.UnknownIOA)
 		}
@@ -478,7 +544,10 @@ func (sf *SrvSession) serverHandler(asduPack *asdu.ASDU) error {
 		if asduPack.CommonAddr == asdu.InvalidCommonAddr {
 			return asduPack.SendReplyMirror(sf, asdu.UnknownCA)
 		}
-		ioa, _ := asduPack.GetTestCommand()
+		ioa, _, err := asduPack.ParseTestCommand()
+		if err != nil {
+			return err
+		}
 		if ioa != asdu.InfoObjAddrIrrelevant {
 			return asduPack.SendReplyMirror(sf, asdu.UnknownIOA)
 		}
@@ -491,7 +560,10 @@ func (sf *SrvSession) serverHandler(asduPack *asdu.ASDU) error {
 		if asduPack.CommonAddr == asdu.InvalidCommonAddr {
 			return asduPack.SendReplyMirror(sf, asdu.UnknownCA)
 		}
-		ioa, qrp := asduPack.GetResetProcessCmd()
+		ioa, qrp, err := asduPack.ParseResetProcessCmd()
+		if err != nil {
+			return err
+		}
 		if ioa != asdu.InfoObjAddrIrrelevant {
 			return asduPack.SendReplyMirror(sf, asdu.UnknownIOA)
 		}
@@ -504,7 +576,10 @@ func (sf *SrvSession) serverHandler(asduPack *asdu.ASDU) error {
 		if asduPack.CommonAddr == asdu.InvalidCommonAddr {
 			return asduPack.SendReplyMirror(sf, asdu.UnknownCA)
 		}
-		ioa, msec := asduPack.GetDelayAcquireCommand()
+		ioa, msec, err := asduPack.ParseDelayAcquireCommand()
+		if err != nil {
+			return err
+		}
 		if ioa != asdu.InfoObjAddrIrrelevant {
 			return asduPack.SendReplyMirror(sf, asdu.UnknownIOA)
 		}
@@ -529,18 +604,60 @@ func (sf *SrvSession) Params() *asdu.Params {
 
 // Send asdu frame
 func (sf *SrvSession) Send(u *asdu.ASDU) error {
+	return sf.send(nil, u, nil)
+}
+
+// SendContext send asdu frame, wait for the send buffer if it is full
+// until the connection drops or ctx is done. imp interface asdu.ContextConnect
+func (sf *SrvSession) SendContext(ctx context.Context, u *asdu.ASDU) error {
+	return sf.send(ctx, u, nil)
+}
+
+// connDone 当前连接的结束信号
+func (sf *SrvSession) connDone() <-chan struct{} {
+	sf.rwMux.RLock()
+	defer sf.rwMux.RUnlock()
+	if sf.ctx == nil {
+		return nil
+	}
+	return sf.ctx.Done()
+}
+
+// SendWithAck send asdu frame, the returned Delivery completes when the peer
+// acknowledges the I-frame, or fails if the connection drops or ctx is done first.
+func (sf *SrvSession) SendWithAck(ctx context.Context, u *asdu.ASDU) *Delivery {
+	d := newDelivery(ctx)
+	if err := ctx.Err(); err != nil {
+		d.resolve(err)
+	} else if err = sf.send(nil, u, d); err != nil {
+		d.resolve(err)
+	}
+	return d
+}
+
+// send ctx为nil时发送缓冲满立即返回ErrBufferFulled, 否则等待
+func (sf *SrvSession) send(ctx context.Context, u *asdu.ASDU, ack *Delivery) error {
 	if !sf.IsConnected() {
 		return ErrUseClosedConnection
 	}
-	data, err := u.MarshalBinary()
+	buf, err := encodeASDU(u)
 	if err != nil {
 		return err
 	}
-	select {
-	case sf.sendASDU <- data:
-	default:
-		return ErrBufferFulled
+	buf.ack = ack
+	if ctx == nil {
+		if !sf.sendASDU.push(u, buf) {
+			err = ErrBufferFulled
+		}
+	} else {
+		err = sf.sendASDU.pushContext(ctx, sf.connDone(), u, buf)
+	}
+	if err != nil {
+		putBuf(buf)
+		sf.metrics.SendBuffer(sf.sendASDU.len(), sf.sendASDU.cap())
+		return err
 	}
+	sf.metrics.SendBuffer(sf.sendASDU.len(), sf.sendASDU.cap())
 	return nil
 }
 
@@ -548,3 +665,8 @@ func (sf *SrvSession) Send(u *asdu.ASDU) error {
 func (sf *SrvSession) UnderlyingConn() net.Conn {
 	return sf.conn
 }
+
+// Identity returns the identification read from the registration frame, see Server.SetPreamble
+func (sf *SrvSession) Identity() string {
+	return sf.identity
+}
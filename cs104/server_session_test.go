@@ -0,0 +1,141 @@
+package cs104
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/clog"
+)
+
+func TestSrvSession_serverHandler(t *testing.T) {
+	newCmd := func(typeID asdu.TypeID, cause asdu.Cause, number byte, infoObj ...byte) *asdu.ASDU {
+		a := asdu.NewASDU(asdu.ParamsWide, asdu.Identifier{
+			Type:       typeID,
+			Variable:   asdu.VariableStruct{Number: number},
+			Coa:        asdu.CauseOfTransmission{Cause: cause},
+			CommonAddr: 1,
+		})
+		a.AppendBytes(infoObj...)
+		return a
+	}
+	tests := []struct {
+		name      string
+		asdu      *asdu.ASDU
+		wantErr   error
+		wantCalls int
+	}{
+		{"read", newCmd(asdu.C_RD_NA_1, asdu.Request, 1, 0x01, 0x00, 0x00), nil, 1},
+		{"read without address", newCmd(asdu.C_RD_NA_1, asdu.Request, 1, 0x01), asdu.ErrTruncatedObject, 0},
+		{"interrogation count", newCmd(asdu.C_IC_NA_1, asdu.Activation, 2, 0x00, 0x00, 0x00, 0x14, 0x00, 0x00, 0x00, 0x14),
+			asdu.ErrCountMismatch, 0},
+		{"clock sync truncated", newCmd(asdu.C_CS_NA_1, asdu.Activation, 1, 0x00, 0x00, 0x00, 0x01, 0x02),
+			asdu.ErrTruncatedObject, 0},
+		{"clock sync invalid time", newCmd(asdu.C_CS_NA_1, asdu.Activation, 1, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x00, 0x13),
+			asdu.ErrInvalidTimeTag, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			station := &mockStation{}
+			sf := &SrvSession{params: asdu.ParamsWide, handler: station, Clog: clog.NewLogger("cs104 server => ")}
+			if err := sf.serverHandler(tt.asdu); !errors.Is(err, tt.wantErr) {
+				t.Errorf("serverHandler() error = %v, want %v", err, tt.wantErr)
+			}
+			if station.calls != tt.wantCalls {
+				t.Errorf("handler called %d times, want %d", station.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func newSendSession(size int) *SrvSession {
+	return &SrvSession{params: asdu.ParamsWide, status: connected, sendASDU: newSendQueue(DefaultSendPriority(), size), metrics: noopMetrics{}}
+}
+
+func newSendASDU() *asdu.ASDU {
+	a := asdu.NewASDU(asdu.ParamsWide, asdu.Identifier{
+		Type:       asdu.M_ME_NC_1,
+		Variable:   asdu.VariableStruct{Number: 1},
+		Coa:        asdu.CauseOfTransmission{Cause: asdu.Spontaneous},
+		CommonAddr: 1,
+	})
+	_ = a.AppendInfoObjAddr(1)
+	a.AppendFloat32(1).AppendBytes(0x00)
+	return a
+}
+
+func TestSrvSession_Send(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		status  uint32
+		want    []byte
+		wantErr error
+	}{
+		{"send", 1, connected,
+			[]byte{0x68, 0x12, 0x02, 0x00, 0x04, 0x00, 0x0d, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x80, 0x3f, 0x00}, nil},
+		{"buffer fulled", 0, connected, nil, ErrBufferFulled},
+		{"closed", 1, disconnected, nil, ErrUseClosedConnection},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := newSendSession(tt.size)
+			sf.status = tt.status
+			if err := sf.Send(newSendASDU()); err != tt.wantErr {
+				t.Fatalf("SrvSession.Send() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.want == nil {
+				return
+			}
+			buf := sf.sendASDU.pop()
+			buf.setIFrame(1, 2)
+			if !bytes.Equal(buf.bytes(), tt.want) {
+				t.Errorf("SrvSession.Send() apdu = % x, want % x", buf.bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkSrvSession_Send(b *testing.B) {
+	sf := newSendSession(1)
+	a := newSendASDU()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := sf.Send(a); err != nil {
+			b.Fatal(err)
+		}
+		buf := sf.sendASDU.pop()
+		buf.setIFrame(uint16(i), 0)
+		putBuf(buf)
+	}
+}
+
+func TestSrvSession_SendContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		closed  bool
+		wantErr error
+	}{
+		{"send", 1, false, nil},
+		{"buffer fulled", 0, false, context.DeadlineExceeded},
+		{"connection closed", 0, true, ErrUseClosedConnection},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := newSendSession(tt.size)
+			sf.ctx, sf.cancel = context.WithCancel(context.Background())
+			if tt.closed {
+				sf.cancel()
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if err := sf.SendContext(ctx, newSendASDU()); err != tt.wantErr {
+				t.Errorf("SrvSession.SendContext() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
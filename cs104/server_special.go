@@ -7,9 +7,7 @@ package cs104
 import (
 	"context"
 	"errors"
-	"math/rand"
 	"sync/atomic"
-	"time"
 
 	"github.com/thinkgos/go-iecp5/asdu"
 	"github.com/thinkgos/go-iecp5/clog"
@@ -31,6 +29,9 @@ type ServerSpecial interface {
 	SetLogProvider(p clog.LogProvider)
 }
 
+// errSessionLost 已建立的会话断开, 报告给重连回调
+var errSessionLost = errors.New("session lost")
+
 type serverSpec struct {
 	SrvSession
 	option      ClientOption
@@ -45,10 +46,10 @@ func NewServerSpecial(handler ServerHandlerInterface, o *ClientOption) ServerSpe
 			params:  &o.params,
 			handler: handler,
 
-			rcvASDU:  make(chan []byte, 1024),
-			sendASDU: make(chan []byte, 1024),
-			rcvRaw:   make(chan []byte, 1024),
-			sendRaw:  make(chan []byte, 1024), // may not block!
+			rcvASDU:  make(chan *apduBuf, 1024),
+			sendASDU: newSendQueue(o.priority, 1024),
+			rcvRaw:   make(chan *apduBuf, 1024),
+			sendRaw:  make(chan *apduBuf, 1024), // may not block!
 
 			Clog: clog.NewLogger("cs104 serverSpec => "),
 		},
@@ -89,7 +90,7 @@ func (sf *serverSpec) running() {
 	sf.rwMux.Unlock()
 	defer sf.setConnectStatus(initial)
 
-	for {
+	for attempt := 0; ; {
 		select {
 		case <-ctx.Done():
 			return
@@ -97,25 +98,32 @@ func (sf *serverSpec) running() {
 		}
 
 		sf.Debug("connecting server %+v", sf.option.server)
-		conn, err := openConnection(sf.option.server, sf.option.TLSConfig, sf.config.ConnectTimeout0)
+		conn, err := openConnection(ctx, sf.option.server, sf.option.TLSConfig, sf.config.ConnectTimeout0)
+		if err == nil {
+			conn, _, err = sf.option.preamble.start(conn, sf.config.ConnectTimeout0)
+		}
 		if err != nil {
 			sf.Error("connect failed, %v", err)
-			if !sf.option.autoReconnect {
+			attempt++
+			wait, ok := sf.option.reconnectWait(attempt, err, false)
+			if !ok || !sleepContext(ctx, wait) {
 				return
 			}
-			time.Sleep(sf.option.reconnectInterval)
 			continue
 		}
+		attempt = 0
 		sf.Debug("connect success")
 		sf.conn = conn
 		sf.run(ctx)
 		sf.Debug("disconnected server %+v", sf.option.server)
-		select {
-		case <-ctx.Done():
+		if ctx.Err() != nil {
+			return
+		}
+		// 连接断开作为新一轮重连的第一次失败
+		attempt++
+		wait, ok := sf.option.reconnectWait(attempt, errSessionLost, true)
+		if !ok || !sleepContext(ctx, wait) {
 			return
-		default:
-			// 随机500ms-1s的重试，避免快速重试造成服务器许多无效连接
-			time.Sleep(time.Millisecond * time.Duration(500+rand.Intn(500)))
 		}
 	}
 }
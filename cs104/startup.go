@@ -0,0 +1,160 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs104
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// StartupStep 启动流程中STARTDT确认后执行的步骤
+type StartupStep int
+
+// 启动步骤
+const (
+	StartupInterrogation        StartupStep = iota + 1 // 总召唤, 等待激活终止
+	StartupClockSync                                   // 时钟同步, 等待激活确认
+	StartupCounterInterrogation                        // 电能量召唤, 等待激活终止
+)
+
+// String returns the name of step
+func (sf StartupStep) String() string {
+	switch sf {
+	case StartupInterrogation:
+		return "interrogation"
+	case StartupClockSync:
+		return "clock sync"
+	case StartupCounterInterrogation:
+		return "counter interrogation"
+	}
+	return fmt.Sprintf("StartupStep(%d)", int(sf))
+}
+
+// Startup 客户端连接建立后的自动启动流程:
+// 发送STARTDT并等待确认, 可选等待初始化结束(M_EI_NA_1), 然后按Steps的顺序执行各步骤.
+// 会话中收到初始化结束(从站重启)时重新执行Steps.
+type Startup struct {
+	CommonAddr asdu.CommonAddr               // 命令的公共地址
+	Steps      []StartupStep                 // 执行的步骤及顺序
+	QOI        asdu.QualifierOfInterrogation // 总召唤限定词
+	QCC        asdu.QualifierCountCall       // 电能量召唤限定词
+	Timeout    time.Duration                 // 每步等待应答的超时
+	Retries    int                           // 每步失败后的重试次数
+	// STARTDT确认后等待初始化结束, 超时后继续执行Steps
+	WaitEndOfInit    bool
+	EndOfInitTimeout time.Duration
+}
+
+// DefaultStartup 默认的启动流程: 总召唤,时钟同步,电能量召唤, 不等待初始化结束
+func DefaultStartup(ca asdu.CommonAddr) Startup {
+	return Startup{
+		CommonAddr:       ca,
+		Steps:            []StartupStep{StartupInterrogation, StartupClockSync, StartupCounterInterrogation},
+		QOI:              asdu.QOIStation,
+		QCC:              asdu.QualifierCountCall{Request: asdu.QCCTotal, Freeze: asdu.QCCFrzRead},
+		Timeout:          30 * time.Second,
+		Retries:          2,
+		EndOfInitTimeout: 10 * time.Second,
+	}
+}
+
+// startupLoop 执行启动流程, 连接断开时退出
+func (sf *Client) startupLoop() {
+	sf.Debug("startupLoop started")
+	defer func() {
+		sf.wg.Done()
+		sf.Debug("startupLoop stopped")
+	}()
+
+	s := sf.option.startup
+	// 丢弃上一个连接遗留的事件
+	select {
+	case <-sf.startupActive:
+	default:
+	}
+	select {
+	case <-sf.endOfInit:
+	default:
+	}
+
+	sf.SendStartDt()
+	select {
+	case <-sf.ctx.Done():
+		return
+	case <-sf.startupActive:
+	}
+
+	if s.WaitEndOfInit {
+		timer := time.NewTimer(s.EndOfInitTimeout)
+		select {
+		case <-sf.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			sf.Warn("startup: no end of initialization within %v", s.EndOfInitTimeout)
+		case <-sf.endOfInit:
+			timer.Stop()
+		}
+	}
+
+	for {
+		if !sf.startupSteps() {
+			return
+		}
+		// 等待从站重启后的初始化结束
+		select {
+		case <-sf.ctx.Done():
+			return
+		case <-sf.endOfInit:
+		}
+		sf.Debug("startup: end of initialization received, restart steps")
+	}
+}
+
+// startupSteps 按顺序执行各步骤, 执行中收到初始化结束时从头执行, 连接断开时返回false
+func (sf *Client) startupSteps() bool {
+	s := sf.option.startup
+RESTART:
+	for {
+		for _, step := range s.Steps {
+			err := sf.startupStep(step)
+			switch {
+			case err == errEndOfInit:
+				sf.Debug("startup: end of initialization received, restart steps")
+				continue RESTART
+			case sf.ctx.Err() != nil:
+				return false
+			case err != nil:
+				sf.Error("startup: %v failed, %v", step, err)
+			}
+		}
+		return true
+	}
+}
+
+// startupStep 执行一个步骤, 失败后重试
+func (sf *Client) startupStep(step StartupStep) error {
+	s := sf.option.startup
+	j := &Job{CommonAddr: s.CommonAddr, QOI: s.QOI, QCC: s.QCC, Timeout: s.Timeout}
+	switch step {
+	case StartupInterrogation:
+		j.Kind = JobInterrogation
+	case StartupClockSync:
+		j.Kind = JobClockSync
+	case StartupCounterInterrogation:
+		j.Kind = JobCounterInterrogation
+	default:
+		return fmt.Errorf("unknown step %v", step)
+	}
+	for i := 0; ; i++ {
+		err := sf.runJob(j, true)
+		if err == nil || err == errEndOfInit || sf.ctx.Err() != nil || i >= s.Retries {
+			return err
+		}
+		sf.Warn("startup: %v failed, %v, retry", step, err)
+	}
+}
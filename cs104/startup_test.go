@@ -0,0 +1,72 @@
+package cs104
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+func TestClient_Startup(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	srv := NewServer(&mockStation{})
+	go srv.ListenAndServer(addr)
+	defer srv.Close()
+
+	sent := make(chan string, 64)
+	s := DefaultStartup(1)
+	s.Steps = []StartupStep{StartupClockSync, StartupInterrogation}
+	o := NewOption().SetReconnectInterval(50 * time.Millisecond).SetStartup(&s)
+	if err = o.AddRemoteServer(addr); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(&mockClientHandler{}, o).SetObserver(ObserverFunc(func(f *Frame) {
+		switch {
+		case f.Direction != DirSent:
+		case f.Control.Type == FrameU:
+			sent <- f.Control.String()
+		case f.Control.Type == FrameI:
+			sent <- f.ASDU.Type.String()
+		}
+	}))
+	if err = client.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	next := func(n int) []string {
+		var got []string
+		for len(got) < n {
+			select {
+			case v := <-sent:
+				got = append(got, v)
+			case <-time.After(3 * time.Second):
+				t.Fatalf("timeout waiting frame, got %q", got)
+			}
+		}
+		return got
+	}
+	want := []string{Control{Type: FrameU, Function: UStartDtActive}.String(), asdu.C_CS_NA_1.String(), asdu.C_IC_NA_1.String()}
+	if got := next(3); !reflect.DeepEqual(got, want) {
+		t.Fatalf("startup sent %q, want %q", got, want)
+	}
+
+	// 从站重启后重新执行
+	time.Sleep(50 * time.Millisecond)
+	if err = asdu.EndOfInitialization(srv, asdu.CauseOfTransmission{Cause: asdu.Initialized}, 1,
+		asdu.InfoObjAddrIrrelevant, asdu.CauseOfInitial{Cause: asdu.COILocalPowerOn}); err != nil {
+		t.Fatal(err)
+	}
+	want = want[1:]
+	if got := next(2); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after end of initialization sent %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package cs104
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ConnState 客户端的连接状态
+type ConnState int
+
+// 连接状态
+const (
+	StateConnecting   ConnState = iota // 正在建立TCP连接
+	StateConnected                     // TCP已连接, 数据传输未激活
+	StateActive                        // STARTDT已确认, 数据传输激活
+	StateStopped                       // STOPDT已确认, 数据传输停止
+	StateDisconnected                  // 连接断开或建立连接失败
+)
+
+// String returns the name of state
+func (sf ConnState) String() string {
+	switch sf {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateActive:
+		return "active"
+	case StateStopped:
+		return "stopped"
+	case StateDisconnected:
+		return "disconnected"
+	}
+	return fmt.Sprintf("ConnState(%d)", int(sf))
+}
+
+// StateEvent 连接状态的变化
+type StateEvent struct {
+	State ConnState
+	// StateDisconnected 的原因: *DialError, ErrT1Timeout, ErrTestFrTimeout, ErrSequence,
+	// ErrRemoteClosed, ErrUseClosedConnection(Close) 或读写连接的错误
+	Err error
+}
+
+// SetStateHandler set the handler called when the connection state changes,
+// it is called in the state machine, so it should return quickly.
+func (sf *Client) SetStateHandler(f func(c *Client, e StateEvent)) *Client {
+	sf.stateMux.Lock()
+	sf.stateHandler = f
+	sf.stateMux.Unlock()
+	return sf
+}
+
+// Connect start the client and block until the connection is established and STARTDT is confirmed.
+// STARTDT is sent by Connect if no startup sequence is set.
+// The client is closed if it returns an error, such as dial failed, connection lost or ctx done,
+// ErrUseClosedConnection if Close is called before the connection is active,
+// after success it reconnects as Start when the connection is lost.
+func (sf *Client) Connect(ctx context.Context) error {
+	if sf.connectStatus() != initial {
+		return errors.New("client already started")
+	}
+	ch := make(chan StateEvent, 16)
+	sf.stateMux.Lock()
+	sf.stateWaiters = append(sf.stateWaiters, ch)
+	sf.stateMux.Unlock()
+	defer func() {
+		sf.stateMux.Lock()
+		for i, v := range sf.stateWaiters {
+			if v == ch {
+				sf.stateWaiters = append(sf.stateWaiters[:i], sf.stateWaiters[i+1:]...)
+				break
+			}
+		}
+		sf.stateMux.Unlock()
+	}()
+
+	if err := sf.Start(); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			_ = sf.Close()
+			return ctx.Err()
+		case e := <-ch:
+			switch e.State {
+			case StateConnected:
+				if sf.option.startup == nil {
+					sf.SendStartDt()
+				}
+			case StateActive:
+				return nil
+			case StateDisconnected:
+				_ = sf.Close()
+				return e.Err
+			}
+		}
+	}
+}
+
+// setState 通知连接状态的变化
+func (sf *Client) setState(s ConnState, err error) {
+	e := StateEvent{s, err}
+	sf.stateMux.Lock()
+	for _, ch := range sf.stateWaiters {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	f := sf.stateHandler
+	sf.stateMux.Unlock()
+	if f != nil {
+		f(sf, e)
+	}
+}
+
+// setLostErr 记录recvLoop/sendLoop的错误, 只保留第一个
+func (sf *Client) setLostErr(err error) {
+	sf.rwMux.Lock()
+	if sf.lostErr == nil {
+		sf.lostErr = err
+	}
+	sf.rwMux.Unlock()
+}
@@ -0,0 +1,131 @@
+package cs104
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// rawServer accept a connection and serve it with f
+func rawServer(t *testing.T, f func(conn net.Conn)) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		f(conn)
+	}()
+	return l.Addr().String()
+}
+
+func TestClient_Connect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := l.Addr().String()
+	l.Close()
+
+	var dialErr *DialError
+	tests := []struct {
+		name    string
+		addr    string
+		wantErr func(err error) bool
+	}{
+		{"dial failed", closedAddr, func(err error) bool { return errors.As(err, &dialErr) }},
+		{"startdt timeout", rawServer(t, func(conn net.Conn) { _, _ = io.Copy(ioutil.Discard, conn) }),
+			func(err error) bool { return err == ErrT1Timeout }},
+		{"remote closed", rawServer(t, func(net.Conn) {}),
+			func(err error) bool { return err == ErrRemoteClosed }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.SendUnAckTimeout1 = time.Second
+			o := NewOption().SetConfig(cfg).SetAutoReconnect(false)
+			if err := o.AddRemoteServer(tt.addr); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			err := NewClient(&mockClientHandler{}, o).Connect(ctx)
+			if !tt.wantErr(err) {
+				t.Errorf("Client.Connect() = %v", err)
+			}
+		})
+	}
+}
+
+func TestClient_ConnectClosedWhileDialing(t *testing.T) {
+	// TLS握手无应答, 连接一直进行中
+	addr := rawServer(t, func(conn net.Conn) { _, _ = io.Copy(ioutil.Discard, conn) })
+	o := NewOption()
+	if err := o.AddRemoteServer("tcps://" + addr); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(&mockClientHandler{}, o)
+	result := make(chan error, 1)
+	go func() { result <- client.Connect(context.Background()) }()
+
+	time.Sleep(200 * time.Millisecond)
+	_ = client.Close()
+	select {
+	case err := <-result:
+		if err != ErrUseClosedConnection {
+			t.Errorf("Client.Connect() = %v, want %v", err, ErrUseClosedConnection)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Client.Connect() not returned after Close")
+	}
+}
+
+func TestClient_SetStateHandler(t *testing.T) {
+	addr := rawServer(t, func(conn net.Conn) {
+		b := make([]byte, 6)
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return
+		}
+		_, _ = conn.Write(newUFrame(uStartDtConfirm))
+		a, _ := newSendASDU().MarshalBinary()
+		apdu, _ := newIFrame(5, 0, a) // 发送序号错误
+		_, _ = conn.Write(apdu)
+		_, _ = io.Copy(ioutil.Discard, conn)
+	})
+	o := NewOption().SetAutoReconnect(false)
+	if err := o.AddRemoteServer(addr); err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan StateEvent, 16)
+	client := NewClient(&mockClientHandler{}, o).SetStateHandler(func(c *Client, e StateEvent) { events <- e })
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var got []StateEvent
+	for len(got) < 4 {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-ctx.Done():
+			t.Fatalf("timeout waiting state events, got %v", got)
+		}
+	}
+	want := []StateEvent{{StateConnecting, nil}, {StateConnected, nil}, {StateActive, nil}, {StateDisconnected, ErrSequence}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("state events %v, want %v", got, want)
+	}
+}
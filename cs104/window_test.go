@@ -0,0 +1,128 @@
+package cs104
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// countIFrames read APDUs from conn until d passed, returns the number of I-frames
+func countIFrames(t *testing.T, conn net.Conn, d time.Duration) int {
+	t.Helper()
+	n := 0
+	_ = conn.SetReadDeadline(time.Now().Add(d))
+	head := make([]byte, 2)
+	for {
+		if _, err := io.ReadFull(conn, head); err != nil {
+			return n
+		}
+		if head[0] != startFrame {
+			t.Fatalf("unexpected start byte %#02x", head[0])
+		}
+		body := make([]byte, head[1])
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return n
+		}
+		if body[0]&0x01 == 0 {
+			n++
+		}
+	}
+}
+
+func TestSendWindow(t *testing.T) {
+	const k = 2
+	cfg := DefaultConfig()
+	cfg.SendUnAckLimitK = k
+
+	coa := asdu.CauseOfTransmission{Cause: asdu.Spontaneous}
+	tests := []struct {
+		name string
+		// run start the endpoint under test, returns the peer connection after STARTDT confirmed
+		// and the connect used to send ASDU
+		run func(t *testing.T) (net.Conn, asdu.Connect)
+	}{
+		{"server session", func(t *testing.T) (net.Conn, asdu.Connect) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr := l.Addr().String()
+			l.Close()
+			srv := NewServer(&mockStation{}).SetConfig(cfg)
+			go srv.ListenAndServer(addr)
+			t.Cleanup(func() { _ = srv.Close() })
+
+			var conn net.Conn
+			for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+				if conn, err = net.Dial("tcp", addr); err == nil {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal(err)
+				}
+			}
+			t.Cleanup(func() { conn.Close() })
+			if _, err = conn.Write(newUFrame(uStartDtActive)); err != nil {
+				t.Fatal(err)
+			}
+			con := make([]byte, 6)
+			if _, err = io.ReadFull(conn, con); err != nil {
+				t.Fatal(err)
+			}
+			return conn, srv
+		}},
+		{"client", func(t *testing.T) (net.Conn, asdu.Connect) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { l.Close() })
+			o := NewOption().SetConfig(cfg)
+			if err = o.AddRemoteServer(l.Addr().String()); err != nil {
+				t.Fatal(err)
+			}
+			client := NewClient(&mockClientHandler{}, o)
+			client.SetOnConnectHandler(func(c *Client) { c.SendStartDt() })
+			if err = client.Start(); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = client.Close() })
+
+			conn, err := l.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { conn.Close() })
+			act := make([]byte, 6)
+			if _, err = io.ReadFull(conn, act); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = conn.Write(newUFrame(uStartDtConfirm)); err != nil {
+				t.Fatal(err)
+			}
+			for deadline := time.Now().Add(time.Second); !client.IsActive(); time.Sleep(10 * time.Millisecond) {
+				if time.Now().After(deadline) {
+					t.Fatal("timeout waiting STARTDT con")
+				}
+			}
+			return conn, client
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, c := tt.run(t)
+			// 对端不确认, 最多发送k个I帧
+			for i := 0; i < k+3; i++ {
+				if err := asdu.Single(c, false, coa, 1, asdu.SinglePointInfo{Ioa: asdu.InfoObjAddr(i + 1)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if n := countIFrames(t, conn, 500*time.Millisecond); n != k {
+				t.Errorf("sent %d I-frames without acknowledge, want %d", n, k)
+			}
+		})
+	}
+}
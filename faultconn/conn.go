@@ -0,0 +1,378 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+// Package faultconn wrap a net.Conn to inject faults into the cs104 APDU stream, like
+// latency, dropped, duplicated, split or corrupted frames and stalled writes.
+//
+// The faults follow a schedule of rules matched against every APDU read or written,
+// the random choices are taken from a source seeded by Schedule.Seed, so the same
+// frame sequence always gets the same faults. Use Injector.Wrap with
+// cs104.ClientOption.SetConnWrapper or cs104.Server.SetConnWrapper.
+package faultconn
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+// ErrClosed 连接已关闭, 阻塞中的延迟被中断
+var ErrClosed = errors.New("faultconn: use of closed connection")
+
+// Direction 帧的方向
+type Direction byte
+
+// 帧的方向
+const (
+	Read  Direction = 1 << iota // 从对端接收的帧
+	Write                       // 发送到对端的帧
+	Both  = Read | Write
+)
+
+func (sf Direction) String() string {
+	switch sf {
+	case Read:
+		return "read"
+	case Write:
+		return "write"
+	}
+	return "both"
+}
+
+// Action 注入的故障
+type Action byte
+
+// 注入的故障
+const (
+	Delay     Action = iota // 延迟 Delay 后传输, 其后的帧同时被延迟
+	Drop                    // 丢弃
+	Duplicate               // 重复传输
+	Split                   // 分成 Chunk 字节多次传输
+	Corrupt                 // 随机修改一个字节
+	Stall                   // 阻塞 Delay, 0 阻塞到连接关闭
+)
+
+func (sf Action) String() string {
+	switch sf {
+	case Delay:
+		return "delay"
+	case Drop:
+		return "drop"
+	case Duplicate:
+		return "duplicate"
+	case Split:
+		return "split"
+	case Corrupt:
+		return "corrupt"
+	case Stall:
+		return "stall"
+	}
+	return "unknown"
+}
+
+// Rule 故障规则, 匹配的帧按规则的顺序依次注入故障
+type Rule struct {
+	Dir         Direction         // 匹配的方向
+	Types       []cs104.FrameType // 匹配的帧类型, 空匹配所有类型
+	Skip        int               // 跳过前 Skip 个匹配的帧
+	Count       int               // 最多注入的帧数, 0 不限
+	Probability float64           // 注入的概率, 0 总是注入
+	Action      Action
+	Delay       time.Duration // Delay, Stall 的时间
+	Jitter      time.Duration // Delay 随机增加 [0, Jitter) 的时间
+	Chunk       int           // Split 每次传输的字节数, 0 为 1
+}
+
+// match 是否匹配帧
+func (sf *Rule) match(dir Direction, t cs104.FrameType) bool {
+	if sf.Dir&dir == 0 {
+		return false
+	}
+	if len(sf.Types) == 0 {
+		return true
+	}
+	for _, v := range sf.Types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Schedule 故障计划
+type Schedule struct {
+	Seed  int64
+	Rules []Rule
+}
+
+// Event 一次注入的故障
+type Event struct {
+	Time    time.Time
+	Dir     Direction
+	Control cs104.Control
+	Action  Action
+}
+
+// fault 一个帧上要注入的故障
+type fault struct {
+	action Action
+	delay  time.Duration
+	chunk  int
+	pos    int  // Corrupt 的位置
+	mask   byte // Corrupt 的异或值
+}
+
+// Conn 注入故障的连接
+type Conn struct {
+	net.Conn
+
+	mu      sync.Mutex // 保护 rules, seen, applied, rnd, events
+	rules   []Rule
+	seen    []int
+	applied []int
+	rnd     *rand.Rand
+	events  []Event
+
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	wmu  sync.Mutex
+	wbuf []byte // 未组成完整APDU的发送数据
+
+	rmu  sync.Mutex
+	rraw []byte   // 未组成完整APDU的接收数据
+	rout [][]byte // 待读取的数据段
+	rerr error
+}
+
+// New 以故障计划包装连接
+func New(c net.Conn, s Schedule) *Conn {
+	return &Conn{
+		Conn:    c,
+		rules:   s.Rules,
+		seen:    make([]int, len(s.Rules)),
+		applied: make([]int, len(s.Rules)),
+		rnd:     rand.New(rand.NewSource(s.Seed)),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Events 返回已注入的故障
+func (sf *Conn) Events() []Event {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	return append([]Event(nil), sf.events...)
+}
+
+// Write imp net.Conn, 完整的APDU按计划注入故障后写入
+func (sf *Conn) Write(b []byte) (int, error) {
+	sf.wmu.Lock()
+	defer sf.wmu.Unlock()
+
+	sf.wbuf = append(sf.wbuf, b...)
+	for {
+		advance, token, _ := cs104.ScanAPDU(sf.wbuf, false)
+		if advance == 0 {
+			break
+		}
+		var err error
+		if token == nil {
+			_, err = sf.Conn.Write(sf.wbuf[:advance])
+		} else {
+			err = sf.inject(Write, token, func(seg []byte) error {
+				_, err := sf.Conn.Write(seg)
+				return err
+			})
+		}
+		sf.wbuf = sf.wbuf[advance:]
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Read imp net.Conn, 接收的APDU按计划注入故障后读出
+func (sf *Conn) Read(p []byte) (int, error) {
+	sf.rmu.Lock()
+	defer sf.rmu.Unlock()
+
+	for len(sf.rout) == 0 {
+		if sf.rerr != nil {
+			return 0, sf.rerr
+		}
+		buf := make([]byte, cs104.APDUSizeMax)
+		n, err := sf.Conn.Read(buf)
+		sf.rraw = append(sf.rraw, buf[:n]...)
+		for {
+			advance, token, _ := cs104.ScanAPDU(sf.rraw, false)
+			if advance == 0 {
+				break
+			}
+			if token == nil {
+				sf.rout = append(sf.rout, append([]byte(nil), sf.rraw[:advance]...))
+			} else if e := sf.inject(Read, token, func(seg []byte) error {
+				sf.rout = append(sf.rout, seg)
+				return nil
+			}); e != nil {
+				err = e
+			}
+			sf.rraw = sf.rraw[advance:]
+		}
+		if err != nil {
+			if len(sf.rraw) > 0 {
+				sf.rout = append(sf.rout, sf.rraw)
+				sf.rraw = nil
+			}
+			sf.rerr = err
+		}
+	}
+	n := copy(p, sf.rout[0])
+	if n < len(sf.rout[0]) {
+		sf.rout[0] = sf.rout[0][n:]
+	} else {
+		sf.rout = sf.rout[1:]
+	}
+	return n, nil
+}
+
+// Close imp net.Conn, 中断阻塞中的延迟
+func (sf *Conn) Close() error {
+	sf.closeOnce.Do(func() { close(sf.closed) })
+	return sf.Conn.Close()
+}
+
+// faults 返回帧要注入的故障
+func (sf *Conn) faults(dir Direction, apdu []byte) []fault {
+	ctl, _, err := cs104.ParseAPDU(apdu)
+	if err != nil {
+		return nil
+	}
+
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	var fs []fault
+	for i := range sf.rules {
+		r := &sf.rules[i]
+		if !r.match(dir, ctl.Type) {
+			continue
+		}
+		sf.seen[i]++
+		if sf.seen[i] <= r.Skip || (r.Count > 0 && sf.applied[i] >= r.Count) {
+			continue
+		}
+		if r.Probability > 0 && sf.rnd.Float64() >= r.Probability {
+			continue
+		}
+		sf.applied[i]++
+		f := fault{action: r.Action, delay: r.Delay, chunk: r.Chunk}
+		switch r.Action {
+		case Delay:
+			if r.Jitter > 0 {
+				f.delay += time.Duration(sf.rnd.Int63n(int64(r.Jitter)))
+			}
+		case Split:
+			if f.chunk <= 0 {
+				f.chunk = 1
+			}
+		case Corrupt:
+			f.pos = sf.rnd.Intn(len(apdu))
+			f.mask = byte(sf.rnd.Intn(255) + 1)
+		}
+		fs = append(fs, f)
+		sf.events = append(sf.events, Event{time.Now(), dir, ctl, r.Action})
+	}
+	return fs
+}
+
+// inject 注入故障, 将结果数据段交给out
+func (sf *Conn) inject(dir Direction, apdu []byte, out func(seg []byte) error) error {
+	data := append([]byte(nil), apdu...)
+	copies, chunk := 1, 0
+	for _, f := range sf.faults(dir, apdu) {
+		switch f.action {
+		case Delay, Stall:
+			if err := sf.wait(f.action, f.delay); err != nil {
+				return err
+			}
+		case Drop:
+			return nil
+		case Duplicate:
+			copies++
+		case Split:
+			chunk = f.chunk
+		case Corrupt:
+			data[f.pos] ^= f.mask
+		}
+	}
+
+	for ; copies > 0; copies-- {
+		if chunk == 0 {
+			if err := out(append([]byte(nil), data...)); err != nil {
+				return err
+			}
+			continue
+		}
+		for i := 0; i < len(data); i += chunk {
+			end := i + chunk
+			if end > len(data) {
+				end = len(data)
+			}
+			if err := out(append([]byte(nil), data[i:end]...)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// wait 等待延迟, Stall 的延迟为0时等待到连接关闭
+func (sf *Conn) wait(action Action, d time.Duration) error {
+	if action == Stall && d == 0 {
+		<-sf.closed
+		return ErrClosed
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-sf.closed:
+		return ErrClosed
+	}
+}
+
+// Injector 按同一故障计划包装多个连接, 第n个连接的随机种子为 Seed+n
+type Injector struct {
+	schedule Schedule
+	mu       sync.Mutex
+	conns    []*Conn
+}
+
+// NewInjector 创建故障注入者
+func NewInjector(s Schedule) *Injector {
+	return &Injector{schedule: s}
+}
+
+// Wrap 包装连接, 用于 cs104.ClientOption.SetConnWrapper 或 cs104.Server.SetConnWrapper
+func (sf *Injector) Wrap(c net.Conn) net.Conn {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	s := sf.schedule
+	s.Seed += int64(len(sf.conns))
+	conn := New(c, s)
+	sf.conns = append(sf.conns, conn)
+	return conn
+}
+
+// Conns 返回已包装的连接
+func (sf *Injector) Conns() []*Conn {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	return append([]*Conn(nil), sf.conns...)
+}
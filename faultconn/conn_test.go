@@ -0,0 +1,264 @@
+package faultconn
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+var (
+	iFrame = []byte{0x68, 0x0e, 0x00, 0x00, 0x00, 0x00, 0x64, 0x01, 0x06, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14}
+	sFrame = []byte{0x68, 0x04, 0x01, 0x00, 0x02, 0x00}
+	uFrame = []byte{0x68, 0x04, 0x07, 0x00, 0x00, 0x00}
+)
+
+func join(b ...[]byte) []byte { return bytes.Join(b, nil) }
+
+func TestConn_Write(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Rule
+		in    [][]byte
+		want  []byte
+	}{
+		{"none", nil, [][]byte{iFrame, sFrame}, join(iFrame, sFrame)},
+		{"drop s", []Rule{{Dir: Write, Types: []cs104.FrameType{cs104.FrameS}, Action: Drop}},
+			[][]byte{iFrame, sFrame, uFrame}, join(iFrame, uFrame)},
+		{"drop read only", []Rule{{Dir: Read, Action: Drop}}, [][]byte{iFrame}, iFrame},
+		{"duplicate", []Rule{{Dir: Both, Types: []cs104.FrameType{cs104.FrameI}, Action: Duplicate}},
+			[][]byte{iFrame, sFrame}, join(iFrame, iFrame, sFrame)},
+		{"skip count", []Rule{{Dir: Write, Skip: 1, Count: 1, Action: Drop}},
+			[][]byte{sFrame, uFrame, iFrame}, join(sFrame, iFrame)},
+		{"split write", []Rule{{Dir: Write, Action: Split, Chunk: 4}}, [][]byte{iFrame[:3], iFrame[3:]}, iFrame},
+		{"garbage", nil, [][]byte{{0x01, 0x02}, uFrame}, join([]byte{0x01, 0x02}, uFrame)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			c := New(&pipe{w: &out}, Schedule{Rules: tt.rules})
+			for _, b := range tt.in {
+				if n, err := c.Write(b); err != nil || n != len(b) {
+					t.Fatalf("Write() = %d, %v", n, err)
+				}
+			}
+			if !bytes.Equal(out.Bytes(), tt.want) {
+				t.Errorf("written % x, want % x", out.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestConn_Read(t *testing.T) {
+	c := New(&pipe{r: bytes.NewReader(join(uFrame, iFrame, sFrame))}, Schedule{Rules: []Rule{
+		{Dir: Read, Types: []cs104.FrameType{cs104.FrameI}, Action: Split, Chunk: 6},
+		{Dir: Read, Types: []cs104.FrameType{cs104.FrameS}, Action: Drop},
+	}})
+	var reads [][]byte
+	buf := make([]byte, 255)
+	for {
+		n, err := c.Read(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		reads = append(reads, append([]byte(nil), buf[:n]...))
+	}
+	want := [][]byte{uFrame, iFrame[:6], iFrame[6:12], iFrame[12:]}
+	if len(reads) != len(want) {
+		t.Fatalf("Read() %d times, want %d", len(reads), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(reads[i], want[i]) {
+			t.Errorf("Read() #%d % x, want % x", i, reads[i], want[i])
+		}
+	}
+	if ev := c.Events(); len(ev) != 2 || ev[0].Action != Split || ev[1].Action != Drop || ev[1].Control.Type != cs104.FrameS {
+		t.Errorf("Events() = %+v", ev)
+	}
+}
+
+func TestConn_deterministic(t *testing.T) {
+	s := Schedule{Seed: 42, Rules: []Rule{{Dir: Write, Probability: 0.5, Action: Corrupt}}}
+	run := func() []byte {
+		var out bytes.Buffer
+		c := New(&pipe{w: &out}, s)
+		for i := 0; i < 20; i++ {
+			_, _ = c.Write(iFrame)
+		}
+		return out.Bytes()
+	}
+	a, b := run(), run()
+	if !bytes.Equal(a, b) {
+		t.Fatal("same seed got different faults")
+	}
+	if bytes.Equal(a, bytes.Repeat(iFrame, 20)) {
+		t.Fatal("no fault injected")
+	}
+}
+
+func TestConn_delay(t *testing.T) {
+	var out bytes.Buffer
+	c := New(&pipe{w: &out}, Schedule{Rules: []Rule{
+		{Dir: Write, Action: Delay, Delay: 50 * time.Millisecond, Jitter: 10 * time.Millisecond},
+	}})
+	start := time.Now()
+	_, _ = c.Write(sFrame)
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("Write() returned after %v, want delay 50ms", d)
+	}
+
+	c = New(&pipe{w: &out}, Schedule{Rules: []Rule{{Dir: Write, Action: Stall}}})
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		c.Close()
+	}()
+	if _, err := c.Write(sFrame); err != ErrClosed {
+		t.Errorf("stalled Write() error = %v, want %v", err, ErrClosed)
+	}
+}
+
+// pipe is a net.Conn reads from r and writes to w
+type pipe struct {
+	net.Conn
+	r io.Reader
+	w io.Writer
+}
+
+func (sf *pipe) Read(b []byte) (int, error)  { return sf.r.Read(b) }
+func (sf *pipe) Write(b []byte) (int, error) { return sf.w.Write(b) }
+func (sf *pipe) Close() error                { return nil }
+
+// station answer interrogation with ten points
+type station struct{}
+
+func (station) InterrogationHandler(c asdu.Connect, a *asdu.ASDU, _ asdu.QualifierOfInterrogation) error {
+	_ = a.SendReplyMirror(c, asdu.ActivationCon)
+	for i := 0; i < 10; i++ {
+		_ = asdu.Single(c, false, asdu.CauseOfTransmission{Cause: asdu.InterrogatedByStation}, a.CommonAddr,
+			asdu.SinglePointInfo{Ioa: asdu.InfoObjAddr(100 + i), Value: true})
+	}
+	return a.SendReplyMirror(c, asdu.ActivationTerm)
+}
+func (station) CounterInterrogationHandler(asdu.Connect, *asdu.ASDU, asdu.QualifierCountCall) error {
+	return nil
+}
+func (station) ReadHandler(asdu.Connect, *asdu.ASDU, asdu.InfoObjAddr) error { return nil }
+func (station) ClockSyncHandler(asdu.Connect, *asdu.ASDU, time.Time) error   { return nil }
+func (station) ResetProcessHandler(asdu.Connect, *asdu.ASDU, asdu.QualifierOfResetProcessCmd) error {
+	return nil
+}
+func (station) DelayAcquisitionHandler(asdu.Connect, *asdu.ASDU, uint16) error { return nil }
+func (station) ASDUHandler(asdu.Connect, *asdu.ASDU) error                     { return nil }
+
+// master signal the interrogation termination
+type master struct {
+	term chan struct{}
+}
+
+func (sf *master) InterrogationHandler(_ asdu.Connect, a *asdu.ASDU) error {
+	if a.Coa.Cause == asdu.ActivationTerm {
+		sf.term <- struct{}{}
+	}
+	return nil
+}
+func (sf *master) CounterInterrogationHandler(asdu.Connect, *asdu.ASDU) error { return nil }
+func (sf *master) ReadHandler(asdu.Connect, *asdu.ASDU) error                 { return nil }
+func (sf *master) TestCommandHandler(asdu.Connect, *asdu.ASDU) error          { return nil }
+func (sf *master) ClockSyncHandler(asdu.Connect, *asdu.ASDU) error            { return nil }
+func (sf *master) ResetProcessHandler(asdu.Connect, *asdu.ASDU) error         { return nil }
+func (sf *master) DelayAcquisitionHandler(asdu.Connect, *asdu.ASDU) error     { return nil }
+func (sf *master) ASDUHandler(asdu.Connect, *asdu.ASDU) error                 { return nil }
+
+func TestClientServer(t *testing.T) {
+	config := cs104.Config{SendUnAckTimeout1: time.Second, RecvUnAckTimeout2: time.Second, IdleTimeout3: 5 * time.Second}
+	tests := []struct {
+		name   string
+		client []Rule
+		server []Rule
+		lost   bool // 连接应被关闭
+	}{
+		{"split reads", nil, []Rule{{Dir: Read, Action: Split, Chunk: 1}, {Dir: Write, Action: Split, Chunk: 3}}, false},
+		{"latency", []Rule{{Dir: Both, Action: Delay, Delay: 5 * time.Millisecond, Jitter: 5 * time.Millisecond}}, nil, false},
+		{"ack dropped", []Rule{{Dir: Write, Types: []cs104.FrameType{cs104.FrameS}, Action: Drop}}, nil, true},
+		{"ack delayed past t1", nil, []Rule{{Dir: Read, Types: []cs104.FrameType{cs104.FrameS}, Action: Delay, Delay: 1500 * time.Millisecond}}, true},
+		{"duplicate I frame", []Rule{{Dir: Write, Types: []cs104.FrameType{cs104.FrameI}, Action: Duplicate}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr := l.Addr().String()
+			l.Close()
+
+			srv := cs104.NewServer(station{})
+			srv.SetConfig(config)
+			srv.SetConnWrapper(NewInjector(Schedule{Seed: 1, Rules: tt.server}).Wrap)
+			go srv.ListenAndServer(addr)
+			defer srv.Close()
+
+			m := &master{make(chan struct{}, 1)}
+			var lost int32
+			o := cs104.NewOption().SetConfig(config).SetAutoReconnect(false).
+				SetConnWrapper(NewInjector(Schedule{Seed: 1, Rules: tt.client}).Wrap)
+			if err = o.AddRemoteServer(addr); err != nil {
+				t.Fatal(err)
+			}
+			client := cs104.NewClient(m, o)
+			client.SetOnConnectHandler(func(c *cs104.Client) { c.SendStartDt() })
+			client.SetConnectionLostHandler(func(*cs104.Client) { atomic.StoreInt32(&lost, 1) })
+			for i := 0; i < 50 && !client.IsActive(); i++ {
+				if !client.IsConnected() {
+					_ = client.Start()
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			defer client.Close()
+			if !client.IsActive() {
+				t.Fatal("client not active")
+			}
+
+			if err = client.InterrogationCmd(asdu.CauseOfTransmission{Cause: asdu.Activation}, 1, asdu.QOIStation); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case <-m.term:
+			case <-time.After(2 * time.Second):
+				if !tt.lost {
+					t.Fatal("no interrogation termination")
+				}
+			}
+			if !tt.lost {
+				rd := asdu.NewASDU(client.Params(), asdu.Identifier{
+					Type:       asdu.C_RD_NA_1,
+					Variable:   asdu.VariableStruct{Number: 1},
+					Coa:        asdu.CauseOfTransmission{Cause: asdu.Request},
+					CommonAddr: 1,
+				})
+				_ = rd.AppendInfoObjAddr(100)
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				err = client.SendWithAck(ctx, rd).Wait(ctx)
+				cancel()
+				if err != nil {
+					t.Errorf("SendWithAck() error = %v", err)
+				}
+			}
+			for i := 0; i < 30 && tt.lost && atomic.LoadInt32(&lost) == 0; i++ {
+				time.Sleep(100 * time.Millisecond)
+			}
+			if got := atomic.LoadInt32(&lost) == 1; got != tt.lost {
+				t.Errorf("connection lost = %v, want %v", got, tt.lost)
+			}
+		})
+	}
+}
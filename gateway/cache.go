@@ -0,0 +1,138 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package gateway
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// degrade 链路中断时置位的品质描述词
+const degrade = byte(asdu.QDSNotTopical | asdu.QDSInvalid)
+
+// cacheType 监视方向可被总召唤的类型, 带时标的类型映射到不带时标的类型
+// value: 不带时标的类型, 信息元素长度, 品质描述词偏移, 品质描述词掩码
+var cacheType = map[asdu.TypeID]struct {
+	base    asdu.TypeID
+	size    int
+	offset  int
+	quality byte
+}{
+	asdu.M_SP_NA_1: {asdu.M_SP_NA_1, 1, 0, degrade},
+	asdu.M_SP_TA_1: {asdu.M_SP_NA_1, 1, 0, degrade},
+	asdu.M_SP_TB_1: {asdu.M_SP_NA_1, 1, 0, degrade},
+	asdu.M_DP_NA_1: {asdu.M_DP_NA_1, 1, 0, degrade},
+	asdu.M_DP_TA_1: {asdu.M_DP_NA_1, 1, 0, degrade},
+	asdu.M_DP_TB_1: {asdu.M_DP_NA_1, 1, 0, degrade},
+	asdu.M_ST_NA_1: {asdu.M_ST_NA_1, 2, 1, degrade},
+	asdu.M_ST_TA_1: {asdu.M_ST_NA_1, 2, 1, degrade},
+	asdu.M_ST_TB_1: {asdu.M_ST_NA_1, 2, 1, degrade},
+	asdu.M_BO_NA_1: {asdu.M_BO_NA_1, 5, 4, degrade},
+	asdu.M_BO_TA_1: {asdu.M_BO_NA_1, 5, 4, degrade},
+	asdu.M_BO_TB_1: {asdu.M_BO_NA_1, 5, 4, degrade},
+	asdu.M_ME_NA_1: {asdu.M_ME_NA_1, 3, 2, degrade},
+	asdu.M_ME_TA_1: {asdu.M_ME_NA_1, 3, 2, degrade},
+	asdu.M_ME_TD_1: {asdu.M_ME_NA_1, 3, 2, degrade},
+	asdu.M_ME_NB_1: {asdu.M_ME_NB_1, 3, 2, degrade},
+	asdu.M_ME_TB_1: {asdu.M_ME_NB_1, 3, 2, degrade},
+	asdu.M_ME_TE_1: {asdu.M_ME_NB_1, 3, 2, degrade},
+	asdu.M_ME_NC_1: {asdu.M_ME_NC_1, 5, 4, degrade},
+	asdu.M_ME_TC_1: {asdu.M_ME_NC_1, 5, 4, degrade},
+	asdu.M_ME_TF_1: {asdu.M_ME_NC_1, 5, 4, degrade},
+	asdu.M_IT_NA_1: {asdu.M_IT_NA_1, 5, 4, 0x80}, // 计数量顺序记法 IV
+	asdu.M_IT_TA_1: {asdu.M_IT_NA_1, 5, 4, 0x80},
+	asdu.M_IT_TB_1: {asdu.M_IT_NA_1, 5, 4, 0x80},
+	asdu.M_PS_NA_1: {asdu.M_PS_NA_1, 5, 4, degrade},
+}
+
+// point 缓存的点值, 不带时标的信息元素集
+type point struct {
+	typeID asdu.TypeID
+	data   []byte
+}
+
+// pointCache 缓存每个站最新的点值, 用于下行链路中断时应答总召唤和品质降级
+type pointCache struct {
+	mu       sync.Mutex
+	stations map[asdu.CommonAddr]map[asdu.InfoObjAddr]point
+}
+
+func newPointCache() *pointCache {
+	return &pointCache{stations: make(map[asdu.CommonAddr]map[asdu.InfoObjAddr]point)}
+}
+
+// update 用ASDU更新公共地址ca的站的点值,不可缓存的类型忽略
+func (sf *pointCache) update(ca asdu.CommonAddr, a *asdu.ASDU) {
+	ct, ok := cacheType[a.Type]
+	if !ok {
+		return
+	}
+	elems, err := splitElements(a)
+	if err != nil {
+		return
+	}
+
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	points, ok := sf.stations[ca]
+	if !ok {
+		points = make(map[asdu.InfoObjAddr]point)
+		sf.stations[ca] = points
+	}
+	for _, v := range elems {
+		points[v.ioa] = point{ct.base, v.data[:ct.size]}
+	}
+}
+
+// snapshot 返回公共地址ca的站的所有点值, 按类型分组, 信息对象地址升序
+// isDegraded 为true时置位品质描述词 NT 和 IV
+func (sf *pointCache) snapshot(ca asdu.CommonAddr, isDegraded bool) map[asdu.TypeID][]element {
+	sf.mu.Lock()
+	points := sf.stations[ca]
+	r := make(map[asdu.TypeID][]element)
+	for ioa, v := range points {
+		data := append([]byte(nil), v.data...)
+		if isDegraded {
+			ct := cacheType[v.typeID]
+			data[ct.offset] |= ct.quality
+		}
+		r[v.typeID] = append(r[v.typeID], element{ioa, data})
+	}
+	sf.mu.Unlock()
+
+	for _, elems := range r {
+		sort.Slice(elems, func(i, j int) bool { return elems[i].ioa < elems[j].ioa })
+	}
+	return r
+}
+
+// send 发送公共地址ca的站的所有点值
+func (sf *pointCache) send(c asdu.Connect, ca asdu.CommonAddr, cause asdu.Cause, isDegraded bool) error {
+	snap := sf.snapshot(ca, isDegraded)
+	types := make([]asdu.TypeID, 0, len(snap))
+	for t := range snap {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	for _, t := range types {
+		us, err := joinElements(c.Params(), asdu.Identifier{
+			Type:       t,
+			Coa:        asdu.CauseOfTransmission{Cause: cause},
+			CommonAddr: ca,
+		}, snap[t])
+		if err != nil {
+			return err
+		}
+		for _, u := range us {
+			if err = c.Send(u); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
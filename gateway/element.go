@@ -0,0 +1,130 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package gateway
+
+import (
+	"errors"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// element 信息对象, 信息对象地址及其信息元素集(含时标)
+type element struct {
+	ioa  asdu.InfoObjAddr
+	data []byte
+}
+
+var errInfoObjTruncated = errors.New("gateway: information object truncated")
+
+// splitElements 拆分ASDU的信息对象, SQ = 1 时顺序计算各信息对象地址.
+// 未知长度的类型仅支持单个信息对象
+func splitElements(a *asdu.ASDU) ([]element, error) {
+	raw, err := a.Clone().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	info := raw[a.IdentifierSize():]
+	addrSize := a.InfoObjAddrSize
+	n := int(a.Variable.Number)
+
+	objSize, err := asdu.GetInfoObjSize(a.Type)
+	if err != nil {
+		if n != 1 || a.Variable.IsSequence || len(info) < addrSize {
+			return nil, err
+		}
+		objSize = len(info) - addrSize
+	}
+
+	elems := make([]element, 0, n)
+	if a.Variable.IsSequence {
+		if len(info) < addrSize+n*objSize {
+			return nil, errInfoObjTruncated
+		}
+		ioa := decodeInfoObjAddr(info, addrSize)
+		info = info[addrSize:]
+		for i := 0; i < n; i++ {
+			elems = append(elems, element{ioa + asdu.InfoObjAddr(i), append([]byte(nil), info[:objSize]...)})
+			info = info[objSize:]
+		}
+		return elems, nil
+	}
+	if len(info) < n*(addrSize+objSize) {
+		return nil, errInfoObjTruncated
+	}
+	for i := 0; i < n; i++ {
+		ioa := decodeInfoObjAddr(info, addrSize)
+		info = info[addrSize:]
+		elems = append(elems, element{ioa, append([]byte(nil), info[:objSize]...)})
+		info = info[objSize:]
+	}
+	return elems, nil
+}
+
+// joinElements 将信息对象组装成一个或多个(SQ = 0)的ASDU, 每个ASDU不超过最大长度
+func joinElements(p *asdu.Params, id asdu.Identifier, elems []element) ([]*asdu.ASDU, error) {
+	var r []*asdu.ASDU
+	var u *asdu.ASDU
+	var size int
+
+	for _, v := range elems {
+		objLen := p.InfoObjAddrSize + len(v.data)
+		if u == nil || size+objLen > asdu.ASDUSizeMax || u.Variable.Number >= 127 {
+			u = asdu.NewASDU(p, id)
+			u.Variable = asdu.VariableStruct{}
+			size = p.IdentifierSize()
+			r = append(r, u)
+		}
+		if err := u.AppendInfoObjAddr(v.ioa); err != nil {
+			return nil, err
+		}
+		u.AppendBytes(v.data...)
+		u.Variable.Number++
+		size += objLen
+	}
+	return r, nil
+}
+
+// firstInfoObjAddr 获取ASDU第一个信息对象的地址,没有信息对象时返回 InfoObjAddrIrrelevant
+func firstInfoObjAddr(a *asdu.ASDU) asdu.InfoObjAddr {
+	raw, err := a.Clone().MarshalBinary()
+	if err != nil || len(raw) < a.IdentifierSize()+a.InfoObjAddrSize {
+		return asdu.InfoObjAddrIrrelevant
+	}
+	return decodeInfoObjAddr(raw[a.IdentifierSize():], a.InfoObjAddrSize)
+}
+
+func decodeInfoObjAddr(b []byte, size int) asdu.InfoObjAddr {
+	var ioa asdu.InfoObjAddr
+	for i := 0; i < size; i++ {
+		ioa |= asdu.InfoObjAddr(b[i]) << (8 * uint(i))
+	}
+	return ioa
+}
+
+// convert 以参数p和公共地址ca重新编码ASDU, 并按ioa映射信息对象地址,未映射的地址不变.
+// 信息对象地址长度等参数不同或需要映射时逐个信息对象转换
+func convert(p *asdu.Params, a *asdu.ASDU, ca asdu.CommonAddr, ioa map[asdu.InfoObjAddr]asdu.InfoObjAddr) ([]*asdu.ASDU, error) {
+	id := a.Identifier
+	id.CommonAddr = ca
+	if *p == *a.Params && len(ioa) == 0 {
+		r := a.Clone()
+		r.Identifier = id
+		return []*asdu.ASDU{r}, nil
+	}
+
+	elems, err := splitElements(a)
+	if err != nil {
+		return nil, err
+	}
+	if len(elems) == 0 {
+		return nil, errInfoObjTruncated
+	}
+	for i, v := range elems {
+		if addr, ok := ioa[v.ioa]; ok {
+			elems[i].ioa = addr
+		}
+	}
+	return joinElements(p, id, elems)
+}
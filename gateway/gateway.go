@@ -0,0 +1,240 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+// Package gateway republish the data of IEC101 stations or IEC104 RTUs on a single
+// IEC104 server, used as an RTU concentrator or a proxy.
+package gateway
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/clog"
+	"github.com/thinkgos/go-iecp5/cs101"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+// DefaultCommandTimeout 等待下行站命令确认的默认超时时间
+const DefaultCommandTimeout = 10 * time.Second
+
+// DefaultTerminationTimeout 命令确认后等待下行站激活终止的默认超时时间
+const DefaultTerminationTimeout = 5 * time.Minute
+
+// Route 地址的映射, Upstream 为104服务端发布的公共地址, Downstream 为下行站的公共地址,
+// IOA 为下行站到上行的信息对象地址映射, 未映射的地址不变.
+type Route struct {
+	Upstream   asdu.CommonAddr
+	Downstream asdu.CommonAddr
+	IOA        map[asdu.InfoObjAddr]asdu.InfoObjAddr
+}
+
+// route 已校验的地址映射
+type route struct {
+	Route
+	toDown map[asdu.InfoObjAddr]asdu.InfoObjAddr // upstream -> downstream
+}
+
+func newRoute(r Route) (*route, error) {
+	if r.Upstream == asdu.InvalidCommonAddr || r.Upstream == asdu.GlobalCommonAddr ||
+		r.Downstream == asdu.InvalidCommonAddr || r.Downstream == asdu.GlobalCommonAddr {
+		return nil, errors.New("gateway: invalid route common address")
+	}
+	rt := &route{r, make(map[asdu.InfoObjAddr]asdu.InfoObjAddr, len(r.IOA))}
+	for down, up := range r.IOA {
+		if _, ok := rt.toDown[up]; ok {
+			return nil, errors.New("gateway: duplicate upstream information object address")
+		}
+		rt.toDown[up] = down
+	}
+	return rt, nil
+}
+
+// Gateway 规约转换网关, 作为101启动站或104客户端连接下行站, 作为104服务端向主站发布数据.
+// 上行的命令映射地址后转发到下行站,确认,终止及召唤数据回复给发起命令的会话,
+// 下行链路中断时未确认的命令回复否定确认, 缓存的点值置位品质描述词 NT 和 IV 后发布,
+// 总召唤以缓存的点值应答. 全局地址的命令分发到所有的下行站.
+type Gateway struct {
+	server   *cs104.Server
+	router   *cs104.Router
+	upstream asdu.Connect // 无会话的上行数据发送者, 默认为server广播
+	cache    *pointCache
+	tracker  *tracker
+
+	mu    sync.Mutex
+	links []*link
+
+	clog.Clog
+}
+
+// New new a gateway with an IEC104 server, default asdu.ParamsWide params
+func New() *Gateway {
+	sf := &Gateway{
+		router:  cs104.NewRouter(),
+		cache:   newPointCache(),
+		tracker: newTracker(DefaultCommandTimeout, DefaultTerminationTimeout),
+		Clog:    clog.NewLogger("gateway => "),
+	}
+	sf.server = cs104.NewServer(sf.router)
+	sf.upstream = sf.server
+	return sf
+}
+
+// Server returns the IEC104 server, use it to config and listen.
+func (sf *Gateway) Server() *cs104.Server {
+	return sf.server
+}
+
+// SetCommandTimeout set the timeout waiting for the confirmation of the station
+func (sf *Gateway) SetCommandTimeout(t time.Duration) *Gateway {
+	if t > 0 {
+		sf.tracker.timeout = t
+	}
+	return sf
+}
+
+// SetTerminationTimeout set the timeout waiting for the activation termination after the command confirmed,
+// such as the interrogation over a slow link
+func (sf *Gateway) SetTerminationTimeout(t time.Duration) *Gateway {
+	if t > 0 {
+		sf.tracker.termTimeout = t
+	}
+	return sf
+}
+
+// AddCS101 add an IEC101 link on rw with stations configured in o,
+// routes map the address of the stations to the IEC104 server.
+func (sf *Gateway) AddCS101(rw io.ReadWriter, o *cs101.ClientOption, routes ...Route) (*cs101.Client, error) {
+	l := &link{gw: sf}
+	c := cs101.NewClient(rw, l, o)
+	c.SetLinkStatusHandler(func(_ *cs101.Client, ca asdu.CommonAddr, isOnline bool) {
+		l.linkStatus(ca, isOnline)
+	})
+	l.down = c
+	if err := sf.addLink(l, routes); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// AddCS104 add an IEC104 client connect to the RTU configured in o,
+// routes map the address of the stations of the RTU to the IEC104 server.
+// The connect, active and connection lost handler of the client are used by the gateway.
+func (sf *Gateway) AddCS104(o *cs104.ClientOption, routes ...Route) (*cs104.Client, error) {
+	var active uint32
+
+	l := &link{gw: sf}
+	c := cs104.NewClient(l, o)
+	c.SetOnConnectHandler(func(c *cs104.Client) {
+		c.SendStartDt()
+	})
+	c.SetOnActiveHandler(func(*cs104.Client) {
+		if atomic.SwapUint32(&active, 1) == 0 {
+			l.linkStatusAll(true)
+		}
+	})
+	c.SetConnectionLostHandler(func(*cs104.Client) {
+		if atomic.SwapUint32(&active, 0) == 1 {
+			l.linkStatusAll(false)
+		}
+	})
+	l.down = cs104Link{c}
+	if err := sf.addLink(l, routes); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// addLink 校验并注册链路上所有站的地址映射
+func (sf *Gateway) addLink(l *link, routes []Route) error {
+	if len(routes) == 0 {
+		return errors.New("gateway: empty route")
+	}
+
+	routed := make(map[asdu.CommonAddr]bool)
+	for _, ca := range sf.router.CommonAddrs() {
+		routed[ca] = true
+	}
+	l.routes = make(map[asdu.CommonAddr]*route, len(routes))
+	for _, r := range routes {
+		rt, err := newRoute(r)
+		if err != nil {
+			return err
+		}
+		if routed[r.Upstream] {
+			return errors.New("gateway: duplicate upstream common address")
+		}
+		if _, ok := l.routes[r.Downstream]; ok {
+			return errors.New("gateway: duplicate downstream common address")
+		}
+		routed[r.Upstream] = true
+		l.routes[r.Downstream] = rt
+	}
+	for _, rt := range l.routes {
+		// 地址和处理者已校验, 不会失败
+		_ = sf.router.Handle(rt.Upstream, &station{sf, l, rt})
+	}
+
+	sf.mu.Lock()
+	sf.links = append(sf.links, l)
+	sf.mu.Unlock()
+	return nil
+}
+
+// Start start all the links to the stations
+func (sf *Gateway) Start() error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	for _, l := range sf.links {
+		if err := l.down.Start(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close close all the links to the stations and the IEC104 server
+func (sf *Gateway) Close() error {
+	sf.mu.Lock()
+	for _, l := range sf.links {
+		_ = l.down.Close()
+	}
+	sf.mu.Unlock()
+	sf.tracker.close()
+	return sf.server.Close()
+}
+
+// destination 上行ASDU a 的接收者, 没有接收者时返回nil
+func (sf *Gateway) destination(a *asdu.ASDU) asdu.Connect {
+	var c asdu.Connect
+	var ok bool
+
+	switch cause := a.Coa.Cause; {
+	case cause == asdu.ActivationCon || cause == asdu.ActivationTerm || cause == asdu.DeactivationCon ||
+		(cause >= asdu.UnknownTypeID && cause <= asdu.UnknownIOA):
+		// 网关自身发起的命令(如链路恢复时的总召唤)的响应不转发
+		c, _ = sf.tracker.confirm(a)
+		return c
+	case cause >= asdu.InterrogatedByStation && cause <= asdu.InterrogatedByGroup16:
+		c, ok = sf.tracker.lookup(pendingKey{a.CommonAddr, asdu.C_IC_NA_1, asdu.InfoObjAddrIrrelevant})
+		if !ok {
+			// 没有上行会话发起的召唤(如链路恢复时网关自身的总召唤), 作为背景扫描发布
+			a.Coa.Cause = asdu.Background
+		}
+	case cause >= asdu.RequestByGeneralCounter && cause <= asdu.RequestByGroup4Counter:
+		c, ok = sf.tracker.lookup(pendingKey{a.CommonAddr, asdu.C_CI_NA_1, asdu.InfoObjAddrIrrelevant})
+		if !ok {
+			a.Coa.Cause = asdu.Spontaneous
+		}
+	case cause == asdu.Request:
+		c, ok = sf.tracker.lookup(pendingKey{a.CommonAddr, asdu.C_RD_NA_1, firstInfoObjAddr(a)})
+	}
+	if ok {
+		return c
+	}
+	return sf.upstream
+}
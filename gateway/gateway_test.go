@@ -0,0 +1,240 @@
+package gateway
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs101"
+)
+
+var params101 = &asdu.Params{CauseSize: 1, CommonAddrSize: 1, InfoObjAddrSize: 2, InfoObjTimeZone: time.UTC}
+
+// mockConn deliver all send asdu to the channel
+type mockConn struct {
+	p    *asdu.Params
+	sent chan *asdu.ASDU
+}
+
+func newMockConn() *mockConn {
+	return &mockConn{asdu.ParamsWide, make(chan *asdu.ASDU, 64)}
+}
+
+func (sf *mockConn) Params() *asdu.Params     { return sf.p }
+func (sf *mockConn) UnderlyingConn() net.Conn { return nil }
+func (sf *mockConn) Send(a *asdu.ASDU) error {
+	sf.sent <- a.Clone()
+	return nil
+}
+
+func (sf *mockConn) expect(t *testing.T, typeID asdu.TypeID, cause asdu.Cause, isNegative bool) *asdu.ASDU {
+	t.Helper()
+	select {
+	case a := <-sf.sent:
+		if a.Type != typeID || a.Coa.Cause != cause || a.Coa.IsNegative != isNegative {
+			t.Fatalf("got %v, want %v %v negative %v", a.Identifier, typeID, cause, isNegative)
+		}
+		return a
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout waiting %v %v", typeID, cause)
+	}
+	return nil
+}
+
+// outstation is a CS101 station with link address 1 and common address 1,
+// it has a single point IOA 1 on and confirm every command.
+func outstation(conn net.Conn) {
+	var queue [][]byte
+
+	reply := func(a *asdu.ASDU, cause asdu.Cause) {
+		r := a.Clone()
+		r.Coa.Cause = cause
+		b, _ := r.MarshalBinary()
+		queue = append(queue, b)
+	}
+	send := func(ctrl byte, data []byte) error {
+		if len(queue) > 0 {
+			ctrl |= cs101.ACD_RES
+		}
+		b, _ := cs101.Ft12{Ctrl: ctrl, LinkAddr: 1, ASDU: data}.Encode(1)
+		_, err := conn.Write(b)
+		return err
+	}
+
+	rd := bufio.NewReader(conn)
+	for {
+		f, err := cs101.DecodeFt12(rd, 1)
+		if err != nil {
+			return
+		}
+		switch f.Function() {
+		case cs101.FccLinkStatus:
+			err = send(cs101.FcsStatus, nil)
+		case cs101.FccResetRemoteLink:
+			err = send(cs101.FcsConfirmed, nil)
+		case cs101.FccUserDataWithConfirmed:
+			a := asdu.NewEmptyASDU(params101)
+			if a.UnmarshalBinary(f.ASDU) == nil {
+				reply(a, asdu.ActivationCon)
+				if a.Type == asdu.C_IC_NA_1 {
+					sp := asdu.NewASDU(params101, asdu.Identifier{
+						Type:       asdu.M_SP_NA_1,
+						Variable:   asdu.VariableStruct{Number: 1},
+						Coa:        asdu.CauseOfTransmission{Cause: asdu.InterrogatedByStation},
+						CommonAddr: 1,
+					})
+					_ = sp.AppendInfoObjAddr(1)
+					sp.AppendBytes(0x01)
+					b, _ := sp.MarshalBinary()
+					queue = append(queue, b)
+				}
+				reply(a, asdu.ActivationTerm)
+			}
+			err = send(cs101.FcsConfirmed, nil)
+		case cs101.FccUnbalanceLevel1UserData, cs101.FccUnbalanceLevel2UserData:
+			if len(queue) == 0 {
+				err = send(cs101.FcsUnbalanceNegativeResponse, nil)
+				break
+			}
+			data := queue[0]
+			queue = queue[1:]
+			err = send(cs101.FcsUnbalanceResponse, data)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func TestGateway(t *testing.T) {
+	master, slave := net.Pipe()
+	go outstation(slave)
+
+	gw := New().SetCommandTimeout(time.Second)
+	upstream := newMockConn()
+	gw.upstream = upstream
+
+	o := cs101.NewOption().SetParams(params101).SetResponseTimeout(200 * time.Millisecond).SetPollInterval(10 * time.Millisecond)
+	if err := o.AddStation(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gw.AddCS101(master, o, Route{Upstream: 100, Downstream: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gw.AddCS101(master, o, Route{Upstream: 100, Downstream: 2}); err == nil {
+		t.Fatal("duplicate upstream common address should fail")
+	}
+	if err := gw.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Close()
+
+	// interrogation by the gateway when link online, ActCon and ActTerm are not forwarded,
+	// the data is published as background scan
+	a := upstream.expect(t, asdu.M_SP_NA_1, asdu.Background, false)
+	if a.CommonAddr != 100 || a.GetSinglePoint()[0].Ioa != 1 {
+		t.Fatalf("unexpected data %v", a)
+	}
+
+	// command forward with ActCon and ActTerm tracking
+	session := newMockConn()
+	cmd := asdu.NewASDU(asdu.ParamsWide, asdu.Identifier{
+		Type:       asdu.C_SC_NA_1,
+		Variable:   asdu.VariableStruct{Number: 1},
+		Coa:        asdu.CauseOfTransmission{Cause: asdu.Activation},
+		CommonAddr: 100,
+	})
+	_ = cmd.AppendInfoObjAddr(5)
+	cmd.AppendBytes(0x01)
+	if err := gw.router.ASDUHandler(session, cmd.Clone()); err != nil {
+		t.Fatal(err)
+	}
+	a = session.expect(t, asdu.C_SC_NA_1, asdu.ActivationCon, false)
+	if a.CommonAddr != 100 || firstInfoObjAddr(a) != 5 {
+		t.Fatalf("unexpected ActCon %v", a)
+	}
+	session.expect(t, asdu.C_SC_NA_1, asdu.ActivationTerm, false)
+
+	// link failed, publish the cache with quality NT and IV
+	slave.Close()
+	a = upstream.expect(t, asdu.M_SP_NA_1, asdu.Spontaneous, false)
+	if sp := a.GetSinglePoint(); sp[0].Qds != asdu.QDSNotTopical|asdu.QDSInvalid {
+		t.Fatalf("unexpected quality %v", sp[0].Qds)
+	}
+
+	// interrogation answered from cache
+	gi := asdu.NewASDU(asdu.ParamsWide, asdu.Identifier{
+		Type:       asdu.C_IC_NA_1,
+		Variable:   asdu.VariableStruct{Number: 1},
+		Coa:        asdu.CauseOfTransmission{Cause: asdu.Activation},
+		CommonAddr: asdu.GlobalCommonAddr,
+	})
+	_ = gi.AppendInfoObjAddr(asdu.InfoObjAddrIrrelevant)
+	gi.AppendBytes(byte(asdu.QOIStation))
+	if err := gw.router.InterrogationHandler(session, gi, asdu.QOIStation); err != nil {
+		t.Fatal(err)
+	}
+	session.expect(t, asdu.C_IC_NA_1, asdu.ActivationCon, false)
+	a = session.expect(t, asdu.M_SP_NA_1, asdu.InterrogatedByStation, false)
+	if sp := a.GetSinglePoint(); a.CommonAddr != 100 || !sp[0].Value || sp[0].Qds != asdu.QDSNotTopical|asdu.QDSInvalid {
+		t.Fatalf("unexpected data %v", a)
+	}
+	session.expect(t, asdu.C_IC_NA_1, asdu.ActivationTerm, false)
+
+	// command rejected
+	if err := gw.router.ASDUHandler(session, cmd.Clone()); err != nil {
+		t.Fatal(err)
+	}
+	session.expect(t, asdu.C_SC_NA_1, asdu.ActivationCon, true)
+}
+
+func TestTracker_terminationAfterCommandTimeout(t *testing.T) {
+	session := newMockConn()
+	gi := asdu.NewASDU(asdu.ParamsWide, asdu.Identifier{
+		Type:       asdu.C_IC_NA_1,
+		Variable:   asdu.VariableStruct{Number: 1},
+		Coa:        asdu.CauseOfTransmission{Cause: asdu.Activation},
+		CommonAddr: 100,
+	})
+	_ = gi.AppendInfoObjAddr(asdu.InfoObjAddrIrrelevant)
+	gi.AppendBytes(byte(asdu.QOIStation))
+	reply := func(cause asdu.Cause) *asdu.ASDU {
+		r := gi.Clone()
+		r.Coa.Cause = cause
+		return r
+	}
+	key := pendingKey{100, asdu.C_IC_NA_1, asdu.InfoObjAddrIrrelevant}
+
+	tr := newTracker(50*time.Millisecond, 300*time.Millisecond)
+	defer tr.close()
+	tr.track(session, gi)
+	if c, ok := tr.confirm(reply(asdu.ActivationCon)); !ok || c != session {
+		t.Fatal("ActCon not tracked")
+	}
+	// ActTerm after the command timeout
+	time.Sleep(150 * time.Millisecond)
+	if c, ok := tr.lookup(key); !ok || c != session {
+		t.Fatal("interrogation data after command timeout not tracked")
+	}
+	if c, ok := tr.confirm(reply(asdu.ActivationTerm)); !ok || c != session {
+		t.Fatal("ActTerm after command timeout not tracked")
+	}
+	if _, ok := tr.lookup(key); ok {
+		t.Fatal("tracked after ActTerm")
+	}
+
+	// no ActTerm within the termination timeout, stop tracking without negative confirmation
+	tr.track(session, gi)
+	tr.confirm(reply(asdu.ActivationCon))
+	time.Sleep(400 * time.Millisecond)
+	if _, ok := tr.lookup(key); ok {
+		t.Fatal("tracked after termination timeout")
+	}
+	select {
+	case a := <-session.sent:
+		t.Fatalf("unexpected reply %v", a.Identifier)
+	default:
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package gateway
+
+import (
+	"errors"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs101"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+// downstream 到下行站的链路
+type downstream interface {
+	asdu.Connect
+	IsOnline(ca asdu.CommonAddr) bool
+	Start() error
+	Close() error
+}
+
+// cs104Link 104客户端, 启动数据传输后所有站在线
+type cs104Link struct {
+	*cs104.Client
+}
+
+// IsOnline imp downstream
+func (sf cs104Link) IsOnline(asdu.CommonAddr) bool {
+	return sf.IsActive()
+}
+
+// link 一个下行链路及其上的站的地址映射
+type link struct {
+	gw     *Gateway
+	down   downstream
+	routes map[asdu.CommonAddr]*route // downstream common address -> route
+}
+
+var _ cs101.ClientHandlerInterface = (*link)(nil)
+var _ cs104.ClientHandlerInterface = (*link)(nil)
+
+// linkStatus 下行站恢复时总召唤, 中断时发布品质降级的缓存点值
+func (sf *link) linkStatus(ca asdu.CommonAddr, isOnline bool) {
+	rt, ok := sf.routes[ca]
+	if !ok {
+		return
+	}
+	gw := sf.gw
+	if isOnline {
+		err := asdu.InterrogationCmd(sf.down, asdu.CauseOfTransmission{Cause: asdu.Activation}, ca, asdu.QOIStation)
+		if err != nil {
+			gw.Warn("station %d interrogation failed, %v", ca, err)
+		}
+		return
+	}
+	gw.tracker.fail(rt.Upstream)
+	if err := gw.cache.send(gw.upstream, rt.Upstream, asdu.Spontaneous, true); err != nil {
+		gw.Warn("station %d publish degraded quality failed, %v", rt.Upstream, err)
+	}
+}
+
+// linkStatusAll 链路上所有站的状态变化
+func (sf *link) linkStatusAll(isOnline bool) {
+	for ca := range sf.routes {
+		sf.linkStatus(ca, isOnline)
+	}
+}
+
+// receive 下行站的ASDU映射地址后回复给发起命令的会话或广播到上行
+func (sf *link) receive(pack *asdu.ASDU) error {
+	rt, ok := sf.routes[pack.CommonAddr]
+	if !ok {
+		return errors.New("gateway: unknown downstream common address")
+	}
+	gw := sf.gw
+	as, err := convert(gw.upstream.Params(), pack, rt.Upstream, rt.IOA)
+	if err != nil {
+		return err
+	}
+	for _, a := range as {
+		gw.cache.update(rt.Upstream, a)
+		c := gw.destination(a)
+		if c == nil {
+			continue
+		}
+		if err = c.Send(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InterrogationHandler imp ClientHandlerInterface
+func (sf *link) InterrogationHandler(_ asdu.Connect, pack *asdu.ASDU) error {
+	return sf.receive(pack)
+}
+
+// CounterInterrogationHandler imp ClientHandlerInterface
+func (sf *link) CounterInterrogationHandler(_ asdu.Connect, pack *asdu.ASDU) error {
+	return sf.receive(pack)
+}
+
+// ReadHandler imp ClientHandlerInterface
+func (sf *link) ReadHandler(_ asdu.Connect, pack *asdu.ASDU) error {
+	return sf.receive(pack)
+}
+
+// TestCommandHandler imp ClientHandlerInterface
+func (sf *link) TestCommandHandler(_ asdu.Connect, pack *asdu.ASDU) error {
+	return sf.receive(pack)
+}
+
+// ClockSyncHandler imp ClientHandlerInterface
+func (sf *link) ClockSyncHandler(_ asdu.Connect, pack *asdu.ASDU) error {
+	return sf.receive(pack)
+}
+
+// ResetProcessHandler imp ClientHandlerInterface
+func (sf *link) ResetProcessHandler(_ asdu.Connect, pack *asdu.ASDU) error {
+	return sf.receive(pack)
+}
+
+// DelayAcquisitionHandler imp ClientHandlerInterface
+func (sf *link) DelayAcquisitionHandler(_ asdu.Connect, pack *asdu.ASDU) error {
+	return sf.receive(pack)
+}
+
+// ASDUHandler imp ClientHandlerInterface
+func (sf *link) ASDUHandler(_ asdu.Connect, pack *asdu.ASDU) error {
+	return sf.receive(pack)
+}
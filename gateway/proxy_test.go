@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+// rtu is an IEC104 server with common address 1, it has a single point IOA 100 on
+// and confirm every command.
+type rtu struct {
+	cmd chan asdu.InfoObjAddr
+}
+
+func (sf *rtu) InterrogationHandler(c asdu.Connect, a *asdu.ASDU, _ asdu.QualifierOfInterrogation) error {
+	_ = a.SendReplyMirror(c, asdu.ActivationCon)
+	_ = asdu.Single(c, false, asdu.CauseOfTransmission{Cause: asdu.InterrogatedByStation}, a.CommonAddr,
+		asdu.SinglePointInfo{Ioa: 100, Value: true})
+	return a.SendReplyMirror(c, asdu.ActivationTerm)
+}
+func (sf *rtu) CounterInterrogationHandler(asdu.Connect, *asdu.ASDU, asdu.QualifierCountCall) error {
+	return nil
+}
+func (sf *rtu) ReadHandler(asdu.Connect, *asdu.ASDU, asdu.InfoObjAddr) error { return nil }
+func (sf *rtu) ClockSyncHandler(asdu.Connect, *asdu.ASDU, time.Time) error   { return nil }
+func (sf *rtu) ResetProcessHandler(asdu.Connect, *asdu.ASDU, asdu.QualifierOfResetProcessCmd) error {
+	return nil
+}
+func (sf *rtu) DelayAcquisitionHandler(asdu.Connect, *asdu.ASDU, uint16) error { return nil }
+func (sf *rtu) ASDUHandler(c asdu.Connect, a *asdu.ASDU) error {
+	sf.cmd <- a.Clone().GetSingleCmd().Ioa
+	_ = a.SendReplyMirror(c, asdu.ActivationCon)
+	return a.SendReplyMirror(c, asdu.ActivationTerm)
+}
+
+func TestGateway_AddCS104(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	station := &rtu{make(chan asdu.InfoObjAddr, 1)}
+	srv := cs104.NewServer(station)
+	go srv.ListenAndServer(addr)
+
+	gw := New().SetCommandTimeout(time.Second)
+	upstream := newMockConn()
+	gw.upstream = upstream
+
+	o := cs104.NewOption().SetReconnectInterval(50 * time.Millisecond)
+	if err = o.AddRemoteServer(addr); err != nil {
+		t.Fatal(err)
+	}
+	_, err = gw.AddCS104(o, Route{Upstream: 200, Downstream: 1, IOA: map[asdu.InfoObjAddr]asdu.InfoObjAddr{100: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = gw.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Close()
+
+	// interrogation by the gateway when data transfer active, address mapped
+	a := upstream.expect(t, asdu.M_SP_NA_1, asdu.Background, false)
+	if sp := a.GetSinglePoint(); a.CommonAddr != 200 || sp[0].Ioa != 1 || !sp[0].Value {
+		t.Fatalf("unexpected data %v", a)
+	}
+
+	// command with the upstream address mapped to the RTU
+	session := newMockConn()
+	err = asdu.SingleCmd(session, asdu.C_SC_NA_1, asdu.CauseOfTransmission{Cause: asdu.Activation}, 200,
+		asdu.SingleCommandInfo{Ioa: 1, Value: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = gw.router.ASDUHandler(session, <-session.sent); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ioa := <-station.cmd:
+		if ioa != 100 {
+			t.Fatalf("RTU got command IOA %d, want 100", ioa)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting command")
+	}
+	a = session.expect(t, asdu.C_SC_NA_1, asdu.ActivationCon, false)
+	if a.CommonAddr != 200 || a.GetSingleCmd().Ioa != 1 {
+		t.Fatalf("unexpected ActCon %v", a)
+	}
+	session.expect(t, asdu.C_SC_NA_1, asdu.ActivationTerm, false)
+
+	// RTU down, publish the cache with quality NT and IV
+	_ = srv.Close()
+	a = upstream.expect(t, asdu.M_SP_NA_1, asdu.Spontaneous, false)
+	if sp := a.GetSinglePoint(); sp[0].Ioa != 1 || sp[0].Qds != asdu.QDSNotTopical|asdu.QDSInvalid {
+		t.Fatalf("unexpected data %v", a)
+	}
+}
+
+func TestNewRoute(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       Route
+		wantErr bool
+	}{
+		{"valid", Route{Upstream: 1, Downstream: 2, IOA: map[asdu.InfoObjAddr]asdu.InfoObjAddr{1: 2, 2: 1}}, false},
+		{"invalid upstream", Route{Upstream: asdu.GlobalCommonAddr, Downstream: 2}, true},
+		{"invalid downstream", Route{Upstream: 1, Downstream: asdu.InvalidCommonAddr}, true},
+		{"duplicate ioa", Route{Upstream: 1, Downstream: 2, IOA: map[asdu.InfoObjAddr]asdu.InfoObjAddr{1: 3, 2: 3}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newRoute(tt.r); (err != nil) != tt.wantErr {
+				t.Errorf("newRoute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
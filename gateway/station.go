@@ -0,0 +1,99 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package gateway
+
+import (
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+	"github.com/thinkgos/go-iecp5/cs104"
+)
+
+// station 上行公共地址对应的下行站
+type station struct {
+	gw   *Gateway
+	link *link
+	*route
+}
+
+var _ cs104.ServerHandlerInterface = (*station)(nil)
+
+func (sf *station) isOnline() bool {
+	return sf.link.down.IsOnline(sf.Downstream)
+}
+
+// forward 命令映射地址后转发到下行站并跟踪其响应, 下行链路中断时由网关应答
+func (sf *station) forward(c asdu.Connect, pack *asdu.ASDU) error {
+	if !sf.isOnline() {
+		return sf.reply(c, pack)
+	}
+	down := sf.link.down
+	as, err := convert(down.Params(), pack, sf.Downstream, sf.toDown)
+	if err != nil {
+		sf.gw.Warn("station %d convert command failed, %v", sf.Upstream, err)
+		return pack.SendReplyMirror(c, asdu.UnknownTypeID)
+	}
+	sf.gw.tracker.track(c, pack)
+	for _, a := range as {
+		if err = down.Send(a); err != nil {
+			sf.gw.tracker.confirm(negativeReply(pack)) // 视为否定确认, 结束跟踪
+			return c.Send(negativeReply(pack))
+		}
+	}
+	return nil
+}
+
+// reply 下行链路中断时应答命令, 站总召唤以品质降级的缓存点值应答, 其它命令回复否定确认
+func (sf *station) reply(c asdu.Connect, pack *asdu.ASDU) error {
+	if pack.Type != asdu.C_IC_NA_1 || pack.Coa.Cause != asdu.Activation {
+		return c.Send(negativeReply(pack))
+	}
+	if err := pack.SendReplyMirror(c, asdu.ActivationCon); err != nil {
+		return err
+	}
+	if err := sf.gw.cache.send(c, sf.Upstream, asdu.InterrogatedByStation, true); err != nil {
+		return err
+	}
+	return pack.SendReplyMirror(c, asdu.ActivationTerm)
+}
+
+// InterrogationHandler imp cs104.ServerHandlerInterface
+func (sf *station) InterrogationHandler(c asdu.Connect, pack *asdu.ASDU, qoi asdu.QualifierOfInterrogation) error {
+	if qoi != asdu.QOIStation && !sf.isOnline() {
+		// 缓存不区分分组, 仅应答站总召唤
+		return c.Send(negativeReply(pack))
+	}
+	return sf.forward(c, pack)
+}
+
+// CounterInterrogationHandler imp cs104.ServerHandlerInterface
+func (sf *station) CounterInterrogationHandler(c asdu.Connect, pack *asdu.ASDU, _ asdu.QualifierCountCall) error {
+	return sf.forward(c, pack)
+}
+
+// ReadHandler imp cs104.ServerHandlerInterface
+func (sf *station) ReadHandler(c asdu.Connect, pack *asdu.ASDU, _ asdu.InfoObjAddr) error {
+	return sf.forward(c, pack)
+}
+
+// ClockSyncHandler imp cs104.ServerHandlerInterface
+func (sf *station) ClockSyncHandler(c asdu.Connect, pack *asdu.ASDU, _ time.Time) error {
+	return sf.forward(c, pack)
+}
+
+// ResetProcessHandler imp cs104.ServerHandlerInterface
+func (sf *station) ResetProcessHandler(c asdu.Connect, pack *asdu.ASDU, _ asdu.QualifierOfResetProcessCmd) error {
+	return sf.forward(c, pack)
+}
+
+// DelayAcquisitionHandler imp cs104.ServerHandlerInterface
+func (sf *station) DelayAcquisitionHandler(c asdu.Connect, pack *asdu.ASDU, _ uint16) error {
+	return sf.forward(c, pack)
+}
+
+// ASDUHandler imp cs104.ServerHandlerInterface
+func (sf *station) ASDUHandler(c asdu.Connect, pack *asdu.ASDU) error {
+	return sf.forward(c, pack)
+}
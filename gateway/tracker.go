@@ -0,0 +1,162 @@
+// Copyright 2020 thinkgos (thinkgo@aliyun.com).  All rights reserved.
+// Use of this source code is governed by a version 3 of the GNU General
+// Public License, license that can be found in the LICENSE file.
+
+package gateway
+
+import (
+	"sync"
+	"time"
+
+	"github.com/thinkgos/go-iecp5/asdu"
+)
+
+// pendingKey 待响应命令的标识, 以上行公共地址区分各个站
+type pendingKey struct {
+	ca     asdu.CommonAddr
+	typeID asdu.TypeID
+	ioa    asdu.InfoObjAddr
+}
+
+// pending 已转发到下行的命令
+type pending struct {
+	conn      asdu.Connect // 发起命令的上行会话
+	neg       *asdu.ASDU   // 否定的激活(停止激活)确认
+	confirmed bool         // 已收到确认
+	timer     *time.Timer
+	gen       int // 超时的代数, 重新计时后旧的超时无效
+}
+
+// tracker 跟踪已转发的命令, 将下行的确认,终止及召唤数据回复给发起命令的上行会话.
+// 超时未确认的命令回复否定确认, 确认后超时未终止的命令结束跟踪.
+type tracker struct {
+	mu          sync.Mutex
+	timeout     time.Duration // 等待激活确认
+	termTimeout time.Duration // 激活确认后等待激活终止
+	pending     map[pendingKey]*pending
+}
+
+func newTracker(timeout, termTimeout time.Duration) *tracker {
+	return &tracker{
+		timeout:     timeout,
+		termTimeout: termTimeout,
+		pending:     make(map[pendingKey]*pending),
+	}
+}
+
+// negativeReply 命令a的否定确认
+func negativeReply(a *asdu.ASDU) *asdu.ASDU {
+	r := a.Clone()
+	if a.Coa.Cause == asdu.Deactivation {
+		r.Coa.Cause = asdu.DeactivationCon
+	} else {
+		r.Coa.Cause = asdu.ActivationCon
+	}
+	r.Coa.IsNegative = true
+	return r
+}
+
+// track 跟踪上行会话c发起的命令a(上行公共地址),同一标识的旧命令被替换
+func (sf *tracker) track(c asdu.Connect, a *asdu.ASDU) {
+	key := pendingKey{a.CommonAddr, a.Type, firstInfoObjAddr(a)}
+	// 读命令没有确认过程
+	p := &pending{conn: c, neg: negativeReply(a), confirmed: a.Type == asdu.C_RD_NA_1}
+
+	sf.mu.Lock()
+	if old, ok := sf.pending[key]; ok {
+		old.timer.Stop()
+	}
+	sf.pending[key] = p
+	sf.arm(key, p, sf.timeout)
+	sf.mu.Unlock()
+}
+
+// arm 命令p重新开始计时d, 调用时需持有锁
+func (sf *tracker) arm(key pendingKey, p *pending, d time.Duration) {
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.gen++
+	gen := p.gen
+	p.timer = time.AfterFunc(d, func() {
+		sf.mu.Lock()
+		if sf.pending[key] != p || p.gen != gen {
+			sf.mu.Unlock()
+			return
+		}
+		delete(sf.pending, key)
+		confirmed := p.confirmed
+		sf.mu.Unlock()
+		if !confirmed {
+			_ = p.conn.Send(p.neg)
+		}
+	})
+}
+
+// confirm 查找下行响应a(上行公共地址)对应的上行会话, 并根据传送原因更新命令状态
+func (sf *tracker) confirm(a *asdu.ASDU) (asdu.Connect, bool) {
+	key := pendingKey{a.CommonAddr, a.Type, firstInfoObjAddr(a)}
+
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	p, ok := sf.pending[key]
+	if !ok {
+		return nil, false
+	}
+	if a.Coa.Cause == asdu.ActivationCon && !a.Coa.IsNegative {
+		// 召唤等命令的激活终止可能在命令超时之后
+		p.confirmed = true
+		sf.arm(key, p, sf.termTimeout)
+	} else {
+		p.timer.Stop()
+		delete(sf.pending, key)
+	}
+	return p.conn, true
+}
+
+// lookup 查找已确认的命令key对应的上行会话, 用于召唤和读命令的应答数据
+func (sf *tracker) lookup(key pendingKey) (asdu.Connect, bool) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	p, ok := sf.pending[key]
+	if !ok {
+		return nil, false
+	}
+	if key.typeID == asdu.C_RD_NA_1 { // 读命令没有确认, 应答即结束
+		p.timer.Stop()
+		delete(sf.pending, key)
+	}
+	return p.conn, true
+}
+
+// fail 下行链路中断, 所有公共地址为ca的站未确认的命令回复否定确认
+func (sf *tracker) fail(ca asdu.CommonAddr) {
+	var negs []*pending
+
+	sf.mu.Lock()
+	for k, p := range sf.pending {
+		if k.ca != ca {
+			continue
+		}
+		p.timer.Stop()
+		delete(sf.pending, k)
+		if !p.confirmed {
+			negs = append(negs, p)
+		}
+	}
+	sf.mu.Unlock()
+
+	for _, p := range negs {
+		_ = p.conn.Send(p.neg)
+	}
+}
+
+// close 停止所有命令的超时
+func (sf *tracker) close() {
+	sf.mu.Lock()
+	for k, p := range sf.pending {
+		p.timer.Stop()
+		delete(sf.pending, k)
+	}
+	sf.mu.Unlock()
+}